@@ -18,10 +18,16 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -66,8 +72,25 @@ func main() {
 	// Create service instances
 	fileService := services.NewFileService()
 	contextService := services.NewContextService(fileService)
-	aiService := services.NewAIService(database, cfg)
-	sessionService := services.NewSessionService(database, fileService, contextService, aiService)
+	aiService, err := services.NewAIService(database, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize AI service: %v", err)
+	}
+	usageService := services.NewUsageService(database, cfg)
+	historyService := services.NewHistoryService(database, cfg, aiService, usageService)
+	repoMapService := services.NewRepoMapService(cfg)
+	codeIndexService, err := services.NewCodeIndexService(database, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize code index service: %v", err)
+	}
+	approvalService := services.NewApprovalService(database, cfg)
+	checkpointService := services.NewCheckpointService(database, cfg)
+	browserService := services.NewBrowserService(cfg)
+	defer browserService.CloseAll()
+	mcpHub := services.NewMcpHub(cfg)
+	go mcpHub.Start(context.Background())
+	defer mcpHub.Close()
+	sessionService := services.NewSessionService(database, fileService, contextService, aiService, usageService)
 	commandService := services.NewCommandService()
 	swaggerService := services.NewSwaggerService()
 
@@ -77,6 +100,15 @@ func main() {
 		contextService,
 		sessionService,
 		aiService,
+		usageService,
+		historyService,
+		repoMapService,
+		codeIndexService,
+		services.NewCompletionRegistry(),
+		approvalService,
+		checkpointService,
+		browserService,
+		mcpHub,
 		commandService,
 		swaggerService,
 		database,
@@ -122,8 +154,30 @@ func main() {
 
 	// Start server
 	port := cfg.Server.Port
-	fmt.Printf("Starting server on port %s...\n", port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	server := &http.Server{Addr: ":" + port, Handler: router}
+	serverErr := make(chan error, 1)
+	go func() {
+		fmt.Printf("Starting server on port %s...\n", port)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	// 收到退出信号时先关闭服务，再执行上面的 defer，关闭浏览器、MCP 服务器和数据库，
+	// 不会留下孤儿的 Chrome 和 MCP 子进程
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			logger.Errorf("Failed to start server: %v", err)
+			return
+		}
+	case <-ctx.Done():
+		stop()
+		logger.Infof("Shutting down server...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Errorf("Failed to shut down server: %v", err)
+		}
 	}
 }
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 
 	yaml "sigs.k8s.io/yaml/goyaml.v2"
 )
@@ -20,6 +21,15 @@ type Config struct {
 	Bin       BinConfig `yaml:"bin"`
 	DiffLine  int       `yaml:"diff_line"`
 	DiffModel string    `yaml:"diff_model"`
+	Agent     Agent     `yaml:"agent"`
+	History   History   `yaml:"history"`
+	Context   Context   `yaml:"context"`
+	RepoMap   RepoMap   `yaml:"repo_map"`
+	CodeIndex CodeIndex `yaml:"code_index"`
+	ApplyDiff ApplyDiff `yaml:"apply_diff"`
+	FetchURL  FetchURL  `yaml:"fetch_url"`
+	Browser   Browser   `yaml:"browser"`
+	MCP       MCP       `yaml:"mcp"`
 }
 
 type Server struct {
@@ -39,8 +49,17 @@ type LLMConfig struct {
 	Temperature float64     `yaml:"temperature" json:"temperature"` // 温度参数
 	TopP        float64     `yaml:"top_p" json:"top_p"`             // Top-P采样
 	EnableLog   bool        `yaml:"enable_log" json:"enable_log"`   // 是否启用日志
-	Proxy       string      `yaml:"proxy" json:"proxy"`             // 代理设置
+	Proxy       string      `yaml:"proxy" json:"proxy"`             // 代理设置，支持 http://、https://、socks5://，为空时使用 HTTP(S)_PROXY 环境变量
 	RateLimit   RateLimit   `yaml:"rate_limit" json:"rate_limit"`   // 速率限制
+
+	ConnectTimeout int       `yaml:"connect_timeout" json:"connect_timeout"` // 建立连接超时(秒)，默认10
+	ReadTimeout    int       `yaml:"read_timeout" json:"read_timeout"`       // 读取空闲超时(秒)，超过该时间没有收到数据则断开，0 表示不限制
+	TLS            TLSConfig `yaml:"tls" json:"tls"`                         // TLS设置
+}
+
+type TLSConfig struct {
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify" json:"insecure_skip_verify"` // 跳过证书校验，仅用于自签名的内网网关
+	CACert             string `yaml:"ca_cert" json:"ca_cert"`                           // 额外信任的CA证书文件(PEM)
 }
 
 type BinConfig struct {
@@ -73,6 +92,7 @@ func (l LLMConfig) GetCurrentLLMInfo(modelName string) ModelInfo {
 
 type ModelInfo struct {
 	Name         string   `yaml:"name" json:"name"`                     // 模型名称
+	Provider     string   `yaml:"provider" json:"provider"`             // 模型提供方: openai(默认，兼容openai接口)、anthropic、ollama
 	Description  string   `yaml:"description" json:"description"`       // 模型描述
 	MaxContext   int      `yaml:"max_context" json:"max_context"`       // 最大上下文长度
 	MaxTokens    int      `yaml:"max_tokens" json:"max_tokens"`         // 最大token数
@@ -80,17 +100,284 @@ type ModelInfo struct {
 	IsChatModel  bool     `yaml:"is_chat_model" json:"is_chat_model"`   // 是否为聊天模型
 	CostPerToken float64  `yaml:"cost_per_token" json:"cost_per_token"` // 每token成本
 	// deepseek 官方建议
-	// 代码生成/数学解题   	0.0
+	// 代码生成/数学解题   	0.0
 	// 数据抽取/分析	1.0
 	// 通用对话	1.3
 	// 翻译	1.3
 	// 创意类写作/诗歌创作	1.5
-	Temperature float64 `yaml:"temperature " json:"temperature "` // 温度设置
+	Temperature *float64 `yaml:"temperature" json:"temperature"` // 温度设置，不配置时使用 llm.temperature
+	TopP        *float64 `yaml:"top_p" json:"top_p"`             // Top-P采样，不配置时不发送
+
+	// 以下为模型独立的接入配置，不配置时使用 llm 下的全局配置
+	BaseURL   string            `yaml:"base_url" json:"base_url"` // 模型独立的API地址
+	APIKey    string            `yaml:"api_key" json:"-"`         // 模型独立的API密钥
+	APIKeyEnv string            `yaml:"api_key_env" json:"-"`     // 从该环境变量读取API密钥，优先于 api_key
+	Headers   map[string]string `yaml:"headers" json:"-"`         // 额外的请求头
+
+	DiffStrategy string `yaml:"diff_strategy" json:"diff_strategy"` // apply_diff 使用的修改格式，不配置时使用 apply_diff.strategy
+	Tokenizer    string `yaml:"tokenizer" json:"tokenizer"`         // 计算token使用的词表: cl100k_base、o200k_base，不配置时按模型名称选择
+}
+
+// GetBaseURL returns the model specific endpoint or the global fallback.
+func (m ModelInfo) GetBaseURL(fallback string) string {
+	if m.BaseURL != "" {
+		return m.BaseURL
+	}
+	return fallback
+}
+
+// GetAPIKey resolves the model key: api_key_env first, then api_key, then the global fallback.
+func (m ModelInfo) GetAPIKey(fallback string) string {
+	if m.APIKeyEnv != "" {
+		if key := os.Getenv(m.APIKeyEnv); key != "" {
+			return key
+		}
+	}
+	if m.APIKey != "" {
+		return m.APIKey
+	}
+	return fallback
+}
+
+// GetTemperature returns the model temperature or the global fallback.
+func (m ModelInfo) GetTemperature(fallback float64) float64 {
+	if m.Temperature != nil {
+		return *m.Temperature
+	}
+	return fallback
+}
+
+// GetTopP returns the model top_p, 0 means not set.
+func (m ModelInfo) GetTopP() float64 {
+	if m.TopP != nil {
+		return *m.TopP
+	}
+	return 0
 }
 
 type RateLimit struct {
-	RequestsPerMinute int `yaml:"requests_per_minute" json:"requests_per_minute"` // 每分钟请求数
-	TokensPerMinute   int `yaml:"tokens_per_minute" json:"tokens_per_minute"`     // 每分钟token数
+	RequestsPerMinute int `yaml:"requests_per_minute" json:"requests_per_minute"` // 每分钟请求数，0 表示不限制
+	TokensPerMinute   int `yaml:"tokens_per_minute" json:"tokens_per_minute"`     // 每分钟token数，0 表示不限制
+	MaxWait           int `yaml:"max_wait" json:"max_wait"`                       // 超出限制时排队等待的最长秒数，超过后直接拒绝，默认60，-1 表示不排队
+	MaxRetries        int `yaml:"max_retries" json:"max_retries"`                 // 上游返回429时的最大重试次数，默认3，-1 表示不重试
+}
+
+// GetMaxWait returns how long a request may queue for the limiter.
+func (r RateLimit) GetMaxWait() time.Duration {
+	switch {
+	case r.MaxWait < 0:
+		return 0
+	case r.MaxWait == 0:
+		return 60 * time.Second
+	}
+	return time.Duration(r.MaxWait) * time.Second
+}
+
+// GetMaxRetries returns how often an upstream 429 is retried.
+func (r RateLimit) GetMaxRetries() int {
+	switch {
+	case r.MaxRetries < 0:
+		return 0
+	case r.MaxRetries == 0:
+		return 3
+	}
+	return r.MaxRetries
+}
+
+// Agent 智能模式下服务端自动循环执行工具的设置
+type Agent struct {
+	MaxSteps    int      `yaml:"max_steps" json:"max_steps"`       // 一次请求最多自动执行的工具步数，默认25
+	AutoApprove []string `yaml:"auto_approve" json:"auto_approve"` // 项目和会话都没有设置批准策略时，无需确认即可执行的工具，"*" 表示全部，默认只允许只读工具
+}
+
+// History 历史消息接近模型上下文上限时的压缩设置
+type History struct {
+	SummaryModel       string `yaml:"summary_model" json:"summary_model"`               // 总结旧消息使用的模型，建议使用便宜的模型，为空时使用当前对话的模型
+	KeepRecentMessages int    `yaml:"keep_recent_messages" json:"keep_recent_messages"` // 始终原样保留的最近消息数，默认6
+	ToolResultTokens   int    `yaml:"tool_result_tokens" json:"tool_result_tokens"`     // 较早的工具结果超过该token数时被缩短，默认500
+}
+
+// GetKeepRecentMessages returns how many recent messages are never compacted.
+func (h History) GetKeepRecentMessages() int {
+	if h.KeepRecentMessages <= 0 {
+		return 6
+	}
+	return h.KeepRecentMessages
+}
+
+// GetToolResultTokens returns the size old tool results are shortened to.
+func (h History) GetToolResultTokens() int {
+	if h.ToolResultTokens <= 0 {
+		return 500
+	}
+	return h.ToolResultTokens
+}
+
+// Context 手动模式下根据导入关系自动加入上下文的依赖文件设置
+type Context struct {
+	DependencyDepth    int `yaml:"dependency_depth" json:"dependency_depth"`         // 自动加入所选文件导入的项目文件的层数，0 表示不自动加入
+	MaxDependencyFiles int `yaml:"max_dependency_files" json:"max_dependency_files"` // 自动加入的依赖文件数上限，默认20
+}
+
+// GetMaxDependencyFiles returns how many dependency files are added at most.
+func (c Context) GetMaxDependencyFiles() int {
+	if c.MaxDependencyFiles <= 0 {
+		return 20
+	}
+	return c.MaxDependencyFiles
+}
+
+// RepoMap 智能模式第一轮系统提示词中的仓库地图设置
+type RepoMap struct {
+	Enabled   bool `yaml:"enabled" json:"enabled"`       // 是否在智能模式的第一轮加入仓库地图
+	MaxTokens int  `yaml:"max_tokens" json:"max_tokens"` // 仓库地图的token上限，默认2048
+	MaxFiles  int  `yaml:"max_files" json:"max_files"`   // 最多解析的源码文件数，默认5000
+}
+
+// GetMaxTokens returns the token budget of the repository map.
+func (r RepoMap) GetMaxTokens() int {
+	if r.MaxTokens <= 0 {
+		return 2048
+	}
+	return r.MaxTokens
+}
+
+// GetMaxFiles returns how many source files are parsed at most.
+func (r RepoMap) GetMaxFiles() int {
+	if r.MaxFiles <= 0 {
+		return 5000
+	}
+	return r.MaxFiles
+}
+
+// CodeIndex codebase_search 语义搜索使用的代码索引设置
+type CodeIndex struct {
+	Enabled       bool   `yaml:"enabled" json:"enabled"`                 // 是否启用代码索引和 codebase_search 工具
+	Provider      string `yaml:"provider" json:"provider"`               // 向量接口: openai(默认，兼容openai的 /v1/embeddings)、ollama、local(本地哈希，不调用接口，只能匹配相同的词)
+	Model         string `yaml:"model" json:"model"`                     // 向量模型，openai 默认 text-embedding-3-small，ollama 默认 nomic-embed-text
+	BaseURL       string `yaml:"base_url" json:"base_url"`               // 向量接口地址，openai 为空时使用 llm.base_url，ollama 默认 http://localhost:11434
+	APIKey        string `yaml:"api_key" json:"-"`                       // 向量接口密钥，openai 为空时使用 llm.api_key
+	APIKeyEnv     string `yaml:"api_key_env" json:"-"`                   // 从该环境变量读取密钥，优先于 api_key
+	Dimensions    int    `yaml:"dimensions" json:"dimensions"`           // 向量维度，0 表示使用模型默认值
+	BatchSize     int    `yaml:"batch_size" json:"batch_size"`           // 每次请求生成向量的分块数，默认32
+	MaxChunkLines int    `yaml:"max_chunk_lines" json:"max_chunk_lines"` // 每个分块的最大行数，默认60
+	MaxFiles      int    `yaml:"max_files" json:"max_files"`             // 每个项目最多索引的文件数，默认5000
+	MaxResults    int    `yaml:"max_results" json:"max_results"`         // 搜索默认返回的结果数，默认10
+}
+
+// GetModel returns the embedding model of the configured provider.
+func (c CodeIndex) GetModel() string {
+	if c.Model != "" {
+		return c.Model
+	}
+	switch c.Provider {
+	case "local":
+		return "local-hash"
+	case "ollama":
+		return "nomic-embed-text"
+	}
+	return "text-embedding-3-small"
+}
+
+// GetAPIKey resolves the embedding key: api_key_env first, then api_key, then the global fallback.
+func (c CodeIndex) GetAPIKey(fallback string) string {
+	if c.APIKeyEnv != "" {
+		if key := os.Getenv(c.APIKeyEnv); key != "" {
+			return key
+		}
+	}
+	if c.APIKey != "" {
+		return c.APIKey
+	}
+	return fallback
+}
+
+// GetBatchSize returns how many chunks are embedded per request.
+func (c CodeIndex) GetBatchSize() int {
+	if c.BatchSize <= 0 {
+		return 32
+	}
+	return c.BatchSize
+}
+
+// GetMaxChunkLines returns the maximum number of lines of a chunk.
+func (c CodeIndex) GetMaxChunkLines() int {
+	if c.MaxChunkLines <= 0 {
+		return 60
+	}
+	return c.MaxChunkLines
+}
+
+// GetMaxFiles returns how many files of a project are indexed at most.
+func (c CodeIndex) GetMaxFiles() int {
+	if c.MaxFiles <= 0 {
+		return 5000
+	}
+	return c.MaxFiles
+}
+
+// GetMaxResults returns the default number of search results.
+func (c CodeIndex) GetMaxResults() int {
+	if c.MaxResults <= 0 {
+		return 10
+	}
+	return c.MaxResults
+}
+
+// defaultAutoApprove 只读的工具默认可以自动执行
+var defaultAutoApprove = []string{"read_file", "list_files", "search_files", "list_code_definition_names", "codebase_search"}
+
+// GetMaxSteps returns the step budget of one agent run.
+func (a Agent) GetMaxSteps() int {
+	if a.MaxSteps <= 0 {
+		return 25
+	}
+	return a.MaxSteps
+}
+
+// GetAutoApprove returns the tools allowed without approval when a project or
+// session has no approval policy of its own.
+func (a Agent) GetAutoApprove() []string {
+	if len(a.AutoApprove) == 0 {
+		return defaultAutoApprove
+	}
+	return a.AutoApprove
+}
+
+// ApplyDiff apply_diff 工具的匹配设置
+type ApplyDiff struct {
+	Strategy       string  `yaml:"strategy" json:"strategy"`               // 修改格式: multireplace(默认，SEARCH/REPLACE 块)、unified(git风格的统一diff)，模型可以通过 diff_strategy 单独设置
+	FuzzyThreshold float64 `yaml:"fuzzy_threshold" json:"fuzzy_threshold"` // SEARCH 内容与原文的最低相似度(0-1]，默认1.0 即精确匹配
+	BufferLines    int     `yaml:"buffer_lines" json:"buffer_lines"`       // 指定了 start_line 时，在其前后多少行内查找匹配，默认40
+}
+
+// GetStrategy returns the diff format used by the given model.
+func (a ApplyDiff) GetStrategy(models []ModelInfo, modelName string) string {
+	for _, model := range models {
+		if model.Name == modelName && model.DiffStrategy != "" {
+			return model.DiffStrategy
+		}
+	}
+	if a.Strategy == "" {
+		return "multireplace"
+	}
+	return a.Strategy
+}
+
+// GetFuzzyThreshold returns the minimum similarity a SEARCH block must reach.
+func (a ApplyDiff) GetFuzzyThreshold() float64 {
+	if a.FuzzyThreshold <= 0 || a.FuzzyThreshold > 1 {
+		return 1.0
+	}
+	return a.FuzzyThreshold
+}
+
+// GetBufferLines returns how many lines around the line hint are searched.
+func (a ApplyDiff) GetBufferLines() int {
+	if a.BufferLines <= 0 {
+		return 40
+	}
+	return a.BufferLines
 }
 
 type Sqlite struct {
@@ -128,3 +415,75 @@ func LoadConfig(file string) (*Config, error) {
 func GetConfig() *Config {
 	return &cfg
 }
+
+// FetchURL fetch_url 工具的设置
+type FetchURL struct {
+	AllowedDomains []string `yaml:"allowed_domains" json:"allowed_domains"` // 允许访问的域名，同时允许其子域名，为空时不限制
+	MaxTokens      int      `yaml:"max_tokens" json:"max_tokens"`           // 返回内容的token上限，超出部分截断，默认8000
+	Timeout        int      `yaml:"timeout" json:"timeout"`                 // 请求超时(秒)，默认30
+}
+
+// GetMaxTokens returns the token budget of a fetched page.
+func (f FetchURL) GetMaxTokens() int {
+	if f.MaxTokens <= 0 {
+		return 8000
+	}
+	return f.MaxTokens
+}
+
+// GetTimeout returns the timeout of a fetch request.
+func (f FetchURL) GetTimeout() time.Duration {
+	if f.Timeout <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(f.Timeout) * time.Second
+}
+
+// Browser browser_action 工具的设置
+type Browser struct {
+	Enabled      bool   `yaml:"enabled" json:"enabled"`             // 是否允许模型使用无头浏览器
+	Bin          string `yaml:"bin" json:"bin"`                     // Chrome/Chromium 路径，为空时自动查找，找不到会自动下载
+	ViewportSize string `yaml:"viewport_size" json:"viewport_size"` // 窗口大小，默认 900x600
+	IdleTimeout  int    `yaml:"idle_timeout" json:"idle_timeout"`   // 浏览器空闲多少分钟后自动关闭，默认10
+}
+
+// GetViewportSize returns the viewport width and height in pixels.
+func (b Browser) GetViewportSize() (int, int) {
+	var width, height int
+	if _, err := fmt.Sscanf(b.ViewportSize, "%dx%d", &width, &height); err != nil || width <= 0 || height <= 0 {
+		return 900, 600
+	}
+	return width, height
+}
+
+// GetIdleTimeout returns how long an unused browser stays open.
+func (b Browser) GetIdleTimeout() time.Duration {
+	if b.IdleTimeout <= 0 {
+		return 10 * time.Minute
+	}
+	return time.Duration(b.IdleTimeout) * time.Minute
+}
+
+// MCP 外部 MCP 服务器的设置，服务器的工具和资源通过 use_mcp_tool、access_mcp_resource 提供给模型
+type MCP struct {
+	Servers []MCPServer `yaml:"servers" json:"servers"`
+}
+
+// MCPServer 一个通过 stdio 通信的 MCP 服务器
+type MCPServer struct {
+	Name     string            `yaml:"name" json:"name"`         // 服务器名称，模型调用时使用
+	Command  string            `yaml:"command" json:"command"`   // 启动命令
+	Args     []string          `yaml:"args" json:"args"`         // 命令参数
+	Env      map[string]string `yaml:"env" json:"env"`           // 额外的环境变量
+	Dir      string            `yaml:"dir" json:"dir"`           // 工作目录，为空时使用当前目录
+	Disabled bool              `yaml:"disabled" json:"disabled"` // 暂时停用
+	Timeout  int               `yaml:"timeout" json:"timeout"`   // 单个请求超时(秒)，默认60
+}
+
+// GetTimeout returns the timeout of a single request to the server.
+func (m MCPServer) GetTimeout() time.Duration {
+	if m.Timeout <= 0 {
+		return 60 * time.Second
+	}
+	return time.Duration(m.Timeout) * time.Second
+}
@@ -266,81 +266,112 @@ export const sessions = {
     const fetchController = new AbortController();
     const { signal } = fetchController;
 
-    fetch(`${SERVER_URL}/api/sessions/${sessionId}/completions`, {
-      method: "POST",
-      headers: { "Content-Type": "application/json" },
-      body: JSON.stringify(requestData),
-      signal,
-    })
-      .then((response) => {
-        if (!response.ok) {
-          throw new Error(`HTTP error! Status: ${response.status}`);
-        }
-
-        const reader = response.body.getReader();
-        let buffer = "";
-
-        function readStream() {
-          reader
-            .read()
-            .then(({ done, value }) => {
-              if (done) {
-                if (buffer.length > 0) {
-                  try {
-                    const lastChunk = JSON.parse(buffer);
-                    onMessage(lastChunk);
-                  } catch (err) {
-                    console.error("Error parsing last chunk:", err);
-                  }
+    // 记录最后收到的事件ID，断线后带上 Last-Event-ID 重连，服务端会补发错过的内容
+    let lastEventId = null;
+    let reconnects = 0;
+    const maxReconnects = 5;
+    let finished = false;
+
+    const finish = () => {
+      if (finished) return;
+      finished = true;
+      if (onComplete) onComplete();
+    };
+
+    const fail = (error) => {
+      if (error.name === "AbortError" || finished) return;
+      if (lastEventId !== null && reconnects < maxReconnects) {
+        reconnects++;
+        console.warn(`Stream interrupted, reconnecting (${reconnects})...`, error);
+        setTimeout(resume, 1000 * reconnects);
+        return;
+      }
+      console.error("Stream error:", error);
+      finished = true;
+      if (onError) onError(error);
+    };
+
+    const consume = (response) => {
+      if (!response.ok) {
+        throw new Error(`HTTP error! Status: ${response.status}`);
+      }
+
+      const reader = response.body.getReader();
+      let buffer = "";
+
+      function readStream() {
+        reader
+          .read()
+          .then(({ done, value }) => {
+            if (done) {
+              if (buffer.length > 0) {
+                try {
+                  const lastChunk = JSON.parse(buffer);
+                  onMessage(lastChunk);
+                } catch (err) {
+                  console.error("Error parsing last chunk:", err);
                 }
-                if (onComplete) onComplete();
-                return;
               }
-
-              // Decode the chunk and add to buffer
-              const chunk = decoder.decode(value, { stream: true });
-              buffer += chunk;
-
-              // Process complete messages
-              const lines = buffer.split("\n");
-              buffer = lines.pop() || ""; // Keep the incomplete line
-
-              for (const line of lines) {
-                if (line.startsWith("data: ")) {
-                  try {
-                    const data = line.substring(6); // Remove 'data: ' prefix
-                    // if (data === "[DONE]") {
-                    if (data.startsWith("[DONE]")) {
-                      if (onComplete) onComplete();
-                      return;
-                    }
-
-                    const parsedData = JSON.parse(data);
-                    onMessage(parsedData);
-                  } catch (err) {
-                    console.error("Error parsing stream data:", err);
+              finish();
+              return;
+            }
+
+            // Decode the chunk and add to buffer
+            const chunk = decoder.decode(value, { stream: true });
+            buffer += chunk;
+
+            // Process complete messages
+            const lines = buffer.split("\n");
+            buffer = lines.pop() || ""; // Keep the incomplete line
+
+            for (const line of lines) {
+              if (line.startsWith("id: ")) {
+                lastEventId = line.substring(4);
+                continue;
+              }
+              if (line.startsWith("data: ")) {
+                try {
+                  const data = line.substring(6); // Remove 'data: ' prefix
+                  // if (data === "[DONE]") {
+                  if (data.startsWith("[DONE]")) {
+                    finish();
+                    return;
                   }
+
+                  const parsedData = JSON.parse(data);
+                  onMessage(parsedData);
+                } catch (err) {
+                  console.error("Error parsing stream data:", err);
                 }
               }
+            }
 
-              readStream();
-            })
-            .catch((error) => {
-              if (error.name !== "AbortError") {
-                console.error("Stream reading error:", error);
-                if (onError) onError(error);
-              }
-            });
-        }
+            readStream();
+          })
+          .catch(fail);
+      }
 
-        readStream();
+      readStream();
+    };
+
+    const resume = () => {
+      fetch(`${SERVER_URL}/api/sessions/${sessionId}/completions/stream`, {
+        method: "GET",
+        headers: { "Last-Event-ID": lastEventId },
+        signal,
       })
-      .catch((error) => {
-        if (error.name !== "AbortError") {
-          console.error("Fetch error:", error);
-          if (onError) onError(error);
-        }
-      });
+        .then(consume)
+        .catch(fail);
+    };
+
+    fetch(`${SERVER_URL}/api/sessions/${sessionId}/completions`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(requestData),
+      signal,
+    })
+      .then(consume)
+      .catch(fail);
 
     return {
       close: function () {
@@ -349,6 +380,79 @@ export const sessions = {
     };
   },
 
+  /**
+   * Stop a running completion, the stream then ends with status "stopped"
+   * @param {string} sessionId - Session ID
+   * @param {number} userMsgId - User message ID, 0 stops all running completions of the session
+   * @returns {Promise<Object>} Stopped completions
+   */
+  stopCompletion: async function (sessionId, userMsgId = 0) {
+    const response = await fetch(
+      `${SERVER_URL}/api/sessions/${sessionId}/completions/stop`,
+      {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ user_msg_id: userMsgId }),
+      }
+    );
+    return handleResponse(response);
+  },
+
+  /**
+   * Check a tool call against the session approval policy
+   * @param {string} sessionId - Session ID
+   * @param {Object} toolUse - Tool use ({name, params})
+   * @returns {Promise<Object>} Decision ("allow", "ask" or "deny") and policy source
+   */
+  checkToolApproval: async function (sessionId, toolUse) {
+    const response = await fetch(
+      `${SERVER_URL}/api/sessions/${sessionId}/approval-policy/check`,
+      {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify(toolUse),
+      }
+    );
+    return handleResponse(response);
+  },
+
+  /**
+   * Get the file checkpoints saved before each edit tool call of a session
+   * @param {string} sessionId - Session ID
+   * @returns {Promise<Array>} Checkpoints with the snapshotted files
+   */
+  getSessionCheckpoints: async function (sessionId) {
+    const response = await fetch(
+      `${SERVER_URL}/api/sessions/${sessionId}/checkpoints`
+    );
+    return handleResponse(response);
+  },
+
+  /**
+   * Get the workspace changes made since a checkpoint
+   * @param {number} checkpointId - Checkpoint ID
+   * @returns {Promise<Object>} Changed files with their diff
+   */
+  getCheckpointDiff: async function (checkpointId) {
+    const response = await fetch(
+      `${SERVER_URL}/api/checkpoints/${checkpointId}/diff`
+    );
+    return handleResponse(response);
+  },
+
+  /**
+   * Restore the workspace to a checkpoint
+   * @param {number} checkpointId - Checkpoint ID
+   * @returns {Promise<Object>} Restored files
+   */
+  restoreCheckpoint: async function (checkpointId) {
+    const response = await fetch(
+      `${SERVER_URL}/api/checkpoints/${checkpointId}/restore`,
+      { method: "POST" }
+    );
+    return handleResponse(response);
+  },
+
   /**
    * Update session context
    * @param {string} sessionId - Session ID
@@ -396,7 +396,7 @@ export async function sendMessage(
                 "Tool use detected (non-single-html or no path):",
                 toolUseData
               );
-              showToolUseConfirmation(toolUseData, async (isConfirmed) => {
+              const runTool = async (isConfirmed) => {
                 // For attempt_completion, we don't need to do anything further
                 if (toolUseData.name === "attempt_completion") {
                   return;
@@ -422,7 +422,26 @@ export async function sendMessage(
                     aiMessageElement.innerHTML += `<div class="error">工具执行错误: ${toolError.message}</div>`;
                   }
                 }
-              });
+              };
+
+              // 批准策略允许的工具直接执行，不弹出确认框
+              sessions
+                .checkToolApproval(currentSession.id, toolUseData)
+                .then((res) => res.decision)
+                .catch((err) => {
+                  console.error("Failed to check tool approval:", err);
+                  return "ask";
+                })
+                .then((decision) => {
+                  if (
+                    decision === "allow" &&
+                    toolUseData.name !== "attempt_completion"
+                  ) {
+                    runTool(true);
+                  } else {
+                    showToolUseConfirmation(toolUseData, runTool);
+                  }
+                });
             }
 
             setActiveStream(null);
@@ -556,6 +575,75 @@ function showToolUseConfirmation(toolUseData, callback) {
       const commandText = toolUseData.params.command || "";
       displayPath = commandText; // Use command as the "path"
       break;
+    case "apply_diff":
+      displayAction = "修改文件";
+      displayIcon = "✏️";
+      displayColor = "#0ea5e9"; // Blue
+      break;
+    case "search_and_replace":
+      displayAction = "搜索替换";
+      displayIcon = "🔄";
+      displayColor = "#0ea5e9"; // Blue
+      break;
+    case "browser_action":
+      displayAction = "浏览器操作";
+      displayIcon = "🧭";
+      displayColor = "#10b981"; // Green
+      displayPath = [
+        toolUseData.params.action,
+        toolUseData.params.url || toolUseData.params.coordinate || toolUseData.params.text || "",
+      ]
+        .filter(Boolean)
+        .join(" ");
+      break;
+    case "use_mcp_tool":
+      displayAction = "MCP工具";
+      displayIcon = "🔌";
+      displayColor = "#8b5cf6"; // Purple
+      displayPath = `${toolUseData.params.server_name} / ${toolUseData.params.tool_name}`;
+      break;
+    case "access_mcp_resource":
+      displayAction = "MCP资源";
+      displayIcon = "🔌";
+      displayColor = "#8b5cf6"; // Purple
+      displayPath = `${toolUseData.params.server_name} / ${toolUseData.params.uri}`;
+      break;
+    case "fetch_url":
+      displayAction = "读取网页";
+      displayIcon = "🌐";
+      displayColor = "#10b981"; // Green
+      displayPath = toolUseData.params.url || "";
+      break;
+    case "move_file":
+      displayAction = "移动文件";
+      displayIcon = "📦";
+      displayColor = "#0ea5e9"; // Blue
+      displayPath = `${toolUseData.params.path} → ${toolUseData.params.new_path || ""}`;
+      break;
+    case "delete_file":
+      displayAction = "删除文件";
+      displayIcon = "🗑️";
+      displayColor = "#ef4444"; // Red
+      break;
+    case "create_directory":
+      displayAction = "创建目录";
+      displayIcon = "📁";
+      displayColor = "#0ea5e9"; // Blue
+      break;
+    case "batch_edit":
+      displayAction = "批量修改文件";
+      displayIcon = "🗂️";
+      displayColor = "#0ea5e9"; // Blue
+      try {
+        // 展示涉及的所有文件
+        const operations = JSON.parse(toolUseData.params.operations || "[]");
+        displayPath = operations
+          .map((op) => `${op.action} ${op.path}${op.new_path ? " → " + op.new_path : ""}`)
+          .join(", ");
+      } catch (e) {
+        displayPath = "";
+      }
+      break;
     case "insert_content":
       displayAction = "插入内容";
       displayIcon = "➕";
@@ -571,6 +659,12 @@ function showToolUseConfirmation(toolUseData, callback) {
       displayIcon = "🔍";
       displayColor = "#ec4899"; // Pink
       break;
+    case "codebase_search":
+      displayAction = "语义搜索代码";
+      displayIcon = "🧭";
+      displayColor = "#ec4899"; // Pink
+      displayPath = `${toolUseData.params.query || ""}${toolUseData.params.path ? " (" + toolUseData.params.path + ")" : ""}`;
+      break;
     case "list_code_definition_names":
       displayAction = "列出代码定义";
       displayIcon = "📋";
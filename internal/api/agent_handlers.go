@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"mind-weaver/internal/db"
+	"mind-weaver/internal/services"
+	"mind-weaver/internal/third/assistantmessage"
+	"mind-weaver/internal/third/tools"
+	"mind-weaver/internal/utils"
+	"mind-weaver/pkg/logger"
+)
+
+// runAgentLoop 智能模式的自动循环：请求模型 → 解析工具调用 → 执行工具 → 把结果作为用户消息回传，
+// 直到模型调用 attempt_completion / ask_followup_question、达到步数上限、遇到需要确认的工具或被停止
+func (h *Handler) runAgentLoop(ctx context.Context, out utils.FlushWriter, req OpenAICompatRequest, historyMessages []*services.Message, systemtPrompt string, userMsg *services.MessageInfo, sessionInfo *services.SessionInfo) *services.AgentResult {
+	result := &services.AgentResult{Steps: []services.AgentStep{}}
+	maxSteps := req.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = h.cfg.Agent.GetMaxSteps()
+	}
+
+	// 按会话生效的批准策略决定哪些工具可以自动执行，读取失败时使用默认策略
+	policy := h.approval.DefaultPolicy()
+	if effective, err := h.approval.GetSessionPolicy(req.SessionID); err != nil {
+		logger.Errorf("Failed to get approval policy of session %d: %v", req.SessionID, err)
+	} else {
+		policy = effective.Policy
+	}
+	diffStrategy := services.NewDiffStrategy(h.cfg, req.Model)
+	fetchOptions := services.NewFetchOptions(h.cfg)
+	approved := func(toolUse *assistantmessage.ToolUse) bool {
+		return policy.Decide(*toolUse, req.ProjectPath) != tools.ApprovalAsk
+	}
+
+	for {
+		// 步数用完时不再请求模型，工具结果已经保存，用户可以继续对话
+		if len(result.Steps) >= maxSteps {
+			result.StopReason = services.AgentStopStepBudget
+			return result
+		}
+
+		turn := h.streamByLineTurn(ctx, out, req, historyMessages, systemtPrompt, userMsg, sessionInfo)
+		result.Usage.Add(turn.Usage)
+		result.MessageID = turn.MsgID
+		result.Content = turn.Content
+		if ctx.Err() != nil {
+			result.StopReason = services.AgentStopStopped
+			return result
+		}
+		if turn.Err != nil {
+			result.StopReason = services.AgentStopError
+			return result
+		}
+
+		toolUse, stopReason := services.NextAgentAction(turn.Content, len(result.Steps), maxSteps, approved)
+		if stopReason != "" {
+			result.StopReason = stopReason
+			result.Tool = toolUse
+			return result
+		}
+
+		// 被策略拒绝的工具也交给 ExecuteTool，错误信息会回传给模型
+		executeRes, err := h.executeTool(req.SessionID, turn.MsgID, tools.ExecutorInput{
+			Ctx:          ctx,
+			ToolUse:      *toolUse,
+			Cwd:          req.ProjectPath,
+			DiffStrategy: diffStrategy,
+			Approval:     policy,
+			Fetch:        fetchOptions,
+			Browser:      h.browsers.Session(req.SessionID),
+			Mcp:          h.mcpHub,
+			CodeIndex:    h.codeSearcher(),
+		})
+		step := services.AgentStep{Step: len(result.Steps) + 1, Tool: *toolUse}
+		if err != nil {
+			step.Result = err.Error()
+			step.IsError = true
+		} else {
+			step.Result = executeRes.Result
+			step.IsError = executeRes.IsError
+		}
+		result.Steps = append(result.Steps, step)
+		writeAgentEvent(out, "agent_step", step)
+		logger.Infof("Agent step %d of session %d: %s, is error: %v", step.Step, req.SessionID, toolUse.Name, step.IsError)
+
+		// 本轮对话加入历史，工具结果作为下一轮的用户消息
+		if systemtPrompt != "" {
+			historyMessages = append(historyMessages, &services.Message{Role: services.MsgTypeSystem, Content: systemtPrompt})
+			systemtPrompt = ""
+		}
+		historyMessages = append(historyMessages,
+			&services.Message{Id: userMsg.ID, Role: services.MsgTypeUser, Content: userMsg.Content},
+			&services.Message{Id: turn.MsgID, Role: services.MsgTypeAssistant, Content: turn.Content},
+		)
+		userMsg, err = h.sessionService.AddUserMessage(req.SessionID, services.ToolResultMessage(executeRes, err))
+		if err != nil {
+			logger.Errorf("Failed to add tool result message: %v", err)
+			result.StopReason = services.AgentStopError
+			return result
+		}
+	}
+}
+
+// streamAgent 以SSE输出自动循环，每执行一个工具发送一次 agent_step 事件，结束时发送 agent 事件
+func (h *Handler) streamAgent(ctx context.Context, out utils.FlushWriter, req OpenAICompatRequest, historyMessages []*services.Message, systemtPrompt string, userMsg *services.MessageInfo, sessionInfo *services.SessionInfo) {
+	result := h.runAgentLoop(ctx, out, req, historyMessages, systemtPrompt, userMsg, sessionInfo)
+	status := messageStatus(ctx)
+	if status == db.MessageStatusInterrupted {
+		return
+	}
+
+	writeAgentEvent(out, "agent", result)
+	fmt.Fprintf(out, "data: [DONE]\ndata: {\"status\":%q}\n\n", streamDoneStatus(status))
+	out.Flush()
+}
+
+// runAgent 非流式的自动循环，适合脚本调用。循环同样在独立的协程中执行，
+// 事件写入可重放的缓存，调用方超时断开后仍可通过 /completions/stream 继续查看进度
+func (h *Handler) runAgent(c *gin.Context, req OpenAICompatRequest, historyMessages []*services.Message, systemtPrompt string, userMsg *services.MessageInfo, sessionInfo *services.SessionInfo) {
+	ctx, completion, release := h.completions.Start(req.SessionID, userMsg.ID)
+	completion.Attach()
+	defer completion.Detach()
+
+	done := make(chan *services.AgentResult, 1)
+	go func() {
+		defer release()
+		done <- h.runAgentLoop(ctx, completion.Stream, req, historyMessages, systemtPrompt, userMsg, sessionInfo)
+	}()
+
+	var result *services.AgentResult
+	select {
+	case result = <-done:
+	case <-c.Request.Context().Done():
+		return
+	}
+
+	response := OpenAICompatResponse{
+		ID:      fmt.Sprintf("chatcmpl-%d", req.SessionID),
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   req.Model,
+		Choices: []Choice{
+			{
+				Index: 0,
+				Message: ChatMessage{
+					Role:    "assistant",
+					Content: result.Content,
+				},
+				FinishReason: result.StopReason,
+			},
+		},
+		Usage: UsageInfo{
+			PromptTokens:     result.Usage.PromptTokens,
+			CompletionTokens: result.Usage.CompletionTokens,
+			TotalTokens:      result.Usage.TotalTokens,
+		},
+		Agent: result,
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func writeAgentEvent(out utils.FlushWriter, key string, value any) {
+	data, _ := json.Marshal(map[string]any{key: value})
+	fmt.Fprintf(out, "data: %s\n\n", data)
+	out.Flush()
+}
@@ -0,0 +1,256 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"mind-weaver/internal/api/base"
+	"mind-weaver/internal/db"
+	"mind-weaver/internal/services"
+	"mind-weaver/internal/third/assistantmessage"
+	"mind-weaver/internal/third/tools"
+)
+
+type CheckToolApprovalResp struct {
+	Decision tools.ApprovalDecision `json:"decision"` // allow、ask 或 deny
+	Source   string                 `json:"source"`   // 生效策略的来源：session、project 或 default
+}
+
+// GetSessionApprovalPolicy 获取会话生效的工具批准策略
+// @Summary      获取会话的工具批准策略
+// @Description  返回会话实际生效的策略：会话自己的策略，没有时为项目的策略，都没有时为配置中的默认策略
+// @Tags         session
+// @Produce      json
+// @Param        id   path      int  true  "会话ID"
+// @Success      200  {object}  base.Response{data=services.EffectivePolicy}
+// @Failure      400  {object}  base.Response
+// @Failure      404  {object}  base.Response
+// @Failure      500  {object}  base.Response
+// @Router       /sessions/{id}/approval-policy [get]
+func (h *Handler) GetSessionApprovalPolicy(c *gin.Context) {
+	sessionID, ok := h.parseSessionID(c)
+	if !ok {
+		return
+	}
+
+	policy, err := h.approval.GetSessionPolicy(sessionID)
+	if err != nil {
+		base.ErrorResponse(c, http.StatusInternalServerError, base.ErrCodeInternalError, fmt.Sprintf("Failed to get approval policy: %v", err))
+		return
+	}
+
+	base.SuccessResponse(c, policy)
+}
+
+// SaveSessionApprovalPolicy 设置会话的工具批准策略
+// @Summary      设置会话的工具批准策略
+// @Description  会话的策略优先于项目的策略
+// @Tags         session
+// @Accept       json
+// @Produce      json
+// @Param        id       path  int                   true  "会话ID"
+// @Param        request  body  tools.ApprovalPolicy  true  "批准策略"
+// @Success      200  {object}  base.Response{data=tools.ApprovalPolicy}
+// @Failure      400  {object}  base.Response
+// @Failure      404  {object}  base.Response
+// @Failure      500  {object}  base.Response
+// @Router       /sessions/{id}/approval-policy [put]
+func (h *Handler) SaveSessionApprovalPolicy(c *gin.Context) {
+	sessionID, ok := h.parseSessionID(c)
+	if !ok {
+		return
+	}
+	h.savePolicy(c, db.PolicyScopeSession, sessionID)
+}
+
+// DeleteSessionApprovalPolicy 删除会话的工具批准策略
+// @Summary      删除会话的工具批准策略
+// @Description  删除后会话使用项目的策略
+// @Tags         session
+// @Produce      json
+// @Param        id   path      int  true  "会话ID"
+// @Success      200  {object}  base.Response
+// @Failure      400  {object}  base.Response
+// @Failure      404  {object}  base.Response
+// @Failure      500  {object}  base.Response
+// @Router       /sessions/{id}/approval-policy [delete]
+func (h *Handler) DeleteSessionApprovalPolicy(c *gin.Context) {
+	sessionID, ok := h.parseSessionID(c)
+	if !ok {
+		return
+	}
+	h.deletePolicy(c, db.PolicyScopeSession, sessionID)
+}
+
+// CheckToolApproval 判断工具调用是否可以直接执行
+// @Summary      检查工具调用的批准结果
+// @Description  根据会话生效的策略判断工具调用是直接执行(allow)、需要用户确认(ask)还是拒绝(deny)，前端据此决定是否弹出确认框
+// @Tags         session
+// @Accept       json
+// @Produce      json
+// @Param        id       path  int                       true  "会话ID"
+// @Param        request  body  assistantmessage.ToolUse  true  "工具调用"
+// @Success      200  {object}  base.Response{data=CheckToolApprovalResp}
+// @Failure      400  {object}  base.Response
+// @Failure      404  {object}  base.Response
+// @Failure      500  {object}  base.Response
+// @Router       /sessions/{id}/approval-policy/check [post]
+func (h *Handler) CheckToolApproval(c *gin.Context) {
+	sessionID, ok := h.parseSessionID(c)
+	if !ok {
+		return
+	}
+
+	var toolUse assistantmessage.ToolUse
+	if err := c.ShouldBindJSON(&toolUse); err != nil {
+		base.ErrorResponse(c, http.StatusBadRequest, base.ErrCodeInvalidParams, err.Error())
+		return
+	}
+
+	session, err := h.database.GetSession(sessionID)
+	if err != nil {
+		base.ErrorResponse(c, http.StatusNotFound, base.ErrCodeNotFound, "Session not found")
+		return
+	}
+	project, err := h.database.GetProject(session.ProjectID)
+	if err != nil {
+		base.ErrorResponse(c, http.StatusNotFound, base.ErrCodeNotFound, "Project not found")
+		return
+	}
+
+	policy, err := h.approval.GetSessionPolicy(sessionID)
+	if err != nil {
+		base.ErrorResponse(c, http.StatusInternalServerError, base.ErrCodeInternalError, fmt.Sprintf("Failed to get approval policy: %v", err))
+		return
+	}
+
+	base.SuccessResponse(c, CheckToolApprovalResp{
+		Decision: policy.Policy.Decide(toolUse, project.Path),
+		Source:   policy.Source,
+	})
+}
+
+// GetProjectApprovalPolicy 获取项目的工具批准策略
+// @Summary      获取项目的工具批准策略
+// @Description  项目没有设置策略时返回配置中的默认策略
+// @Tags         project
+// @Produce      json
+// @Param        id   path      int  true  "项目ID"
+// @Success      200  {object}  base.Response{data=services.EffectivePolicy}
+// @Failure      400  {object}  base.Response
+// @Failure      404  {object}  base.Response
+// @Failure      500  {object}  base.Response
+// @Router       /projects/{id}/approval-policy [get]
+func (h *Handler) GetProjectApprovalPolicy(c *gin.Context) {
+	projectID, ok := h.parseProjectID(c)
+	if !ok {
+		return
+	}
+
+	policy, err := h.approval.GetPolicy(db.PolicyScopeProject, projectID)
+	if err != nil {
+		base.ErrorResponse(c, http.StatusInternalServerError, base.ErrCodeInternalError, fmt.Sprintf("Failed to get approval policy: %v", err))
+		return
+	}
+
+	if policy == nil {
+		base.SuccessResponse(c, services.EffectivePolicy{Source: services.PolicySourceDefault, Policy: h.approval.DefaultPolicy()})
+		return
+	}
+	base.SuccessResponse(c, services.EffectivePolicy{Source: services.PolicySourceProject, Policy: policy})
+}
+
+// SaveProjectApprovalPolicy 设置项目的工具批准策略
+// @Summary      设置项目的工具批准策略
+// @Description  项目下没有单独设置策略的会话都使用该策略
+// @Tags         project
+// @Accept       json
+// @Produce      json
+// @Param        id       path  int                   true  "项目ID"
+// @Param        request  body  tools.ApprovalPolicy  true  "批准策略"
+// @Success      200  {object}  base.Response{data=tools.ApprovalPolicy}
+// @Failure      400  {object}  base.Response
+// @Failure      404  {object}  base.Response
+// @Failure      500  {object}  base.Response
+// @Router       /projects/{id}/approval-policy [put]
+func (h *Handler) SaveProjectApprovalPolicy(c *gin.Context) {
+	projectID, ok := h.parseProjectID(c)
+	if !ok {
+		return
+	}
+	h.savePolicy(c, db.PolicyScopeProject, projectID)
+}
+
+// DeleteProjectApprovalPolicy 删除项目的工具批准策略
+// @Summary      删除项目的工具批准策略
+// @Description  删除后使用配置中的默认策略
+// @Tags         project
+// @Produce      json
+// @Param        id   path      int  true  "项目ID"
+// @Success      200  {object}  base.Response
+// @Failure      400  {object}  base.Response
+// @Failure      404  {object}  base.Response
+// @Failure      500  {object}  base.Response
+// @Router       /projects/{id}/approval-policy [delete]
+func (h *Handler) DeleteProjectApprovalPolicy(c *gin.Context) {
+	projectID, ok := h.parseProjectID(c)
+	if !ok {
+		return
+	}
+	h.deletePolicy(c, db.PolicyScopeProject, projectID)
+}
+
+func (h *Handler) savePolicy(c *gin.Context, scope string, scopeID int64) {
+	var policy tools.ApprovalPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		base.ErrorResponse(c, http.StatusBadRequest, base.ErrCodeInvalidParams, err.Error())
+		return
+	}
+
+	if err := h.approval.SavePolicy(scope, scopeID, &policy); err != nil {
+		base.ErrorResponse(c, http.StatusInternalServerError, base.ErrCodeInternalError, fmt.Sprintf("Failed to save approval policy: %v", err))
+		return
+	}
+
+	base.SuccessResponse(c, policy)
+}
+
+func (h *Handler) deletePolicy(c *gin.Context, scope string, scopeID int64) {
+	if err := h.approval.DeletePolicy(scope, scopeID); err != nil {
+		base.ErrorResponse(c, http.StatusInternalServerError, base.ErrCodeInternalError, fmt.Sprintf("Failed to delete approval policy: %v", err))
+		return
+	}
+
+	base.SuccessResponse(c, nil)
+}
+
+// parseSessionID reads the :id path param and checks that the session exists.
+func (h *Handler) parseSessionID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		base.ErrorResponse(c, http.StatusBadRequest, base.ErrCodeInvalidParams, "Invalid session ID")
+		return 0, false
+	}
+	if _, err := h.database.GetSession(id); err != nil {
+		base.ErrorResponse(c, http.StatusNotFound, base.ErrCodeNotFound, "Session not found")
+		return 0, false
+	}
+	return id, true
+}
+
+// parseProjectID reads the :id path param and checks that the project exists.
+func (h *Handler) parseProjectID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		base.ErrorResponse(c, http.StatusBadRequest, base.ErrCodeInvalidParams, "Invalid project ID")
+		return 0, false
+	}
+	if _, err := h.database.GetProject(id); err != nil {
+		base.ErrorResponse(c, http.StatusNotFound, base.ErrCodeNotFound, "Project not found")
+		return 0, false
+	}
+	return id, true
+}
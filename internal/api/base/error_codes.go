@@ -14,6 +14,9 @@ const (
 	// Task related errors
 	ErrCodeTaskNotRunning = 30001
 	ErrCodeTaskFailed     = 30002
+
+	// LLM related errors
+	ErrCodeRateLimited = 40001
 )
 
 // Error messages mapping
@@ -25,6 +28,7 @@ var ErrorMessages = map[int]string{
 	ErrCodeConnectionFail: "Connection failed",
 	ErrCodeTaskNotRunning: "Task is not running",
 	ErrCodeTaskFailed:     "Task execution failed",
+	ErrCodeRateLimited:    "LLM rate limit exceeded",
 }
 
 // GetErrorMessage returns the error message for a given error code
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"mind-weaver/internal/api/base"
+	"mind-weaver/internal/db"
+	"mind-weaver/internal/services"
+	"mind-weaver/internal/third/assistantmessage"
+	"mind-weaver/internal/third/tools"
+	"mind-weaver/pkg/logger"
+)
+
+type RestoreCheckpointResp struct {
+	Files []string `json:"files"` // 被恢复的文件
+}
+
+// executeTool 执行工具调用。编辑类工具执行前先把要修改的文件保存为检查点并关联到
+// 包含该工具调用的assistant消息，工具没有执行成功时删除检查点
+func (h *Handler) executeTool(sessionID, messageID int64, input tools.ExecutorInput) (*tools.ExecutorResult, error) {
+	var checkpoint *db.Checkpoint
+	// 创建目录不修改文件内容，不需要检查点
+	if paths := tools.EditPaths(input.ToolUse); len(paths) > 0 && input.ToolUse.Name != assistantmessage.CreateDirectory {
+		for i, p := range paths {
+			if !filepath.IsAbs(p) {
+				paths[i] = filepath.Join(input.Cwd, p)
+			}
+			paths[i] = filepath.Clean(paths[i])
+		}
+
+		var err error
+		checkpoint, err = h.checkpoints.Snapshot(sessionID, messageID, string(input.ToolUse.Name), paths)
+		if err != nil {
+			// 无法保存快照时不修改文件，避免改坏后无法恢复
+			return nil, fmt.Errorf("failed to create checkpoint before %s: %w", input.ToolUse.Name, err)
+		}
+	}
+
+	res, err := tools.ExecuteTool(input)
+	if checkpoint != nil && err == nil && res.IsError {
+		if delErr := h.checkpoints.DeleteCheckpoint(checkpoint.ID); delErr != nil {
+			logger.Errorf("Failed to delete unused checkpoint %d: %v", checkpoint.ID, delErr)
+		}
+	}
+	return res, err
+}
+
+// lastAssistantMessageID 返回最后一条assistant消息的ID，自动执行的工具调用就在这条消息中
+func lastAssistantMessageID(messages []*services.Message) int64 {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == services.MsgTypeAssistant {
+			return messages[i].Id
+		}
+	}
+	return 0
+}
+
+// GetSessionCheckpoints 获取会话的检查点
+// @Summary      获取会话的检查点列表
+// @Description  每次编辑类工具执行前都会保存被修改文件的快照，按创建顺序返回
+// @Tags         session
+// @Produce      json
+// @Param        id   path      int  true  "会话ID"
+// @Success      200  {object}  base.Response{data=[]db.Checkpoint}
+// @Failure      400  {object}  base.Response
+// @Failure      404  {object}  base.Response
+// @Failure      500  {object}  base.Response
+// @Router       /sessions/{id}/checkpoints [get]
+func (h *Handler) GetSessionCheckpoints(c *gin.Context) {
+	sessionID, ok := h.parseSessionID(c)
+	if !ok {
+		return
+	}
+
+	checkpoints, err := h.checkpoints.ListCheckpoints(sessionID)
+	if err != nil {
+		base.ErrorResponse(c, http.StatusInternalServerError, base.ErrCodeInternalError, fmt.Sprintf("Failed to get checkpoints: %v", err))
+		return
+	}
+
+	base.SuccessResponse(c, checkpoints)
+}
+
+// GetCheckpointDiff 查看检查点之后的修改
+// @Summary      对比检查点与当前工作区
+// @Description  返回该检查点及之后的检查点涉及的文件，相对于检查点时的内容发生的变化
+// @Tags         checkpoint
+// @Produce      json
+// @Param        id   path      int  true  "检查点ID"
+// @Success      200  {object}  base.Response{data=services.CheckpointDiff}
+// @Failure      400  {object}  base.Response
+// @Failure      404  {object}  base.Response
+// @Failure      500  {object}  base.Response
+// @Router       /checkpoints/{id}/diff [get]
+func (h *Handler) GetCheckpointDiff(c *gin.Context) {
+	checkpointID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		base.ErrorResponse(c, http.StatusBadRequest, base.ErrCodeInvalidParams, "Invalid checkpoint ID")
+		return
+	}
+
+	diff, err := h.checkpoints.Diff(checkpointID)
+	if errors.Is(err, services.ErrCheckpointNotFound) {
+		base.ErrorResponse(c, http.StatusNotFound, base.ErrCodeNotFound, "Checkpoint not found")
+		return
+	}
+	if err != nil {
+		base.ErrorResponse(c, http.StatusInternalServerError, base.ErrCodeInternalError, fmt.Sprintf("Failed to diff checkpoint: %v", err))
+		return
+	}
+
+	base.SuccessResponse(c, diff)
+}
+
+// RestoreCheckpoint 恢复到检查点
+// @Summary      将工作区恢复到检查点
+// @Description  该检查点及之后修改过的文件恢复为检查点时的内容，检查点时不存在的文件会被删除
+// @Tags         checkpoint
+// @Produce      json
+// @Param        id   path      int  true  "检查点ID"
+// @Success      200  {object}  base.Response{data=RestoreCheckpointResp}
+// @Failure      400  {object}  base.Response
+// @Failure      404  {object}  base.Response
+// @Failure      500  {object}  base.Response
+// @Router       /checkpoints/{id}/restore [post]
+func (h *Handler) RestoreCheckpoint(c *gin.Context) {
+	checkpointID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		base.ErrorResponse(c, http.StatusBadRequest, base.ErrCodeInvalidParams, "Invalid checkpoint ID")
+		return
+	}
+
+	files, err := h.checkpoints.Restore(checkpointID)
+	if errors.Is(err, services.ErrCheckpointNotFound) {
+		base.ErrorResponse(c, http.StatusNotFound, base.ErrCodeNotFound, "Checkpoint not found")
+		return
+	}
+	if err != nil {
+		base.ErrorResponse(c, http.StatusInternalServerError, base.ErrCodeInternalError, fmt.Sprintf("Failed to restore checkpoint (restored %v): %v", files, err))
+		return
+	}
+
+	logger.Infof("Restored checkpoint %d, files: %v", checkpointID, files)
+	base.SuccessResponse(c, RestoreCheckpointResp{Files: files})
+}
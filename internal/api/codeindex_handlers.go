@@ -0,0 +1,99 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"mind-weaver/internal/api/base"
+	"mind-weaver/internal/codeindex"
+	"mind-weaver/internal/db"
+	"mind-weaver/internal/services"
+)
+
+// CodeSearchRequest 语义代码搜索请求
+type CodeSearchRequest struct {
+	Query string `json:"query" binding:"required"` // 自然语言描述的查询，例如 "where do we handle refund retries"
+	Path  string `json:"path"`                     // 只搜索该目录或文件，相对项目根目录，为空时搜索整个项目
+	Limit int    `json:"limit"`                    // 返回的结果数，默认使用 code_index.max_results
+}
+
+// SearchCodeIndex 按含义搜索项目代码
+// @Summary      语义代码搜索
+// @Description  先增量更新项目的代码索引，再返回与查询最相似的代码分块，与 codebase_search 工具使用同一个索引
+// @Tags         project
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                true  "项目ID"
+// @Param        request  body      CodeSearchRequest  true  "查询"
+// @Success      200      {object}  base.Response{data=[]codeindex.Result}
+// @Failure      400      {object}  base.Response
+// @Failure      404      {object}  base.Response
+// @Failure      500      {object}  base.Response
+// @Router       /projects/{id}/code-index/search [post]
+func (h *Handler) SearchCodeIndex(c *gin.Context) {
+	var req CodeSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		base.ErrorResponse(c, http.StatusBadRequest, base.ErrCodeInvalidParams, err.Error())
+		return
+	}
+	project, ok := h.codeIndexProject(c)
+	if !ok {
+		return
+	}
+
+	results, err := h.codeIndex.Search(c.Request.Context(), project.Path, req.Query, req.Path, req.Limit)
+	if err != nil {
+		base.ErrorResponse(c, http.StatusInternalServerError, base.ErrCodeInternalError, fmt.Sprintf("Failed to search code index: %v", err))
+		return
+	}
+	if results == nil {
+		results = []codeindex.Result{}
+	}
+	base.SuccessResponse(c, results)
+}
+
+// RebuildCodeIndex 重建项目的代码索引
+// @Summary      重建代码索引
+// @Description  删除项目的代码索引并重新为所有文件生成向量，修改了向量模型或分块设置后使用，平时索引会在搜索时自动增量更新
+// @Tags         project
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "项目ID"
+// @Success      200  {object}  base.Response{data=services.CodeIndexStats}
+// @Failure      400  {object}  base.Response
+// @Failure      404  {object}  base.Response
+// @Failure      500  {object}  base.Response
+// @Router       /projects/{id}/code-index/rebuild [post]
+func (h *Handler) RebuildCodeIndex(c *gin.Context) {
+	project, ok := h.codeIndexProject(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.codeIndex.Rebuild(c.Request.Context(), project.Path)
+	if err != nil {
+		base.ErrorResponse(c, http.StatusInternalServerError, base.ErrCodeInternalError, fmt.Sprintf("Failed to rebuild code index: %v", err))
+		return
+	}
+	base.SuccessResponse(c, stats)
+}
+
+// codeIndexProject 读取路径中的项目，代码索引未启用时返回错误
+func (h *Handler) codeIndexProject(c *gin.Context) (*db.Project, bool) {
+	id, ok := h.parseProjectID(c)
+	if !ok {
+		return nil, false
+	}
+	if h.codeSearcher() == nil {
+		base.ErrorResponse(c, http.StatusBadRequest, base.ErrCodeInvalidParams, services.ErrCodeIndexDisabled.Error())
+		return nil, false
+	}
+	project, err := h.database.GetProject(id)
+	if err != nil {
+		base.ErrorResponse(c, http.StatusNotFound, base.ErrCodeNotFound, "Project not found")
+		return nil, false
+	}
+	return project, true
+}
@@ -4,6 +4,8 @@ import (
 	"mind-weaver/config"
 	"mind-weaver/internal/db"
 	"mind-weaver/internal/services"
+	"mind-weaver/internal/third/mcp"
+	"mind-weaver/internal/third/tools"
 )
 
 type Handler struct {
@@ -11,6 +13,15 @@ type Handler struct {
 	contextService *services.ContextService
 	sessionService *services.SessionService
 	aiService      *services.AIService
+	usageService   *services.UsageService
+	history        *services.HistoryService
+	repoMap        *services.RepoMapService
+	codeIndex      *services.CodeIndexService
+	completions    *services.CompletionRegistry
+	approval       *services.ApprovalService
+	checkpoints    *services.CheckpointService
+	browsers       *services.BrowserService
+	mcpHub         *mcp.Hub
 	database       *db.Database
 	cfg            config.Config
 
@@ -24,6 +35,15 @@ func NewHandler(
 	contextService *services.ContextService,
 	sessionService *services.SessionService,
 	aiService *services.AIService,
+	usageService *services.UsageService,
+	history *services.HistoryService,
+	repoMap *services.RepoMapService,
+	codeIndex *services.CodeIndexService,
+	completions *services.CompletionRegistry,
+	approval *services.ApprovalService,
+	checkpoints *services.CheckpointService,
+	browsers *services.BrowserService,
+	mcpHub *mcp.Hub,
 	commandService *services.CommandService,
 	swaggerService *services.SwaggerService,
 	database *db.Database,
@@ -34,8 +54,25 @@ func NewHandler(
 		contextService: contextService,
 		sessionService: sessionService,
 		aiService:      aiService,
+		usageService:   usageService,
+		history:        history,
+		repoMap:        repoMap,
+		codeIndex:      codeIndex,
+		completions:    completions,
+		approval:       approval,
+		checkpoints:    checkpoints,
+		browsers:       browsers,
+		mcpHub:         mcpHub,
 		database:       database,
 		cfg:            *cfg,
 		commandService: commandService,
 	}
 }
+
+// codeSearcher 返回 codebase_search 使用的代码索引，未启用时返回 nil
+func (h *Handler) codeSearcher() tools.CodeSearcher {
+	if h.codeIndex == nil || !h.codeIndex.Enabled() {
+		return nil
+	}
+	return h.codeIndex
+}
@@ -1,22 +1,27 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"mind-weaver/internal/api/base"
+	"mind-weaver/internal/db"
+	"mind-weaver/internal/llm"
 	"mind-weaver/internal/services"
 	"mind-weaver/internal/third/assistantmessage"
 	thirdPrompts "mind-weaver/internal/third/prompts"
 	"mind-weaver/internal/third/prompts/sections"
 	"mind-weaver/internal/third/tools"
+	"mind-weaver/internal/tokenizer"
 	"mind-weaver/internal/utils"
 	"mind-weaver/pkg/logger"
 	"mind-weaver/pkg/prompts"
@@ -39,6 +44,8 @@ type OpenAICompatRequest struct {
 	ProjectPath  string                      `json:"project_path" binding:"required"`
 	Content      string                      `json:"content,omitempty"`
 	ToolUse      assistantmessage.ToolUseReq `json:"tool_use,omitempty"`
+	Agent        bool                        `json:"agent,omitempty"`     // 智能模式下由服务端自动循环执行工具，直到任务完成或需要用户介入
+	MaxSteps     int                         `json:"max_steps,omitempty"` // 自动循环最多执行的工具步数，不传时使用配置 agent.max_steps
 }
 
 // ChatMessage matches OpenAI message format
@@ -55,6 +62,8 @@ type OpenAICompatResponse struct {
 	Model   string    `json:"model"`
 	Choices []Choice  `json:"choices"`
 	Usage   UsageInfo `json:"usage"`
+	// Agent 是自动循环的执行结果，仅在请求带 agent 时返回
+	Agent *services.AgentResult `json:"agent,omitempty"`
 }
 
 // Choice represents a completion choice
@@ -74,13 +83,14 @@ type UsageInfo struct {
 
 // OpenAICompatStreamHandler handles OpenAI-compatible streaming completions
 // @Summary      OpenAI-compatible streaming completions
-// @Description  Provides an OpenAI-compatible streaming API endpoint for chat completions
+// @Description  Provides an OpenAI-compatible streaming API endpoint for chat completions. In auto mode, "agent": true lets the server run the tool loop until attempt_completion, ask_followup_question, the step budget or a tool that needs approval
 // @Tags         session
 // @Accept       json
 // @Produce      text/event-stream
 // @Param        request body api.OpenAICompatRequest true "Completion request"
 // @Success      200  {object}  object  "SSE stream of completion chunks"
 // @Failure      400  {object}  base.Response
+// @Failure      429  {object}  base.Response  "LLM rate limit exceeded"
 // @Failure      500  {object}  base.Response
 // @Router       /sessions/{id}/completions [post]
 func (h *Handler) OpenAICompatStreamHandler(c *gin.Context) {
@@ -92,6 +102,12 @@ func (h *Handler) OpenAICompatStreamHandler(c *gin.Context) {
 		return
 	}
 
+	// 带 Last-Event-ID 的请求是断线重连，补发错过的事件并继续输出正在进行的回复
+	if lastEventID, ok := parseLastEventID(c); ok {
+		h.resumeCompletion(c, req.SessionID, 0, lastEventID)
+		return
+	}
+
 	if req.Model == "" {
 		req.Model = "qt-claude37" // Default model
 	}
@@ -159,43 +175,55 @@ func (h *Handler) OpenAICompatStreamHandler(c *gin.Context) {
 
 	// Handle streaming response
 	if req.Stream {
-		c.Header("Content-Type", "text/event-stream")
-		c.Header("Cache-Control", "no-cache")
-		c.Header("Connection", "keep-alive")
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Transfer-Encoding", "chunked")
-
-		clientGone := c.Writer.CloseNotify()
-		doneCh := make(chan bool)
+		setSSEHeaders(c)
 
+		// 回复在独立的协程中生成，事件写入可重放的缓存，客户端断线后可以通过 Last-Event-ID 重连；
+		// 调用停止接口或所有客户端断开超过等待时间后 ctx 会被取消，上游的模型请求也随之中止
+		ctx, completion, release := h.completions.Start(req.SessionID, userMsg.ID)
 		go func() {
-			defer close(doneCh)
-
+			defer release()
 			switch sessionInfo.Mode {
 			case services.SessionModeManual:
-				h.streamManual(c, req, historyMessages, systemtPrompt, userMsg)
-			case services.SessionModeAuto,
-				services.SessionModeSingleHtml:
-				h.streamByLine(c, req, historyMessages, systemtPrompt, userMsg, sessionInfo)
+				h.streamManual(ctx, completion.Stream, req, historyMessages, systemtPrompt, userMsg)
+			case services.SessionModeAuto:
+				if req.Agent {
+					h.streamAgent(ctx, completion.Stream, req, historyMessages, systemtPrompt, userMsg, sessionInfo)
+				} else {
+					h.streamByLine(ctx, completion.Stream, req, historyMessages, systemtPrompt, userMsg, sessionInfo)
+				}
+			case services.SessionModeSingleHtml:
+				h.streamByLine(ctx, completion.Stream, req, historyMessages, systemtPrompt, userMsg, sessionInfo)
 			}
 		}()
 
-		select {
-		case <-clientGone:
-			return
-		case <-doneCh:
-			return
-		}
+		h.pipeCompletion(c, completion, 0)
+	} else if req.Agent && sessionInfo.Mode == services.SessionModeAuto {
+		h.runAgent(c, req, historyMessages, systemtPrompt, userMsg, sessionInfo)
 	} else {
-		resContent, err := h.aiService.Chat(systemtPrompt, req.Content, historyMessages, req.Model)
+		historyMessages = h.history.Fit(c.Request.Context(), req.SessionID, req.Model, systemtPrompt, req.Content, historyMessages)
+		resContent, usage, err := h.aiService.Chat(c.Request.Context(), systemtPrompt, req.Content, historyMessages, req.Model)
 		if err != nil {
+			if llm.IsRateLimited(err) {
+				base.ErrorResponse(c, http.StatusTooManyRequests, base.ErrCodeRateLimited, err.Error())
+				return
+			}
 			base.ErrorResponse(c, http.StatusInternalServerError, base.ErrCodeInternalError,
 				fmt.Sprintf("Failed to get AI response: %v", err))
 			return
 		}
 
 		// Save the response to the database
-		h.database.AddMessage(req.SessionID, services.MsgTypeAssistant, resContent)
+		msgId, _ := h.database.AddMessage(req.SessionID, services.MsgTypeAssistant, resContent)
+		h.usageService.RecordUsage(req.SessionID, msgId, req.Model, usage)
+
+		usageInfo := UsageInfo{}
+		if usage != nil {
+			usageInfo = UsageInfo{
+				PromptTokens:     usage.PromptTokens,
+				CompletionTokens: usage.CompletionTokens,
+				TotalTokens:      usage.TotalTokens,
+			}
+		}
 
 		// Return OpenAI compatible response
 		response := OpenAICompatResponse{
@@ -213,17 +241,42 @@ func (h *Handler) OpenAICompatStreamHandler(c *gin.Context) {
 					FinishReason: "stop",
 				},
 			},
-			Usage: UsageInfo{
-				PromptTokens:     0, // We're not tracking tokens
-				CompletionTokens: 0,
-				TotalTokens:      0,
-			},
+			Usage: usageInfo,
 		}
 
 		c.JSON(http.StatusOK, response)
 	}
 }
 
+// messageStatus 根据 ctx 的取消原因返回回复消息保存时的状态
+func messageStatus(ctx context.Context) string {
+	switch {
+	case services.IsStopped(ctx):
+		return db.MessageStatusStopped
+	case ctx.Err() != nil:
+		return db.MessageStatusInterrupted
+	}
+	return db.MessageStatusComplete
+}
+
+// streamDoneStatus 返回SSE完成事件中的 status
+func streamDoneStatus(status string) string {
+	if status == db.MessageStatusStopped {
+		return "stopped"
+	}
+	return "complete"
+}
+
+// writeStreamError 在SSE流中输出错误信息，限流错误使用单独的错误码方便前端提示稍后重试
+func writeStreamError(w io.Writer, err error) {
+	code := base.ErrCodeInternalError
+	if llm.IsRateLimited(err) {
+		code = base.ErrCodeRateLimited
+	}
+	data, _ := json.Marshal(map[string]any{"error": err.Error(), "code": code})
+	fmt.Fprintf(w, "data: %s\n\n", data)
+}
+
 func (h *Handler) switchManualType(c *gin.Context, req OpenAICompatRequest, historyMessages []*services.Message) (string, *services.MessageInfo, error) {
 	var err error
 	systemtPrompt := ""
@@ -277,14 +330,17 @@ func (h *Handler) switchAutoType(c *gin.Context, req OpenAICompatRequest, histor
 		args := thirdPrompts.BuildSystemPromptArgs{
 			EnvCtx: thirdPrompts.EnvironmentContext{
 				Cwd:                 req.ProjectPath,
-				SupportsComputerUse: false,
-				BrowserViewportSize: "",
+				SupportsComputerUse: h.browsers.Enabled(),
+				BrowserViewportSize: h.browsers.ViewportSize(),
 				Language:            "zh-cn",
+				McpServers:          h.mcpHub.Servers(),
+				RepoMap:             h.repoMapForPrompt(req),
+				CodebaseSearch:      h.codeSearcher() != nil,
 			},
 			Mode:               sections.ModeSlug("code"),
 			CustomModeConfigs:  nil,
 			GlobalInstructions: "",
-			// Note: DiffStrategy and RooIgnoreController are nil here
+			DiffStrategy:       services.NewDiffStrategy(h.cfg, req.Model),
 		}
 
 		// Generate the system prompt
@@ -307,6 +363,25 @@ func (h *Handler) switchAutoType(c *gin.Context, req OpenAICompatRequest, histor
 	return systemtPrompt, userMsg, nil
 }
 
+// repoMapForPrompt 生成智能模式第一轮系统提示词中的仓库地图，没有开启或生成失败时返回空
+func (h *Handler) repoMapForPrompt(req OpenAICompatRequest) string {
+	if !h.repoMap.Enabled() || req.ProjectPath == "" {
+		return ""
+	}
+	model := h.cfg.LLM.GetCurrentLLMInfo(req.Model)
+	maxTokens := h.cfg.RepoMap.GetMaxTokens()
+	if model.MaxContext > 0 {
+		// 小上下文的模型不能让地图占掉太多空间
+		maxTokens = min(maxTokens, model.MaxContext/8)
+	}
+	repoMap, err := h.repoMap.Generate(req.ProjectPath, tokenizer.ForModel(model.Name, model.Tokenizer), maxTokens, req.Content)
+	if err != nil {
+		logger.Errorf("Failed to generate repository map of %s: %v", req.ProjectPath, err)
+		return ""
+	}
+	return repoMap
+}
+
 func (h *Handler) switchSingleHtmlSystemPrompt(c *gin.Context, req OpenAICompatRequest, historyMessages []*services.Message) (string, *services.MessageInfo, error) {
 	var err error
 	systemtPrompt := ""
@@ -343,32 +418,33 @@ func (h *Handler) AutoToolUse(c *gin.Context, req OpenAICompatRequest, historyMe
 		ToolUse:             req.ToolUse.ToolUse,
 		Ctx:                 c.Request.Context(),
 		Cwd:                 req.ProjectPath,
-		DiffStrategy:        nil,
+		DiffStrategy:        services.NewDiffStrategy(h.cfg, req.Model),
 		RooIgnoreController: nil,
 		Confirmed:           req.ToolUse.Confirmed,
+		Fetch:               services.NewFetchOptions(h.cfg),
+		Browser:             h.browsers.Session(req.SessionID),
+		Mcp:                 h.mcpHub,
+		CodeIndex:           h.codeSearcher(),
 	}
 
 	var executeRes *tools.ExecutorResult
-	// 检查用户是否同意使用工具
+	// 检查用户是否同意使用工具，用户拒绝时即使策略允许也不执行
 	if !req.ToolUse.Confirmed {
 		errText := fmt.Sprintf("Tool '%s' not approved by user.", req.ToolUse.ToolUse.Name)
 		executeRes = &tools.ExecutorResult{Result: thirdPrompts.FormatToolError(errText), IsError: true}
 	} else {
-		executeParams.RooIgnoreController = nil
-		executeRes, err = tools.ExecuteTool(executeParams)
+		// 用户确认过的工具仍然要经过批准策略，被拒绝的工具不会执行
+		policy, policyErr := h.approval.GetSessionPolicy(req.SessionID)
+		if policyErr != nil {
+			return systemtPrompt, userMsg, policyErr
+		}
+		executeParams.Approval = policy.Policy
+		executeRes, err = h.executeTool(req.SessionID, lastAssistantMessageID(historyMessages), executeParams)
 	}
+	// 添加用户消息
+	userMsg, err = h.sessionService.AddUserMessage(req.SessionID, services.ToolResultMessage(executeRes, err))
 	if err != nil {
-		userMsg, err = h.sessionService.AddUserMessage(req.SessionID, err.Error())
-		if err != nil {
-			return systemtPrompt, userMsg, err
-		}
-	} else {
-		// 添加用户消息
-		content := fmt.Sprintf("%s。一次回复只能使用一个工具", executeRes.Result)
-		userMsg, err = h.sessionService.AddUserMessage(req.SessionID, content)
-		if err != nil {
-			return systemtPrompt, userMsg, err
-		}
+		return systemtPrompt, userMsg, err
 	}
 
 	return systemtPrompt, userMsg, nil
@@ -484,98 +560,179 @@ func normalizeMessageSequence(messages []*services.Message) []*services.Message
 	return result
 }
 
-func (h *Handler) streamManual(c *gin.Context, req OpenAICompatRequest, historyMessages []*services.Message, systemtPrompt string, userMsg *services.MessageInfo) {
+func (h *Handler) streamManual(ctx context.Context, out utils.FlushWriter, req OpenAICompatRequest, historyMessages []*services.Message, systemtPrompt string, userMsg *services.MessageInfo) {
 	// 创建缓冲区以收集完整响应
 	var responseBuffer strings.Builder
 
 	// 自定义写入器，直接发送数据到客户端
 	writer := &utils.SseWriter{
-		ResponseWriter: c.Writer,
+		ResponseWriter: out,
 		Buffer:         &responseBuffer,
 		UserMsgId:      userMsg.ID,
 	}
 
+	// 历史消息超出模型上下文时先压缩
+	historyMessages = h.history.Fit(ctx, req.SessionID, req.Model, systemtPrompt, userMsg.Content, historyMessages)
+
 	// 生成流式响应
-	err := h.aiService.ChatStream(systemtPrompt, userMsg.Content, historyMessages, req.Model, writer)
-	if err != nil {
+	usage, err := h.aiService.ChatStream(ctx, systemtPrompt, userMsg.Content, historyMessages, req.Model, writer)
+	status := messageStatus(ctx)
+	interrupted := status == db.MessageStatusInterrupted
+	if err != nil && ctx.Err() == nil {
 		// 如果出现错误，我们仍要保存已获得的内容
-		fmt.Fprintf(c.Writer, "data: {\"error\":\"%v\"}\n\n", err)
+		writeStreamError(out, err)
 	}
 
 	// 将完整响应保存到数据库
 	aiRes := responseBuffer.String()
+	var msgId int64
 	if responseBuffer.Len() > 0 {
-		msgId, err := h.database.AddMessage(req.SessionID, services.MsgTypeAssistant, aiRes)
+		msgId, err = h.database.AddMessageWithStatus(req.SessionID, services.MsgTypeAssistant, aiRes, status)
 		fmt.Printf("msgId: %v\n", msgId)
 		if err != nil {
 			logger.Errorf("Failed to add message to database: %v", err)
 		} else {
 			// 如果消息ID不为0，则将消息ID保存到预备消息中
-			if msgId != 0 {
+			if msgId != 0 && !interrupted {
 				writer.MsgId = msgId
 				writer.Write([]byte("\n\n"))
 			}
 		}
 	}
+	// 记录本次调用的token消耗
+	h.usageService.RecordUsage(req.SessionID, msgId, req.Model, usage)
+
+	if interrupted {
+		logger.Infof("Stream of session %d interrupted by client, saved %d bytes", req.SessionID, len(aiRes))
+		return
+	}
+
+	// 发送完成事件，被停止时状态为 stopped
+	fmt.Fprintf(out, "data: [DONE]\ndata: {\"status\":%q}\n\n", streamDoneStatus(status))
+	out.Flush()
+}
+
+func (h *Handler) streamByLine(ctx context.Context, out utils.FlushWriter, req OpenAICompatRequest, historyMessages []*services.Message, systemtPrompt string, userMsg *services.MessageInfo, sessionInfo *services.SessionInfo) {
+	turn := h.streamByLineTurn(ctx, out, req, historyMessages, systemtPrompt, userMsg, sessionInfo)
+	if turn.Status == db.MessageStatusInterrupted {
+		return
+	}
+
+	// 发送完成事件，被停止时状态为 stopped
+	fmt.Fprintf(out, "data: [DONE]\ndata: {\"status\":%q}\n\n", streamDoneStatus(turn.Status))
+	out.Flush()
+}
 
-	// 发送完成事件
-	fmt.Fprintf(c.Writer, "data: [DONE]\ndata: {\"status\":\"complete\"}\n\n")
-	c.Writer.Flush()
+// lineTurn 是一次按行流式请求模型的结果
+type lineTurn struct {
+	Content string
+	MsgID   int64
+	Usage   *llm.Usage
+	Status  string
+	Err     error
 }
 
-func (h *Handler) streamByLine(c *gin.Context, req OpenAICompatRequest, historyMessages []*services.Message, systemtPrompt string, userMsg *services.MessageInfo, sessionInfo *services.SessionInfo) {
+// streamByLineTurn 请求一次模型并按行输出，保存回复、记录用量并发送结束标记，但不发送完成事件，
+// 智能模式的自动循环会连续调用多次
+func (h *Handler) streamByLineTurn(ctx context.Context, out utils.FlushWriter, req OpenAICompatRequest, historyMessages []*services.Message, systemtPrompt string, userMsg *services.MessageInfo, sessionInfo *services.SessionInfo) lineTurn {
 	var err error
+	var usage *llm.Usage
 	// 创建缓冲区以收集完整响应
 	var responseBuffer strings.Builder
 
 	// 自定义写入器，按行发送数据到客户端
 	writer := &services.SseLineWriter{
-		ResponseWriter: c.Writer,
+		ResponseWriter: out,
 		Buffer:         &responseBuffer,
 		Mode:           sessionInfo.Mode,
 		Cwd:            req.ProjectPath,
 		UserMsgId:      userMsg.ID,
 	}
+	if sessionInfo.Mode == services.SessionModeSingleHtml {
+		if err := h.useSingleHtmlTools(writer, req.SessionID, userMsg.ID); err != nil {
+			writeStreamError(out, err)
+			return lineTurn{Status: messageStatus(ctx), Err: err}
+		}
+	}
+
+	// 历史消息超出模型上下文时先压缩，自动循环中历史每一步都会变长
+	historyMessages = h.history.Fit(ctx, req.SessionID, req.Model, systemtPrompt, userMsg.Content, historyMessages)
 
 	switch sessionInfo.Mode {
 	case services.SessionModeAuto:
-		err = h.aiService.ChatStreamByLine(systemtPrompt, userMsg.Content, historyMessages, req.Model, writer, sessionInfo.Mode, false)
+		usage, err = h.aiService.ChatStreamByLine(ctx, systemtPrompt, userMsg.Content, historyMessages, req.Model, writer, sessionInfo.Mode, false)
 	case services.SessionModeSingleHtml:
-		err = h.streamModeSingleHtml(c, systemtPrompt, userMsg.Content, historyMessages, &req, writer, &responseBuffer, sessionInfo.Mode)
+		usage, err = h.streamModeSingleHtml(ctx, out, systemtPrompt, userMsg.Content, historyMessages, &req, writer, &responseBuffer, sessionInfo.Mode)
 	}
 	// 生成按行流式响应
-	if err != nil {
+	status := messageStatus(ctx)
+	interrupted := status == db.MessageStatusInterrupted
+	if err != nil && ctx.Err() == nil {
 		// 如果出现错误，我们仍要保存已获得的内容
-		fmt.Fprintf(c.Writer, "data: {\"error\":\"%v\"}\n\n", err)
+		writeStreamError(out, err)
 	}
+	turn := lineTurn{Usage: usage, Status: status, Err: err}
 
 	// 将完整响应保存到数据库
 	aiRes := responseBuffer.String()
+	var msgId int64
 	if responseBuffer.Len() > 0 {
-		msgId, err := h.database.AddMessage(req.SessionID, services.MsgTypeAssistant, aiRes)
+		msgId, err = h.database.AddMessageWithStatus(req.SessionID, services.MsgTypeAssistant, aiRes, status)
 		if err != nil {
 			logger.Errorf("Failed to add message to database: %v", err)
 		} else {
 			// 如果消息ID不为0，则将消息ID保存到预备消息中
-			if msgId != 0 {
+			if msgId != 0 && !interrupted {
 				writer.MsgId = msgId
 				writer.Write([]byte("\n\n"))
 			}
 		}
 	}
+	// 记录本次调用的token消耗
+	h.usageService.RecordUsage(req.SessionID, msgId, req.Model, usage)
+	turn.Content = responseBuffer.String()
+	turn.MsgID = msgId
+
+	if interrupted {
+		logger.Infof("Stream of session %d interrupted by client, saved %d bytes", req.SessionID, len(aiRes))
+		return turn
+	}
 
 	// 发送结束标记
 	if _, err := writer.Write([]byte(services.StreamMsgEndTag)); err != nil {
 		logger.Infof("Write error: %v", err)
 	}
+	return turn
+}
+
+// useSingleHtmlTools 让 single-html 模式的写文件经过会话的批准策略并保存检查点。
+// 流式输出时页面会被反复写入，每个文件只在第一次写入前保存检查点
+func (h *Handler) useSingleHtmlTools(writer *services.SseLineWriter, sessionID, messageID int64) error {
+	policy, err := h.approval.GetSessionPolicy(sessionID)
+	if err != nil {
+		return err
+	}
+	writer.Approval = policy.Policy
 
-	// 发送完成事件
-	fmt.Fprintf(c.Writer, "data: [DONE]\ndata: {\"status\":\"complete\"}\n\n")
-	c.Writer.Flush()
+	checkpointed := map[string]bool{}
+	writer.ExecuteTool = func(input tools.ExecutorInput) (*tools.ExecutorResult, error) {
+		path := input.ToolUse.Params[string(assistantmessage.Path)]
+		if checkpointed[path] {
+			return tools.ExecuteTool(input)
+		}
+		res, err := h.executeTool(sessionID, messageID, input)
+		if err == nil && !res.IsError {
+			checkpointed[path] = true
+		}
+		return res, err
+	}
+	return nil
 }
 
-func (h *Handler) streamModeSingleHtml(c *gin.Context, sysPrompt string, prompt string, historyMsgs []*services.Message, req *OpenAICompatRequest, writer io.Writer, responseBuffer *strings.Builder, mode string) error {
+// streamModeSingleHtml 可能会多次请求模型续写，返回的 usage 是所有轮次的累计值
+func (h *Handler) streamModeSingleHtml(ctx context.Context, out utils.FlushWriter, sysPrompt string, prompt string, historyMsgs []*services.Message, req *OpenAICompatRequest, writer io.Writer, responseBuffer *strings.Builder, mode string) (*llm.Usage, error) {
 	var err error
+	totalUsage := &llm.Usage{}
 	isFinish := false
 	limit := 5
 	path := ""
@@ -583,10 +740,16 @@ func (h *Handler) streamModeSingleHtml(c *gin.Context, sysPrompt string, prompt
 	aiResList := []string{}
 
 	for !isFinish {
-		err = h.aiService.ChatStreamByLine(sysPrompt, prompt, historyMsgs, req.Model, writer, mode, isContinue)
+		var usage *llm.Usage
+		usage, err = h.aiService.ChatStreamByLine(ctx, sysPrompt, prompt, historyMsgs, req.Model, writer, mode, isContinue)
+		totalUsage.Add(usage)
+		if ctx.Err() != nil {
+			// 客户端已断开或被停止，不再发起后续的续写请求
+			break
+		}
 		if err != nil {
 			// 如果出现错误，我们仍要保存已获得的内容
-			fmt.Fprintf(c.Writer, "data: {\"error\":\"%v\"}\n\n", err)
+			writeStreamError(out, err)
 			break
 		}
 		aiRes := responseBuffer.String()
@@ -623,7 +786,7 @@ func (h *Handler) streamModeSingleHtml(c *gin.Context, sysPrompt string, prompt
 		// 限制最长次数，避免无限循环
 		limit -= 1
 		if limit <= 0 {
-			fmt.Fprintf(c.Writer, "data: {\"error\":\"生成长度达到最长限制，当前限制最长询问次数：limit=%v\"}\n\n", limit)
+			fmt.Fprintf(out, "data: {\"error\":\"生成长度达到最长限制，当前限制最长询问次数：limit=%v\"}\n\n", limit)
 			break
 		}
 
@@ -668,15 +831,15 @@ func (h *Handler) streamModeSingleHtml(c *gin.Context, sysPrompt string, prompt
 	// 1. 将生成的代码文件使用无头浏览器打开，确认控制台是否报错
 	// 2. 如果有报错，那么就调用大模型解决拼接冲突
 	// 3. 再次验证文件，如果还是报错，那么将文件发给模型进行排查
-	if len(aiResList) > 1 && h.cfg.Bin.Python != "" {
+	if len(aiResList) > 1 && h.cfg.Bin.Python != "" && ctx.Err() == nil {
 		fullpath := req.ProjectPath + "/" + path
-		h.handleLlmResponseError(aiResList, fullpath)
+		h.handleLlmResponseError(ctx, aiResList, fullpath)
 	}
 
-	return nil
+	return totalUsage, nil
 }
 
-func (h *Handler) handleLlmResponseError(aiResList []string, fullpath string) error {
+func (h *Handler) handleLlmResponseError(ctx context.Context, aiResList []string, fullpath string) error {
 	_, outputs, err := h.commandService.JsInspector(fullpath)
 	if err != nil {
 		logger.Errorf("handleLlmResponseError h.commandService.JsInspector return err: %v", err)
@@ -771,7 +934,7 @@ func (h *Handler) handleLlmResponseError(aiResList []string, fullpath string) er
 		}
 
 		logger.Infof("prompt: %v, model: %v", prompt, h.cfg.DiffModel)
-		response, err := h.aiService.Chat("", prompt, nil, h.cfg.DiffModel)
+		response, _, err := h.aiService.Chat(ctx, "", prompt, nil, h.cfg.DiffModel)
 		if err != nil {
 			logger.Infof("handleLlmResponseError AI服务调用失败: %v", err)
 			return fmt.Errorf("AI服务调用失败: %w", err)
@@ -819,6 +982,150 @@ func outputsToString(outputs []util.CommandOutput) string {
 	return result.String()
 }
 
+func setSSEHeaders(c *gin.Context) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("Access-Control-Allow-Origin", "*")
+	c.Header("Transfer-Encoding", "chunked")
+}
+
+// parseLastEventID reads the Last-Event-ID header, or the last_event_id query
+// parameter for clients that cannot set headers.
+func parseLastEventID(c *gin.Context) (int64, bool) {
+	value := c.GetHeader("Last-Event-ID")
+	if value == "" {
+		value = c.Query("last_event_id")
+	}
+	if value == "" {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// pipeCompletion 将回复的事件从 lastEventID 之后开始输出给客户端，直到回复结束或客户端断开
+func (h *Handler) pipeCompletion(c *gin.Context, completion *services.RunningCompletion, lastEventID int64) {
+	completion.Attach()
+	defer completion.Detach()
+
+	for {
+		events, closed, wait := completion.Stream.EventsAfter(lastEventID)
+		for _, event := range events {
+			fmt.Fprintf(c.Writer, "id: %d\n%s", event.ID, event.Data)
+			lastEventID = event.ID
+		}
+		if len(events) > 0 {
+			c.Writer.Flush()
+			continue
+		}
+		if closed {
+			return
+		}
+
+		select {
+		case <-wait:
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
+
+func (h *Handler) resumeCompletion(c *gin.Context, sessionID, userMsgID, lastEventID int64) {
+	completion := h.completions.Find(sessionID, userMsgID)
+	if completion == nil {
+		base.ErrorResponse(c, http.StatusNotFound, base.ErrCodeNotFound, "No completion to resume")
+		return
+	}
+
+	logger.Infof("Resume completion of session %d, user message %d after event %d", sessionID, completion.UserMsgID, lastEventID)
+	setSSEHeaders(c)
+	h.pipeCompletion(c, completion, lastEventID)
+}
+
+// ResumeCompletion 断线重连，继续接收正在生成的回复
+// @Summary      断线重连正在生成的回复
+// @Description  根据 Last-Event-ID 请求头(或 last_event_id 参数)补发错过的SSE事件，然后继续实时输出，回复结束后事件会保留一段时间
+// @Tags         session
+// @Produce      text/event-stream
+// @Param        id             path   int  true   "会话ID"
+// @Param        user_msg_id    query  int  false  "用户消息ID，不传时为该会话最近的回复"
+// @Param        last_event_id  query  int  false  "最后收到的事件ID，优先使用 Last-Event-ID 请求头"
+// @Success      200  {object}  object  "SSE stream of completion chunks"
+// @Failure      400  {object}  base.Response
+// @Failure      404  {object}  base.Response
+// @Router       /sessions/{id}/completions/stream [get]
+func (h *Handler) ResumeCompletion(c *gin.Context) {
+	idStr := c.Param("id")
+	sessionID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		base.ErrorResponse(c, http.StatusBadRequest, base.ErrCodeInvalidParams, "Invalid session ID")
+		return
+	}
+
+	var userMsgID int64
+	if value := c.Query("user_msg_id"); value != "" {
+		userMsgID, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			base.ErrorResponse(c, http.StatusBadRequest, base.ErrCodeInvalidParams, "Invalid user message ID")
+			return
+		}
+	}
+
+	lastEventID, _ := parseLastEventID(c)
+	h.resumeCompletion(c, sessionID, userMsgID, lastEventID)
+}
+
+type StopCompletionReq struct {
+	UserMsgID int64 `json:"user_msg_id"` // 要停止的回复对应的用户消息ID，为0时停止该会话下所有正在生成的回复
+}
+
+type StopCompletionResp struct {
+	Stopped []*services.RunningCompletion `json:"stopped"`
+}
+
+// StopCompletion 停止正在生成的回复
+// @Summary      停止正在生成的回复
+// @Description  取消上游模型请求，已生成的内容会被保存，流式连接最后会收到 status 为 stopped 的完成事件
+// @Tags         session
+// @Accept       json
+// @Produce      json
+// @Param        id  path      int  true  "会话ID"
+// @Param        request  body  StopCompletionReq  false  "要停止的回复"
+// @Success      200  {object}  base.Response{data=StopCompletionResp}
+// @Failure      400  {object}  base.Response
+// @Failure      404  {object}  base.Response
+// @Router       /sessions/{id}/completions/stop [post]
+func (h *Handler) StopCompletion(c *gin.Context) {
+	idStr := c.Param("id")
+	sessionID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		base.ErrorResponse(c, http.StatusBadRequest, base.ErrCodeInvalidParams, "Invalid session ID")
+		return
+	}
+
+	var req StopCompletionReq
+	// 请求体可以为空
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			base.ErrorResponse(c, http.StatusBadRequest, base.ErrCodeInvalidParams, err.Error())
+			return
+		}
+	}
+
+	stopped := h.completions.Stop(sessionID, req.UserMsgID)
+	if len(stopped) == 0 {
+		base.ErrorResponse(c, http.StatusNotFound, base.ErrCodeNotFound, "No running completion found")
+		return
+	}
+
+	logger.Infof("Stopped %d completion(s) of session %d", len(stopped), sessionID)
+	base.SuccessResponse(c, StopCompletionResp{Stopped: stopped})
+}
+
 type ParseAiContentReq struct {
 	Content string `json:"content"`
 }
@@ -0,0 +1,19 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"mind-weaver/internal/api/base"
+)
+
+// GetMcpServers 获取配置的 MCP 服务器及其工具和资源
+// @Summary      获取MCP服务器列表
+// @Description  获取配置的 MCP 服务器的连接状态、工具和资源
+// @Tags         mcp
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  base.Response{data=[]mcp.ServerInfo}
+// @Router       /mcp/servers [get]
+func (h *Handler) GetMcpServers(c *gin.Context) {
+	base.SuccessResponse(c, h.mcpHub.Servers())
+}
@@ -39,7 +39,7 @@ func (h *Handler) GetModels(c *gin.Context) {
 			MaxTokens:    model.MaxTokens,
 			Capabilities: model.Capabilities,
 			IsChatModel:  model.IsChatModel,
-			Temperature:  model.Temperature,
+			Temperature:  model.GetTemperature(h.cfg.LLM.Temperature),
 		}
 	}
 
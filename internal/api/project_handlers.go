@@ -4,11 +4,13 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 
 	"mind-weaver/internal/api/base"
+	"mind-weaver/internal/tokenizer"
 )
 
 // CreateProject 创建项目
@@ -192,6 +194,40 @@ func (h *Handler) GetProject(c *gin.Context) {
 	base.SuccessResponse(c, project)
 }
 
+// GetProjectUsage 获取项目的token消耗汇总
+// @Summary      获取项目的token消耗与费用
+// @Description  汇总项目下所有会话的token用量与费用，按模型和会话分组
+// @Tags         project
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "项目ID"
+// @Success      200  {object}  base.Response{data=services.ProjectUsage}
+// @Failure      400  {object}  base.Response
+// @Failure      404  {object}  base.Response
+// @Failure      500  {object}  base.Response
+// @Router       /projects/{id}/usage [get]
+func (h *Handler) GetProjectUsage(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		base.ErrorResponse(c, http.StatusBadRequest, base.ErrCodeInvalidParams, "Invalid project ID")
+		return
+	}
+
+	if _, err := h.database.GetProject(id); err != nil {
+		base.ErrorResponse(c, http.StatusNotFound, base.ErrCodeNotFound, "Project not found")
+		return
+	}
+
+	usage, err := h.usageService.GetProjectUsage(id)
+	if err != nil {
+		base.ErrorResponse(c, http.StatusInternalServerError, base.ErrCodeInternalError, fmt.Sprintf("Failed to get project usage: %v", err))
+		return
+	}
+
+	base.SuccessResponse(c, usage)
+}
+
 // GetProjectFiles 获取项目文件树
 // @Summary      获取项目文件结构
 // @Description  获取项目的文件树结构，可指定最大深度
@@ -236,3 +272,99 @@ func (h *Handler) GetProjectFiles(c *gin.Context) {
 
 	base.SuccessResponse(c, fileTree)
 }
+
+// FileDependencies 文件在项目中的导入关系
+type FileDependencies struct {
+	Path       string   `json:"path"`
+	Imports    []string `json:"imports"`     // 该文件导入的项目文件
+	ImportedBy []string `json:"imported_by"` // 导入该文件的项目文件
+}
+
+// GetFileDependencies 获取文件导入的项目文件和导入它的项目文件
+// @Summary      获取文件依赖关系
+// @Description  根据 Go/JS/TS/Python 的导入语句解析文件导入的项目文件和导入它的项目文件，depth 为向外查找的层数
+// @Tags         project
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int     true   "项目ID"
+// @Param        path   query     string  true   "文件路径，绝对路径或相对项目根目录的路径"
+// @Param        depth  query     int     false  "查找层数，默认为1"  minimum(1)
+// @Success      200    {object}  base.Response{data=FileDependencies}
+// @Failure      400    {object}  base.Response
+// @Failure      404    {object}  base.Response
+// @Failure      500    {object}  base.Response
+// @Router       /projects/{id}/dependencies [get]
+func (h *Handler) GetFileDependencies(c *gin.Context) {
+	id, ok := h.parseProjectID(c)
+	if !ok {
+		return
+	}
+	filePath := c.Query("path")
+	if filePath == "" {
+		base.ErrorResponse(c, http.StatusBadRequest, base.ErrCodeInvalidParams, "path is required")
+		return
+	}
+	depth, err := strconv.Atoi(c.DefaultQuery("depth", "1"))
+	if err != nil || depth < 1 {
+		depth = 1
+	}
+
+	project, err := h.database.GetProject(id)
+	if err != nil {
+		base.ErrorResponse(c, http.StatusNotFound, base.ErrCodeNotFound, "Project not found")
+		return
+	}
+	if !filepath.IsAbs(filePath) {
+		filePath = filepath.Join(project.Path, filePath)
+	}
+
+	graph, err := h.contextService.DependencyGraph(project.Path)
+	if err != nil {
+		base.ErrorResponse(c, http.StatusInternalServerError, base.ErrCodeInternalError, fmt.Sprintf("Failed to build dependency graph: %v", err))
+		return
+	}
+	if !graph.Contains(filePath) {
+		base.ErrorResponse(c, http.StatusNotFound, base.ErrCodeNotFound, "File not found in project or its imports are not supported")
+		return
+	}
+
+	base.SuccessResponse(c, FileDependencies{
+		Path:       filepath.Clean(filePath),
+		Imports:    graph.Imports(filePath, depth),
+		ImportedBy: graph.ImportedBy(filePath, depth),
+	})
+}
+
+// GetRepoMap 获取项目的仓库地图
+// @Summary      获取仓库地图
+// @Description  按被其他文件引用的次数排名，返回项目中最重要的定义，智能模式第一轮的系统提示词中使用同样的内容
+// @Tags         project
+// @Accept       json
+// @Produce      json
+// @Param        id          path      int     true   "项目ID"
+// @Param        max_tokens  query     int     false  "token上限，默认使用 repo_map.max_tokens"
+// @Param        request     query     string  false  "用户的请求，其中提到的文件和标识符排名更高"
+// @Success      200         {object}  base.Response{data=string}
+// @Failure      400         {object}  base.Response
+// @Failure      404         {object}  base.Response
+// @Failure      500         {object}  base.Response
+// @Router       /projects/{id}/repo-map [get]
+func (h *Handler) GetRepoMap(c *gin.Context) {
+	id, ok := h.parseProjectID(c)
+	if !ok {
+		return
+	}
+	maxTokens, _ := strconv.Atoi(c.Query("max_tokens"))
+
+	project, err := h.database.GetProject(id)
+	if err != nil {
+		base.ErrorResponse(c, http.StatusNotFound, base.ErrCodeNotFound, "Project not found")
+		return
+	}
+	repoMap, err := h.repoMap.Generate(project.Path, tokenizer.Default(), maxTokens, c.Query("request"))
+	if err != nil {
+		base.ErrorResponse(c, http.StatusInternalServerError, base.ErrCodeInternalError, fmt.Sprintf("Failed to generate repository map: %v", err))
+		return
+	}
+	base.SuccessResponse(c, repoMap)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"mind-weaver/internal/api/base"
+	"mind-weaver/internal/services"
 	"mind-weaver/internal/third/prompts"
 	"mind-weaver/internal/third/prompts/sections"
 )
@@ -16,6 +17,7 @@ import (
 type TestPromptRequest struct {
 	Prompt             string                     `json:"prompt"`
 	Mode               string                     `json:"mode"`
+	Model              string                     `json:"model"` // 决定 apply_diff 使用的修改格式
 	GlobalInstructions string                     `json:"globalInstructions"`
 	EnvContext         prompts.EnvironmentContext `json:"envContext"`
 	CustomModeConfigs  []sections.ModeConfig      `json:"customModeConfigs"`
@@ -48,7 +50,7 @@ func (h *Handler) TestPrompt(c *gin.Context) {
 		Mode:               sections.ModeSlug(req.Mode),
 		CustomModeConfigs:  req.CustomModeConfigs,
 		GlobalInstructions: req.GlobalInstructions,
-		// Note: DiffStrategy and RooIgnoreController are nil here
+		DiffStrategy:       services.NewDiffStrategy(h.cfg, req.Model),
 	}
 
 	// Generate the system prompt
@@ -18,6 +18,15 @@ func RegisterRoutes(router *gin.Engine, handler *Handler) {
 			projects.PUT("/:id", handler.UpdateProject)
 			projects.GET("/:id", handler.GetProject)
 			projects.GET("/:id/files", handler.GetProjectFiles)
+			projects.GET("/:id/dependencies", handler.GetFileDependencies)     // 文件导入和被导入的项目文件
+			projects.GET("/:id/repo-map", handler.GetRepoMap)                  // 按引用次数排名的仓库地图
+			projects.POST("/:id/code-index/search", handler.SearchCodeIndex)   // 语义代码搜索
+			projects.POST("/:id/code-index/rebuild", handler.RebuildCodeIndex) // 重建代码索引
+			projects.GET("/:id/usage", handler.GetProjectUsage)                // 项目token消耗汇总
+			// 工具批准策略
+			projects.GET("/:id/approval-policy", handler.GetProjectApprovalPolicy)
+			projects.PUT("/:id/approval-policy", handler.SaveProjectApprovalPolicy)
+			projects.DELETE("/:id/approval-policy", handler.DeleteProjectApprovalPolicy)
 		}
 
 		// File routes
@@ -36,14 +45,33 @@ func RegisterRoutes(router *gin.Engine, handler *Handler) {
 			sessions.POST("/:id/message", handler.SendMessage)                   // 消息列表
 			sessions.DELETE("/:id/messages/:msgId", handler.DeleteMessage)       // 删除消息，msgId为消息id，当msgId为0时，删除所有消息
 			sessions.POST("/:id/completions", handler.OpenAICompatStreamHandler) // 流式响应
+			sessions.POST("/:id/completions/stop", handler.StopCompletion)       // 停止正在生成的回复
+			sessions.GET("/:id/completions/stream", handler.ResumeCompletion)    // 断线重连，补发错过的事件
 			sessions.POST("/parse/ai-res", handler.ParseAiContent)               // 解析ai响应文本
+			sessions.GET("/:id/usage", handler.GetSessionUsage)                  // token消耗与费用
+
+			// 工具批准策略，会话没有设置时使用项目的策略
+			sessions.GET("/:id/approval-policy", handler.GetSessionApprovalPolicy)
+			sessions.PUT("/:id/approval-policy", handler.SaveSessionApprovalPolicy)
+			sessions.DELETE("/:id/approval-policy", handler.DeleteSessionApprovalPolicy)
+			sessions.POST("/:id/approval-policy/check", handler.CheckToolApproval) // 判断工具调用是否需要用户确认
+
+			sessions.GET("/:id/checkpoints", handler.GetSessionCheckpoints) // 编辑类工具执行前保存的检查点
 
 			// 上下文信息相关接口
 			sessions.PUT("/:id/context", handler.UpdateContext)
 			sessions.GET("/:id/context", handler.GetContext)
 		}
 
+		// 检查点，查看之后的修改或恢复工作区
+		checkpoints := api.Group("/checkpoints")
+		{
+			checkpoints.GET("/:id/diff", handler.GetCheckpointDiff)
+			checkpoints.POST("/:id/restore", handler.RestoreCheckpoint)
+		}
+
 		api.GET("/models", handler.GetModels)
+		api.GET("/mcp/servers", handler.GetMcpServers) // MCP 服务器状态、工具和资源
 		api.POST("/prompts/test", handler.TestPrompt)
 
 		// Command execution routes
@@ -10,7 +10,9 @@ import (
 
 	"mind-weaver/internal/api/base"
 	"mind-weaver/internal/db"
+	"mind-weaver/internal/llm"
 	"mind-weaver/internal/services"
+	"mind-weaver/internal/tokenizer"
 	"mind-weaver/internal/utils"
 	"mind-weaver/pkg/logger"
 )
@@ -110,6 +112,40 @@ func (h *Handler) GetSession(c *gin.Context) {
 	base.SuccessResponse(c, session)
 }
 
+// GetSessionUsage 获取会话的token消耗
+// @Summary      获取会话的token消耗与费用
+// @Description  返回会话内每次模型调用的token用量，以及按模型汇总的费用(基于模型配置的cost_per_token)
+// @Tags         session
+// @Accept       json
+// @Produce      json
+// @Param        id  path      int  true  "会话ID"
+// @Success      200  {object}  base.Response{data=services.SessionUsage}
+// @Failure      400  {object}  base.Response
+// @Failure      404  {object}  base.Response
+// @Failure      500  {object}  base.Response
+// @Router       /sessions/{id}/usage [get]
+func (h *Handler) GetSessionUsage(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		base.ErrorResponse(c, http.StatusBadRequest, base.ErrCodeInvalidParams, "Invalid session ID")
+		return
+	}
+
+	if _, err := h.database.GetSession(id); err != nil {
+		base.ErrorResponse(c, http.StatusNotFound, base.ErrCodeNotFound, "Session not found")
+		return
+	}
+
+	usage, err := h.usageService.GetSessionUsage(id)
+	if err != nil {
+		base.ErrorResponse(c, http.StatusInternalServerError, base.ErrCodeInternalError, fmt.Sprintf("Failed to get session usage: %v", err))
+		return
+	}
+
+	base.SuccessResponse(c, usage)
+}
+
 type SendMessageReq struct {
 	Content      string   `json:"content" binding:"required"`
 	ProjectPath  string   `json:"project_path" binding:"required"`
@@ -131,6 +167,7 @@ type SendMessageResp struct {
 // @Param        request  body  SendMessageReq  true  "消息内容"
 // @Success      200  {object}  base.Response{data=SendMessageResp}
 // @Failure      400  {object}  base.Response
+// @Failure      429  {object}  base.Response  "LLM rate limit exceeded"
 // @Failure      500  {object}  base.Response
 // @Router       /sessions/{id}/message [post]
 func (h *Handler) SendMessage(c *gin.Context) {
@@ -155,7 +192,11 @@ func (h *Handler) SendMessage(c *gin.Context) {
 	}
 
 	// Generate AI response
-	aiMsg, err := h.sessionService.GenerateAIResponse(sessionID, req.ProjectPath, req.ContextFiles, req.Content)
+	aiMsg, err := h.sessionService.GenerateAIResponse(c.Request.Context(), sessionID, req.ProjectPath, req.ContextFiles, req.Content)
+	if llm.IsRateLimited(err) {
+		base.ErrorResponse(c, http.StatusTooManyRequests, base.ErrCodeRateLimited, err.Error())
+		return
+	}
 	if err != nil {
 		base.ErrorResponse(c, http.StatusInternalServerError, base.ErrCodeInternalError, fmt.Sprintf("Failed to generate AI response: %v", err))
 		return
@@ -316,6 +357,7 @@ func (h *Handler) DeleteSession(c *gin.Context) {
 		base.ErrorResponse(c, http.StatusInternalServerError, base.ErrCodeInternalError, fmt.Sprintf("Failed to delete session: %v", err))
 		return
 	}
+	h.browsers.Close(sessionID)
 
 	base.SuccessResponse(c, gin.H{"status": "ok"})
 }
@@ -372,7 +414,9 @@ type PrepareStreamMessageResp struct {
 func (h *Handler) buildSystemPrompt(modelName string, sessionID int64, addSysPrompt bool) string {
 	codePrompt := ""
 	currentModel := h.cfg.LLM.GetCurrentLLMInfo(modelName)
-	codeContextBuilder := utils.NewPromptBuilder(currentModel.MaxContext)
+	// 为模型回复预留 max_tokens，避免请求超出模型上下文
+	codeContextBuilder := utils.NewPromptBuilder(currentModel.MaxContext - currentModel.MaxTokens).
+		SetTokenizer(tokenizer.ForModel(currentModel.Name, currentModel.Tokenizer))
 	sessionInfo, _ := h.sessionService.GetSession(sessionID)
 	var files []string
 	for _, fileContext := range sessionInfo.IncludePatterns {
@@ -389,6 +433,9 @@ func (h *Handler) buildSystemPrompt(modelName string, sessionID int64, addSysPro
 		}
 	}
 
+	// 自动加入所选文件导入的项目文件，放在最后，超出上下文时最先被裁剪
+	files = append(files, h.dependencyFiles(sessionInfo, files)...)
+
 	// 过滤重复的文件
 	hasAdd := map[string]bool{}
 	for _, filePathStr := range files {
@@ -399,7 +446,28 @@ func (h *Handler) buildSystemPrompt(modelName string, sessionID int64, addSysPro
 		codeContextBuilder.AddCodeFile(filePathStr)
 		hasAdd[filePathStr] = true
 	}
+	if currentModel.MaxContext > 0 {
+		codeContextBuilder.TrimContextToFit()
+	}
 	codePrompt = codeContextBuilder.BuildSystemPrompt(addSysPrompt)
 
 	return codePrompt
 }
+
+// dependencyFiles 返回所选文件导入的项目文件，没有开启时返回空
+func (h *Handler) dependencyFiles(sessionInfo *services.SessionInfo, files []string) []string {
+	depth := h.cfg.Context.DependencyDepth
+	if depth <= 0 || sessionInfo == nil || len(files) == 0 {
+		return nil
+	}
+	project, err := h.database.GetProject(sessionInfo.ProjectID)
+	if err != nil || project == nil {
+		return nil
+	}
+	deps, err := h.contextService.GetDependencyFiles(project.Path, files, depth, h.cfg.Context.GetMaxDependencyFiles())
+	if err != nil {
+		logger.Infof("Failed to get dependency files of session %d: %v", sessionInfo.ID, err)
+		return nil
+	}
+	return deps
+}
@@ -56,7 +56,7 @@ func (h *Handler) HandleLlmResponseError(c *gin.Context) {
 		util.ReadFileToString("./test_data/aiNewAddRes-last.txt"),
 	}
 
-	err := h.handleLlmResponseError(aiResList, "/mnt/h/code/test_project/tetris2-test.html")
+	err := h.handleLlmResponseError(c.Request.Context(), aiResList, "/mnt/h/code/test_project/tetris2-test.html")
 	if err != nil {
 		logger.Error("HandleLlmResponseError error: ", err)
 		base.ErrorResponse(c, http.StatusInternalServerError, base.ErrCodeInternalError, err.Error())
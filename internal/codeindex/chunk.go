@@ -0,0 +1,230 @@
+// Package codeindex splits project files into chunks for semantic code search.
+// Source files are cut at the definition boundaries found by the tree-sitter
+// queries in internal/treesitter; other text files and the code between
+// definitions are cut into windows of lines.
+package codeindex
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"mind-weaver/internal/third/ignore"
+	"mind-weaver/internal/treesitter"
+	"mind-weaver/internal/utils"
+)
+
+const (
+	// 单个文件超过该大小时不索引，通常是生成或打包后的文件
+	maxFileSize = 512 * 1024
+	// 生成向量的文本超过该长度时截断，避免超出向量模型的输入上限
+	maxEmbeddingChars = 6000
+)
+
+// Chunk is a piece of a file that is embedded and searched as a whole.
+type Chunk struct {
+	Path      string `json:"path"`       // 相对项目根目录的路径(使用 /)
+	StartLine int    `json:"start_line"` // 从1开始
+	EndLine   int    `json:"end_line"`   // 包含该行
+	Symbol    string `json:"symbol"`     // 定义的名称，不是定义时为空
+	Kind      string `json:"kind"`       // 定义类型，例如 function、class
+	Content   string `json:"content"`
+}
+
+// EmbeddingText returns the text the vector of the chunk is computed from. The
+// path and symbol name are included so that they match questions too.
+func (c Chunk) EmbeddingText() string {
+	header := c.Path
+	if c.Symbol != "" {
+		header += " " + c.Kind + " " + c.Symbol
+	}
+	text := header + "\n" + c.Content
+	if len(text) > maxEmbeddingChars {
+		text = strings.ToValidUTF8(text[:maxEmbeddingChars], "")
+	}
+	return text
+}
+
+// File is an indexable file found by ScanFiles.
+type File struct {
+	Path    string // 相对项目根目录的路径(使用 /)
+	ModTime int64  // 修改时间，UnixNano
+	Size    int64
+}
+
+// IsIndexable reports whether a file is source code or text worth indexing.
+func IsIndexable(path string) bool {
+	if strings.Contains(filepath.Base(path), ".min.") {
+		return false
+	}
+	if treesitter.SupportsTags(path) {
+		return true
+	}
+	// json、xml 大多是数据和锁文件，不参与索引
+	ext := strings.ToLower(filepath.Ext(path))
+	return utils.GetAllowedExtensions()[ext] && ext != ".json" && ext != ".xml"
+}
+
+// ScanFiles lists the indexable files under root, at most maxFiles of them when
+// maxFiles > 0. Dependency, build and hidden directories and the paths matched
+// by the project's .rooignore are skipped, so they are never embedded or stored.
+func ScanFiles(root string, maxFiles int) ([]File, error) {
+	rooIgnore := ignore.NewRooIgnoreController(root)
+	if err := rooIgnore.Initialize(); err != nil {
+		return nil, err
+	}
+
+	var files []File
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if path != root && (utils.IsSkippedProjectDir(d.Name()) || !rooIgnore.ValidateAccess(path)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if maxFiles > 0 && len(files) >= maxFiles {
+			return filepath.SkipAll
+		}
+		if !d.Type().IsRegular() || !IsIndexable(path) || !rooIgnore.ValidateAccess(path) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() == 0 || info.Size() > maxFileSize {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		files = append(files, File{Path: filepath.ToSlash(rel), ModTime: info.ModTime().UnixNano(), Size: info.Size()})
+		return nil
+	})
+	return files, err
+}
+
+// ReadFile reads an indexable file, nil without error for minified code.
+func ReadFile(root, rel string) ([]byte, error) {
+	content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(rel)))
+	if err != nil {
+		return nil, err
+	}
+	if utils.IsMinified(content) {
+		return nil, nil
+	}
+	return content, nil
+}
+
+// ChunkFile splits a file into chunks of at most maxLines lines. Every
+// definition becomes a chunk, definitions longer than maxLines are split and the
+// definitions nested in them get chunks of their own. Lines outside of any
+// definition are grouped into windows.
+func ChunkFile(path string, content []byte, maxLines int) []Chunk {
+	if maxLines <= 0 {
+		maxLines = 60
+	}
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	var defs []treesitter.Tag
+	if treesitter.SupportsTags(path) {
+		if tags, err := treesitter.ParseTags(path, content); err == nil && tags != nil {
+			defs = tags.Definitions
+		}
+	}
+	// 外层定义在前，同一行开始的定义较长的在前
+	sort.SliceStable(defs, func(i, j int) bool {
+		if defs[i].StartLine != defs[j].StartLine {
+			return defs[i].StartLine < defs[j].StartLine
+		}
+		return defs[i].EndLine > defs[j].EndLine
+	})
+
+	var chunks []Chunk
+	covered := make([]bool, len(lines))
+	addChunk := func(start, end int, tag *treesitter.Tag) {
+		chunk := Chunk{Path: path, StartLine: start + 1, EndLine: end + 1, Content: strings.Join(lines[start:end+1], "\n")}
+		if tag != nil {
+			chunk.Symbol, chunk.Kind = tag.Name, tag.Kind
+		}
+		chunks = append(chunks, chunk)
+		for i := start; i <= end; i++ {
+			covered[i] = true
+		}
+	}
+
+	parentEnd := -1 // 上一个能完整放进一个分块的定义的结束行，其中嵌套的定义不再单独分块
+	for i := range defs {
+		tag := &defs[i]
+		start, end := int(tag.StartLine), min(int(tag.EndLine), len(lines)-1)
+		if start <= parentEnd || start > end {
+			continue
+		}
+		// 定义前紧挨着的注释和装饰器属于这个定义
+		for start > 0 && !covered[start-1] && start > parentEnd+1 && isLeadingComment(lines[start-1]) {
+			start--
+		}
+		for from := start; from <= end; from += maxLines {
+			addChunk(from, min(from+maxLines-1, end), tag)
+		}
+		if end-start+1 <= maxLines {
+			parentEnd = end
+		}
+	}
+
+	// 不属于任何定义的行，例如导入、包级变量、文档
+	for start := 0; start < len(lines); {
+		if covered[start] {
+			start++
+			continue
+		}
+		end := start
+		for end+1 < len(lines) && !covered[end+1] && end+1-start < maxLines {
+			end++
+		}
+		next := end + 1
+		// 去掉首尾的空行
+		for start < end && strings.TrimSpace(lines[start]) == "" {
+			start++
+		}
+		for end > start && strings.TrimSpace(lines[end]) == "" {
+			end--
+		}
+		if countNonBlank(lines[start:end+1]) >= 2 {
+			addChunk(start, end, nil)
+		}
+		start = next
+	}
+
+	sort.SliceStable(chunks, func(i, j int) bool { return chunks[i].StartLine < chunks[j].StartLine })
+	return chunks
+}
+
+func isLeadingComment(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	for _, prefix := range []string{"//", "#", "/*", "*", "@", "--", "\"\"\""} {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func countNonBlank(lines []string) int {
+	count := 0
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			count++
+		}
+	}
+	return count
+}
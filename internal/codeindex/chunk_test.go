@@ -0,0 +1,113 @@
+package codeindex
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const refundSource = `package billing
+
+import "time"
+
+// RetryRefund 按指数退避重试失败的退款
+func RetryRefund(id int) error {
+	for attempt := 0; attempt < 3; attempt++ {
+		time.Sleep(time.Second)
+	}
+	return nil
+}
+
+type Refund struct {
+	ID int
+}
+
+func (r *Refund) Cancel() {}
+`
+
+func TestChunkFile(t *testing.T) {
+	chunks := ChunkFile("billing/refund.go", []byte(refundSource), 60)
+	var got []string
+	for _, chunk := range chunks {
+		got = append(got, fmt.Sprintf("%d-%d %s", chunk.StartLine, chunk.EndLine, chunk.Symbol))
+	}
+	// 导入作为没有定义的分块，注释属于紧跟的函数
+	want := []string{"1-3 ", "5-11 RetryRefund", "13-15 Refund", "17-17 Cancel"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("Expected chunks %v, got %v", want, got)
+	}
+	if !strings.HasPrefix(chunks[1].Content, "// RetryRefund") || chunks[1].Kind != "function" {
+		t.Errorf("Expected the function chunk with its comment, got %+v", chunks[1])
+	}
+	if text := chunks[1].EmbeddingText(); !strings.HasPrefix(text, "billing/refund.go function RetryRefund\n") {
+		t.Errorf("Expected the path and symbol in the embedding text, got %q", text)
+	}
+
+	// 超过最大行数的定义被拆分
+	split := ChunkFile("billing/refund.go", []byte(refundSource), 4)
+	if split[1].StartLine != 5 || split[1].EndLine != 8 || split[2].StartLine != 9 || split[2].Symbol != "RetryRefund" {
+		t.Errorf("Expected RetryRefund to be split into windows, got %+v", split[1:3])
+	}
+
+	// 不支持解析的文本文件按行分块
+	lines := make([]string, 25)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i+1)
+	}
+	text := ChunkFile("docs/notes.md", []byte(strings.Join(lines, "\n")+"\n"), 10)
+	if len(text) != 3 || text[2].StartLine != 21 || text[2].EndLine != 25 {
+		t.Errorf("Expected 3 windows of lines, got %+v", text)
+	}
+}
+
+func TestScanFiles(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"main.go":                   "package main\n",
+		"docs/README.md":            "# docs\n",
+		"package-lock.json":         "{}\n",
+		"static/app.min.js":         "var a=1\n",
+		"node_modules/lib/index.js": "module.exports = 1\n",
+		".git/config":               "[core]\n",
+		".rooignore":                "secrets/\ndocs/private.md\n",
+		"secrets/keys.go":           "package secrets\n",
+		"docs/private.md":           "# private\n",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := ScanFiles(root, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	if got := strings.Join(paths, ","); got != "docs/README.md,main.go" {
+		t.Errorf("Expected only source and text files not ignored by .rooignore, got %s", got)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	entries := []Entry{
+		{Chunk: Chunk{Path: "billing/refund.go"}, Vector: []float32{1, 0}},
+		{Chunk: Chunk{Path: "billing/invoice.go"}, Vector: []float32{0.6, 0.8}},
+		{Chunk: Chunk{Path: "api/refund.go"}, Vector: []float32{0.8, 0.6}},
+		{Chunk: Chunk{Path: "api/other.go"}, Vector: []float32{-1, 0}},
+	}
+	results := Search(entries, []float32{1, 0}, "", 0)
+	if len(results) != 3 || results[0].Path != "billing/refund.go" || results[1].Path != "api/refund.go" {
+		t.Errorf("Expected results by similarity without the opposite chunk, got %+v", results)
+	}
+	results = Search(entries, []float32{1, 0}, "./billing/", 1)
+	if len(results) != 1 || results[0].Path != "billing/refund.go" {
+		t.Errorf("Expected the best result under billing, got %+v", results)
+	}
+}
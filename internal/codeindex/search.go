@@ -0,0 +1,64 @@
+package codeindex
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// Entry is an indexed chunk together with its vector.
+type Entry struct {
+	Chunk
+	Vector []float32
+}
+
+// Result is a chunk matching a search query.
+type Result struct {
+	Chunk
+	Score float64 `json:"score"` // 与查询的余弦相似度
+}
+
+// Search returns the limit entries most similar to query whose path is under
+// pathPrefix (empty for the whole project), best first. Entries that share
+// nothing with the query are left out.
+func Search(entries []Entry, query []float32, pathPrefix string, limit int) []Result {
+	pathPrefix = strings.Trim(strings.TrimPrefix(pathPrefix, "./"), "/")
+	if pathPrefix == "." {
+		pathPrefix = ""
+	}
+
+	var results []Result
+	for _, entry := range entries {
+		if pathPrefix != "" && entry.Path != pathPrefix && !strings.HasPrefix(entry.Path, pathPrefix+"/") {
+			continue
+		}
+		score := Cosine(query, entry.Vector)
+		if score <= 0 {
+			continue
+		}
+		results = append(results, Result{Chunk: entry.Chunk, Score: score})
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// Cosine returns the cosine similarity of two vectors, 0 when their dimensions
+// differ or either is zero.
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
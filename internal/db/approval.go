@@ -0,0 +1,39 @@
+package db
+
+import (
+	"database/sql"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Tool approval policy operations
+func (db *Database) SaveToolApprovalPolicy(scope string, scopeID int64, policy string) error {
+	_, err := db.Exec(`
+		INSERT INTO tool_approval_policies (scope, scope_id, policy) VALUES (?, ?, ?)
+		ON CONFLICT (scope, scope_id) DO UPDATE SET policy = excluded.policy, updated_at = CURRENT_TIMESTAMP
+	`, scope, scopeID, policy)
+	return err
+}
+
+// GetToolApprovalPolicy returns nil without error when no policy is stored.
+func (db *Database) GetToolApprovalPolicy(scope string, scopeID int64) (*ToolApprovalPolicy, error) {
+	policy := &ToolApprovalPolicy{}
+	err := db.QueryRow(`
+		SELECT id, scope, scope_id, policy, created_at, updated_at
+		FROM tool_approval_policies WHERE scope = ? AND scope_id = ?
+	`, scope, scopeID).Scan(
+		&policy.ID, &policy.Scope, &policy.ScopeID, &policy.Policy, &policy.CreatedAt, &policy.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (db *Database) DeleteToolApprovalPolicy(scope string, scopeID int64) error {
+	_, err := db.Exec(`DELETE FROM tool_approval_policies WHERE scope = ? AND scope_id = ?`, scope, scopeID)
+	return err
+}
@@ -0,0 +1,129 @@
+package db
+
+import (
+	"database/sql"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Checkpoint operations
+func (db *Database) CreateCheckpoint(checkpoint *Checkpoint) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := tx.Exec(`INSERT INTO checkpoints (session_id, message_id, tool_name) VALUES (?, ?, ?)`,
+		checkpoint.SessionID, checkpoint.MessageID, checkpoint.ToolName)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	for _, file := range checkpoint.Files {
+		_, err = tx.Exec(`INSERT INTO checkpoint_files (checkpoint_id, path, existed, hash) VALUES (?, ?, ?, ?)`,
+			id, file.Path, file.Existed, file.Hash)
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	return id, tx.Commit()
+}
+
+// GetCheckpoint returns nil without error when the checkpoint does not exist.
+func (db *Database) GetCheckpoint(id int64) (*Checkpoint, error) {
+	checkpoint := &Checkpoint{}
+	err := db.QueryRow(`
+		SELECT id, session_id, message_id, tool_name, created_at FROM checkpoints WHERE id = ?
+	`, id).Scan(&checkpoint.ID, &checkpoint.SessionID, &checkpoint.MessageID, &checkpoint.ToolName, &checkpoint.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint.Files, err = db.getCheckpointFiles(`WHERE checkpoint_id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+// ListSessionCheckpoints returns the checkpoints of a session with their files, oldest first.
+func (db *Database) ListSessionCheckpoints(sessionID int64) ([]*Checkpoint, error) {
+	rows, err := db.Query(`
+		SELECT id, session_id, message_id, tool_name, created_at FROM checkpoints WHERE session_id = ? ORDER BY id
+	`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checkpoints := []*Checkpoint{}
+	byID := map[int64]*Checkpoint{}
+	for rows.Next() {
+		checkpoint := &Checkpoint{Files: []*CheckpointFile{}}
+		err := rows.Scan(&checkpoint.ID, &checkpoint.SessionID, &checkpoint.MessageID, &checkpoint.ToolName, &checkpoint.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		checkpoints = append(checkpoints, checkpoint)
+		byID[checkpoint.ID] = checkpoint
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	files, err := db.getCheckpointFiles(`WHERE checkpoint_id IN (SELECT id FROM checkpoints WHERE session_id = ?)`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if checkpoint, ok := byID[file.CheckpointID]; ok {
+			checkpoint.Files = append(checkpoint.Files, file)
+		}
+	}
+	return checkpoints, nil
+}
+
+func (db *Database) DeleteCheckpoint(id int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err = tx.Exec(`DELETE FROM checkpoint_files WHERE checkpoint_id = ?`, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err = tx.Exec(`DELETE FROM checkpoints WHERE id = ?`, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db *Database) getCheckpointFiles(where string, args ...any) ([]*CheckpointFile, error) {
+	rows, err := db.Query(`SELECT id, checkpoint_id, path, existed, hash FROM checkpoint_files `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := []*CheckpointFile{}
+	for rows.Next() {
+		file := &CheckpointFile{}
+		if err := rows.Scan(&file.ID, &file.CheckpointID, &file.Path, &file.Existed, &file.Hash); err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, rows.Err()
+}
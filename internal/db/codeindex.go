@@ -0,0 +1,155 @@
+package db
+
+import (
+	"encoding/binary"
+	"math"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Code index operations
+func (db *Database) ListCodeIndexFiles(projectPath string) ([]*CodeIndexFile, error) {
+	rows, err := db.Query(`
+		SELECT id, project_path, path, mod_time, size, hash, model, indexed_at
+		FROM code_index_files WHERE project_path = ? ORDER BY path
+	`, projectPath)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := []*CodeIndexFile{}
+	for rows.Next() {
+		file := &CodeIndexFile{}
+		err := rows.Scan(&file.ID, &file.ProjectPath, &file.Path, &file.ModTime, &file.Size, &file.Hash, &file.Model, &file.IndexedAt)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, rows.Err()
+}
+
+// SaveCodeIndexFile inserts or updates a file and replaces all of its chunks.
+func (db *Database) SaveCodeIndexFile(file *CodeIndexFile, chunks []*CodeIndexChunk) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO code_index_files (project_path, path, mod_time, size, hash, model) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (project_path, path) DO UPDATE SET
+			mod_time = excluded.mod_time, size = excluded.size, hash = excluded.hash,
+			model = excluded.model, indexed_at = CURRENT_TIMESTAMP
+	`, file.ProjectPath, file.Path, file.ModTime, file.Size, file.Hash, file.Model)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	var id int64
+	err = tx.QueryRow(`SELECT id FROM code_index_files WHERE project_path = ? AND path = ?`, file.ProjectPath, file.Path).Scan(&id)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if _, err = tx.Exec(`DELETE FROM code_index_chunks WHERE file_id = ?`, id); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	for _, chunk := range chunks {
+		_, err = tx.Exec(`
+			INSERT INTO code_index_chunks (file_id, start_line, end_line, symbol, kind, content, vector) VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, id, chunk.StartLine, chunk.EndLine, chunk.Symbol, chunk.Kind, chunk.Content, encodeVector(chunk.Vector))
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	return id, tx.Commit()
+}
+
+// UpdateCodeIndexFileStat records a new modification time of a file whose
+// content did not change, so it is not hashed again.
+func (db *Database) UpdateCodeIndexFileStat(id, modTime, size int64) error {
+	_, err := db.Exec(`UPDATE code_index_files SET mod_time = ?, size = ? WHERE id = ?`, modTime, size, id)
+	return err
+}
+
+func (db *Database) DeleteCodeIndexFile(id int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err = tx.Exec(`DELETE FROM code_index_chunks WHERE file_id = ?`, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err = tx.Exec(`DELETE FROM code_index_files WHERE id = ?`, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteCodeIndex removes the whole index of a project.
+func (db *Database) DeleteCodeIndex(projectPath string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM code_index_chunks WHERE file_id IN (SELECT id FROM code_index_files WHERE project_path = ?)`, projectPath)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err = tx.Exec(`DELETE FROM code_index_files WHERE project_path = ?`, projectPath); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListCodeIndexChunks returns all chunks of a project with their vectors.
+func (db *Database) ListCodeIndexChunks(projectPath string) ([]*CodeIndexChunk, error) {
+	rows, err := db.Query(`
+		SELECT c.id, c.file_id, f.path, c.start_line, c.end_line, c.symbol, c.kind, c.content, c.vector
+		FROM code_index_chunks c JOIN code_index_files f ON f.id = c.file_id
+		WHERE f.project_path = ? ORDER BY f.path, c.start_line
+	`, projectPath)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	chunks := []*CodeIndexChunk{}
+	for rows.Next() {
+		chunk := &CodeIndexChunk{}
+		var vector []byte
+		err := rows.Scan(&chunk.ID, &chunk.FileID, &chunk.Path, &chunk.StartLine, &chunk.EndLine, &chunk.Symbol, &chunk.Kind, &chunk.Content, &vector)
+		if err != nil {
+			return nil, err
+		}
+		chunk.Vector = decodeVector(vector)
+		chunks = append(chunks, chunk)
+	}
+	return chunks, rows.Err()
+}
+
+func encodeVector(vector []float32) []byte {
+	buf := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(v))
+	}
+	return buf
+}
+
+func decodeVector(buf []byte) []float32 {
+	vector := make([]float32, len(buf)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return vector
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"database/sql"
+	"fmt"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -71,6 +72,7 @@ func createTables(db *sql.DB) error {
 			role TEXT NOT NULL,
 			content TEXT NOT NULL,
 			timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			status TEXT NOT NULL DEFAULT 'complete',
 			FOREIGN KEY (session_id) REFERENCES sessions (id) ON DELETE CASCADE
 		)
 	`)
@@ -78,6 +80,11 @@ func createTables(db *sql.DB) error {
 		return err
 	}
 
+	// 旧数据库没有 status 字段，需要补上
+	if err = addColumnIfNotExists(db, "messages", "status", "TEXT NOT NULL DEFAULT 'complete'"); err != nil {
+		return err
+	}
+
 	// Code contexts table
 	_, err = db.Exec(`
 		CREATE TABLE IF NOT EXISTS code_contexts (
@@ -89,5 +96,182 @@ func createTables(db *sql.DB) error {
 			FOREIGN KEY (session_id) REFERENCES sessions (id) ON DELETE CASCADE
 		)
 	`)
+	if err != nil {
+		return err
+	}
+
+	// Message usage table，记录每次模型调用的token消耗
+	// 不设置外键，删除消息/会话后已产生的费用仍然计入项目统计
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS message_usages (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_id INTEGER NOT NULL,
+			project_id INTEGER,
+			message_id INTEGER,
+			model TEXT NOT NULL,
+			prompt_tokens INTEGER NOT NULL DEFAULT 0,
+			completion_tokens INTEGER NOT NULL DEFAULT 0,
+			total_tokens INTEGER NOT NULL DEFAULT 0,
+			cost REAL NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_message_usages_session ON message_usages (session_id)`)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_message_usages_project ON message_usages (project_id)`)
+	if err != nil {
+		return err
+	}
+
+	// Tool approval policies table，scope 为 project 或 session，policy 为JSON
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS tool_approval_policies (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			scope TEXT NOT NULL,
+			scope_id INTEGER NOT NULL,
+			policy TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (scope, scope_id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Checkpoints table，每次编辑类工具执行前记录被修改文件的快照，文件内容按哈希保存在 temp_storage_path 下
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS checkpoints (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_id INTEGER NOT NULL,
+			message_id INTEGER NOT NULL DEFAULT 0,
+			tool_name TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (session_id) REFERENCES sessions (id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	// existed 为0表示工具执行前文件不存在，恢复时需要删除
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS checkpoint_files (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			checkpoint_id INTEGER NOT NULL,
+			path TEXT NOT NULL,
+			existed INTEGER NOT NULL DEFAULT 1,
+			hash TEXT NOT NULL DEFAULT '',
+			FOREIGN KEY (checkpoint_id) REFERENCES checkpoints (id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_checkpoints_session ON checkpoints (session_id)`)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_checkpoint_files_checkpoint ON checkpoint_files (checkpoint_id)`)
+	if err != nil {
+		return err
+	}
+
+	// History summaries table，压缩历史时生成的摘要，覆盖 last_message_id 及之前的消息
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS history_summaries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_id INTEGER NOT NULL,
+			last_message_id INTEGER NOT NULL,
+			content TEXT NOT NULL,
+			model TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (session_id) REFERENCES sessions (id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_history_summaries_session ON history_summaries (session_id)`)
+	if err != nil {
+		return err
+	}
+
+	// Code index tables，codebase_search 使用的代码分块和向量，按项目路径区分，文件修改后重新生成
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS code_index_files (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			project_path TEXT NOT NULL,
+			path TEXT NOT NULL,
+			mod_time INTEGER NOT NULL DEFAULT 0,
+			size INTEGER NOT NULL DEFAULT 0,
+			hash TEXT NOT NULL DEFAULT '',
+			model TEXT NOT NULL DEFAULT '',
+			indexed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (project_path, path)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	// vector 为小端序的 float32 数组
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS code_index_chunks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			file_id INTEGER NOT NULL,
+			start_line INTEGER NOT NULL,
+			end_line INTEGER NOT NULL,
+			symbol TEXT NOT NULL DEFAULT '',
+			kind TEXT NOT NULL DEFAULT '',
+			content TEXT NOT NULL,
+			vector BLOB NOT NULL,
+			FOREIGN KEY (file_id) REFERENCES code_index_files (id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_code_index_chunks_file ON code_index_chunks (file_id)`)
+	return err
+}
+
+// addColumnIfNotExists adds a column to an existing table, used to migrate databases
+// created before the column was introduced.
+func addColumnIfNotExists(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			colType    string
+			notNull    int
+			defaultVal sql.NullString
+			pk         int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultVal, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
 	return err
 }
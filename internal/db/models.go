@@ -4,6 +4,19 @@ import (
 	"time"
 )
 
+// Message status
+const (
+	MessageStatusComplete    = "complete"
+	MessageStatusInterrupted = "interrupted" // 客户端断开连接，回复只生成了一部分
+	MessageStatusStopped     = "stopped"     // 用户主动停止生成
+)
+
+// Tool approval policy scope
+const (
+	PolicyScopeProject = "project"
+	PolicyScopeSession = "session"
+)
+
 type Project struct {
 	ID           int64     `json:"id"`
 	Name         string    `json:"name"`
@@ -36,6 +49,7 @@ type Message struct {
 	Role      string    `json:"role"` // "user" or "ai"
 	Content   string    `json:"content"`
 	Timestamp time.Time `json:"timestamp"`
+	Status    string    `json:"status"` // "complete", "interrupted" or "stopped"
 }
 
 type CodeContext struct {
@@ -45,3 +59,88 @@ type CodeContext struct {
 	Content   string    `json:"content"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+type MessageUsage struct {
+	ID               int64     `json:"id"`
+	SessionID        int64     `json:"session_id"`
+	ProjectID        int64     `json:"project_id"`
+	MessageID        int64     `json:"message_id"` // 对应的assistant消息，0表示没有保存消息
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	TotalTokens      int       `json:"total_tokens"`
+	Cost             float64   `json:"cost"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// UsageSummary is an aggregated view over message_usages rows.
+type UsageSummary struct {
+	Key              string  `json:"key,omitempty"` // 分组字段的值，如模型名称或会话ID
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+type ToolApprovalPolicy struct {
+	ID        int64     `json:"id"`
+	Scope     string    `json:"scope"` // "project" or "session"
+	ScopeID   int64     `json:"scope_id"`
+	Policy    string    `json:"policy"` // JSON of tools.ApprovalPolicy
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Checkpoint is the snapshot of the files a tool call was about to modify.
+type Checkpoint struct {
+	ID        int64             `json:"id"`
+	SessionID int64             `json:"session_id"`
+	MessageID int64             `json:"message_id"` // 包含该工具调用的assistant消息
+	ToolName  string            `json:"tool_name"`
+	CreatedAt time.Time         `json:"created_at"`
+	Files     []*CheckpointFile `json:"files"`
+}
+
+type CheckpointFile struct {
+	ID           int64  `json:"id"`
+	CheckpointID int64  `json:"checkpoint_id"`
+	Path         string `json:"path"`    // 绝对路径
+	Existed      bool   `json:"existed"` // 工具执行前文件是否存在
+	Hash         string `json:"hash"`    // 文件内容的sha256，文件不存在时为空
+}
+
+// HistorySummary is the summary of the messages of a session up to LastMessageID.
+type HistorySummary struct {
+	ID            int64     `json:"id"`
+	SessionID     int64     `json:"session_id"`
+	LastMessageID int64     `json:"last_message_id"` // 摘要覆盖的最后一条消息
+	Content       string    `json:"content"`
+	Model         string    `json:"model"` // 生成摘要的模型
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// CodeIndexFile is a file of a project whose chunks are in the code index.
+type CodeIndexFile struct {
+	ID          int64     `json:"id"`
+	ProjectPath string    `json:"project_path"` // 项目绝对路径
+	Path        string    `json:"path"`         // 相对项目根目录的路径(使用 /)
+	ModTime     int64     `json:"mod_time"`     // 索引时的修改时间，UnixNano
+	Size        int64     `json:"size"`
+	Hash        string    `json:"hash"`  // 文件内容的sha256
+	Model       string    `json:"model"` // 生成向量使用的模型，模型变化后需要重新索引
+	IndexedAt   time.Time `json:"indexed_at"`
+}
+
+// CodeIndexChunk is a chunk of an indexed file with its embedding vector.
+type CodeIndexChunk struct {
+	ID        int64     `json:"id"`
+	FileID    int64     `json:"file_id"`
+	Path      string    `json:"path"` // 所属文件的路径，查询时从 code_index_files 中读取
+	StartLine int       `json:"start_line"`
+	EndLine   int       `json:"end_line"`
+	Symbol    string    `json:"symbol"`
+	Kind      string    `json:"kind"`
+	Content   string    `json:"content"`
+	Vector    []float32 `json:"-"`
+}
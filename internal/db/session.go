@@ -70,8 +70,13 @@ func (db *Database) ListProjectSessions(projectID int64) ([]*Session, error) {
 
 // Message CRUD operations
 func (db *Database) AddMessage(sessionID int64, role, content string) (int64, error) {
+	return db.AddMessageWithStatus(sessionID, role, content, MessageStatusComplete)
+}
+
+// AddMessageWithStatus 保存消息并记录其状态，如被中断的回复
+func (db *Database) AddMessageWithStatus(sessionID int64, role, content, status string) (int64, error) {
 	stmt, err := db.Prepare(`
-		INSERT INTO messages (session_id, role, content) VALUES (?, ?, ?)
+		INSERT INTO messages (session_id, role, content, status) VALUES (?, ?, ?, ?)
 	`)
 	if err != nil {
 		return 0, err
@@ -84,7 +89,7 @@ func (db *Database) AddMessage(sessionID int64, role, content string) (int64, er
 		log.Printf("Failed to update session timestamp: %v", err)
 	}
 
-	res, err := stmt.Exec(sessionID, role, content)
+	res, err := stmt.Exec(sessionID, role, content, status)
 	if err != nil {
 		return 0, err
 	}
@@ -94,7 +99,7 @@ func (db *Database) AddMessage(sessionID int64, role, content string) (int64, er
 
 func (db *Database) GetSessionMessages(sessionID int64) ([]*Message, error) {
 	rows, err := db.Query(`
-		SELECT id, session_id, role, content, timestamp
+		SELECT id, session_id, role, content, timestamp, status
 		FROM messages WHERE session_id = ? ORDER BY timestamp
 	`, sessionID)
 	if err != nil {
@@ -107,7 +112,7 @@ func (db *Database) GetSessionMessages(sessionID int64) ([]*Message, error) {
 		message := &Message{}
 		err := rows.Scan(
 			&message.ID, &message.SessionID, &message.Role,
-			&message.Content, &message.Timestamp,
+			&message.Content, &message.Timestamp, &message.Status,
 		)
 		if err != nil {
 			return nil, err
@@ -225,6 +230,13 @@ func (db *Database) DeleteSession(id int64) error {
 		return err
 	}
 
+	// Delete history summaries
+	_, err = tx.Exec("DELETE FROM history_summaries WHERE session_id = ?", id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	// Delete code contexts
 	_, err = tx.Exec("DELETE FROM code_contexts WHERE session_id = ?", id)
 	if err != nil {
@@ -232,6 +244,18 @@ func (db *Database) DeleteSession(id int64) error {
 		return err
 	}
 
+	// Delete checkpoints，快照文件按内容共享，不在这里删除
+	_, err = tx.Exec("DELETE FROM checkpoint_files WHERE checkpoint_id IN (SELECT id FROM checkpoints WHERE session_id = ?)", id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM checkpoints WHERE session_id = ?", id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	// Delete the session
 	_, err = tx.Exec("DELETE FROM sessions WHERE id = ?", id)
 	if err != nil {
@@ -251,6 +275,16 @@ func (db *Database) DeleteMessage(id int64) error {
 		return err
 	}
 
+	// 覆盖了该消息的历史摘要不再有效，需要在删除消息之前按消息所属的会话删除
+	_, err = tx.Exec(`
+		DELETE FROM history_summaries
+		WHERE session_id = (SELECT session_id FROM messages WHERE id = ?) AND last_message_id >= ?
+	`, id, id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	// Delete messages
 	_, err = tx.Exec("DELETE FROM messages WHERE id = ?", id)
 	if err != nil {
@@ -277,6 +311,13 @@ func (db *Database) DeleteAllMessage(id int64) error {
 		return err
 	}
 
+	// 清空对话后旧消息的摘要也一起删除
+	_, err = tx.Exec("DELETE FROM history_summaries WHERE session_id = ?", id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	// Commit the transaction
 	return tx.Commit()
 }
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"database/sql"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// History summary operations
+func (db *Database) AddHistorySummary(summary *HistorySummary) (int64, error) {
+	result, err := db.Exec(`
+		INSERT INTO history_summaries (session_id, last_message_id, content, model) VALUES (?, ?, ?, ?)
+	`, summary.SessionID, summary.LastMessageID, summary.Content, summary.Model)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// GetLatestHistorySummary returns the summary covering the most messages of a
+// session, nil without error when the session has none.
+func (db *Database) GetLatestHistorySummary(sessionID int64) (*HistorySummary, error) {
+	summary := &HistorySummary{}
+	err := db.QueryRow(`
+		SELECT id, session_id, last_message_id, content, model, created_at
+		FROM history_summaries WHERE session_id = ?
+		ORDER BY last_message_id DESC, id DESC LIMIT 1
+	`, sessionID).Scan(
+		&summary.ID, &summary.SessionID, &summary.LastMessageID, &summary.Content, &summary.Model, &summary.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Usage operations
+func (db *Database) AddMessageUsage(usage *MessageUsage) (int64, error) {
+	stmt, err := db.Prepare(`
+		INSERT INTO message_usages (session_id, project_id, message_id, model, prompt_tokens, completion_tokens, total_tokens, cost)
+		VALUES (?, (SELECT project_id FROM sessions WHERE id = ?), ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(
+		usage.SessionID, usage.SessionID, usage.MessageID, usage.Model,
+		usage.PromptTokens, usage.CompletionTokens, usage.TotalTokens, usage.Cost,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.LastInsertId()
+}
+
+func (db *Database) GetSessionUsages(sessionID int64) ([]*MessageUsage, error) {
+	rows, err := db.Query(`
+		SELECT id, session_id, IFNULL(project_id, 0), IFNULL(message_id, 0), model,
+			prompt_tokens, completion_tokens, total_tokens, cost, created_at
+		FROM message_usages WHERE session_id = ? ORDER BY id
+	`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usages := []*MessageUsage{}
+	for rows.Next() {
+		usage := &MessageUsage{}
+		err := rows.Scan(
+			&usage.ID, &usage.SessionID, &usage.ProjectID, &usage.MessageID, &usage.Model,
+			&usage.PromptTokens, &usage.CompletionTokens, &usage.TotalTokens, &usage.Cost, &usage.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		usages = append(usages, usage)
+	}
+	return usages, nil
+}
+
+// SumUsages aggregates message_usages filtered by column = id and grouped by groupBy.
+// An empty groupBy returns a single total row.
+func (db *Database) SumUsages(column string, id int64, groupBy string) ([]*UsageSummary, error) {
+	if !isUsageColumn(column) || (groupBy != "" && !isUsageColumn(groupBy)) {
+		return nil, fmt.Errorf("invalid usage column: %s/%s", column, groupBy)
+	}
+
+	keyExpr := "''"
+	groupClause := ""
+	if groupBy != "" {
+		keyExpr = "CAST(" + groupBy + " AS TEXT)"
+		groupClause = " GROUP BY " + groupBy + " ORDER BY SUM(cost) DESC"
+	}
+
+	rows, err := db.Query(`
+		SELECT `+keyExpr+`, COUNT(*), IFNULL(SUM(prompt_tokens), 0), IFNULL(SUM(completion_tokens), 0),
+			IFNULL(SUM(total_tokens), 0), IFNULL(SUM(cost), 0)
+		FROM message_usages WHERE `+column+` = ?`+groupClause, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := []*UsageSummary{}
+	for rows.Next() {
+		summary := &UsageSummary{}
+		err := rows.Scan(
+			&summary.Key, &summary.Requests, &summary.PromptTokens,
+			&summary.CompletionTokens, &summary.TotalTokens, &summary.Cost,
+		)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+func isUsageColumn(column string) bool {
+	switch column {
+	case "session_id", "project_id", "model":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package depgraph
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// goModule 项目中的一个 Go 模块，项目中可以有多个 go.mod
+type goModule struct {
+	path string // 模块路径，例如 mind-weaver
+	dir  string // go.mod 所在目录
+}
+
+func loadGoModules(goMods []string) []goModule {
+	var modules []goModule
+	for _, goMod := range goMods {
+		if path := readModulePath(goMod); path != "" {
+			modules = append(modules, goModule{path: path, dir: filepath.Dir(goMod)})
+		}
+	}
+	// 较长的模块路径优先匹配，嵌套模块不会被外层模块覆盖
+	sort.Slice(modules, func(i, j int) bool {
+		return len(modules[i].path) > len(modules[j].path)
+	})
+	return modules
+}
+
+// readModulePath 读取 go.mod 中的 module 指令
+func readModulePath(goMod string) string {
+	f, err := os.Open(goMod)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "module") {
+			continue
+		}
+		path := strings.TrimSpace(strings.TrimPrefix(line, "module"))
+		if idx := strings.Index(path, "//"); idx >= 0 {
+			path = strings.TrimSpace(path[:idx])
+		}
+		if unquoted, err := strconv.Unquote(path); err == nil {
+			path = unquoted
+		}
+		return path
+	}
+	return ""
+}
+
+// resolveGo 把导入路径映射到项目中的包目录，包内所有非测试文件都是依赖
+func (g *Graph) resolveGo(importPath string) []string {
+	for _, mod := range g.goModules {
+		var dir string
+		switch {
+		case importPath == mod.path:
+			dir = mod.dir
+		case strings.HasPrefix(importPath, mod.path+"/"):
+			dir = filepath.Join(mod.dir, filepath.FromSlash(strings.TrimPrefix(importPath, mod.path+"/")))
+		default:
+			continue
+		}
+		return g.goPackageFiles(dir)
+	}
+	return nil
+}
+
+// indexGoPackages 按目录索引非测试的 Go 文件，调用方需要持有锁
+func (g *Graph) indexGoPackages() {
+	g.goPackages = map[string][]string{}
+	for path, info := range g.files {
+		if info.lang == LangGo && !strings.HasSuffix(path, "_test.go") {
+			dir := filepath.Dir(path)
+			g.goPackages[dir] = append(g.goPackages[dir], path)
+		}
+	}
+}
+
+func (g *Graph) goPackageFiles(dir string) []string {
+	return g.goPackages[dir]
+}
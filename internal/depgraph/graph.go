@@ -0,0 +1,243 @@
+// Package depgraph builds the import graph of a project: which project files a
+// file imports and which project files import it. Go imports are parsed with
+// go/parser and resolved through go.mod module paths, JS/TS imports through
+// relative paths and tsconfig/jsconfig baseUrl and paths, Python imports
+// through packages relative to the project root. Imports of the standard
+// library and third-party packages are not part of the graph.
+package depgraph
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"mind-weaver/internal/utils"
+)
+
+// 单个文件超过该大小时不解析导入，通常是生成或打包后的文件
+const maxFileSize = 1 << 20
+
+// Graph is the import graph of one project. It is safe for concurrent use;
+// call Refresh to pick up changed files.
+type Graph struct {
+	root string
+
+	mu         sync.RWMutex
+	files      map[string]*fileInfo // 绝对路径 -> 文件信息
+	imports    map[string][]string  // 文件 -> 它导入的项目文件
+	importedBy map[string][]string  // 文件 -> 导入它的项目文件
+	goModules  []goModule
+	goPackages map[string][]string  // 目录 -> 包内非测试的 Go 文件
+	tsConfigs  map[string]*tsConfig // tsconfig 所在目录 -> 配置
+}
+
+type fileInfo struct {
+	modTime time.Time
+	size    int64
+	lang    string
+	specs   []string // 文件中的导入语句，未解析
+}
+
+// Build parses the imports of every supported file under root.
+func Build(root string) (*Graph, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	g := &Graph{root: abs, files: map[string]*fileInfo{}}
+	if err := g.Refresh(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Root returns the absolute project path.
+func (g *Graph) Root() string {
+	return g.root
+}
+
+// Refresh re-parses files that changed since the last build and resolves the
+// graph again. Unchanged files are not read.
+func (g *Graph) Refresh() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	seen := map[string]bool{}
+	var goMods, tsConfigPaths []string
+	err := filepath.WalkDir(g.root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if path == g.root {
+				return err
+			}
+			return nil // 无法访问的子目录直接跳过
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != g.root && utils.IsSkippedProjectDir(name) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch name {
+		case "go.mod":
+			goMods = append(goMods, path)
+			return nil
+		case "tsconfig.json", "jsconfig.json":
+			tsConfigPaths = append(tsConfigPaths, path)
+			return nil
+		}
+		lang := Language(path)
+		if lang == "" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > maxFileSize {
+			return nil
+		}
+		seen[path] = true
+		if old, ok := g.files[path]; ok && old.modTime.Equal(info.ModTime()) && old.size == info.Size() {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		g.files[path] = &fileInfo{modTime: info.ModTime(), size: info.Size(), lang: lang, specs: ParseImports(path, string(content))}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for path := range g.files {
+		if !seen[path] {
+			delete(g.files, path)
+		}
+	}
+
+	g.goModules = loadGoModules(goMods)
+	g.tsConfigs = loadTSConfigs(tsConfigPaths)
+	g.resolveAll()
+	return nil
+}
+
+// resolveAll 根据已解析的导入语句重新生成正反两个方向的边
+func (g *Graph) resolveAll() {
+	g.indexGoPackages()
+	g.imports = map[string][]string{}
+	g.importedBy = map[string][]string{}
+	for path, info := range g.files {
+		deps := map[string]bool{}
+		for _, spec := range info.specs {
+			for _, target := range g.resolve(path, info.lang, spec) {
+				if target != path {
+					deps[target] = true
+				}
+			}
+		}
+		for target := range deps {
+			g.imports[path] = append(g.imports[path], target)
+			g.importedBy[target] = append(g.importedBy[target], path)
+		}
+	}
+	for _, edges := range []map[string][]string{g.imports, g.importedBy} {
+		for path := range edges {
+			sort.Strings(edges[path])
+		}
+	}
+}
+
+func (g *Graph) resolve(from, lang, spec string) []string {
+	switch lang {
+	case LangGo:
+		return g.resolveGo(spec)
+	case LangJavaScript, LangTypeScript:
+		if target := g.resolveJS(from, spec); target != "" {
+			return []string{target}
+		}
+	case LangPython:
+		return g.resolvePython(from, spec)
+	}
+	return nil
+}
+
+// Imports returns the project files that file imports, directly and up to
+// depth levels of indirection. Files closer to file come first. depth <= 0
+// means 1.
+func (g *Graph) Imports(file string, depth int) []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return walk(g.imports, []string{g.abs(file)}, depth)
+}
+
+// ImportsOfFiles returns the project files imported by any of files, up to
+// depth levels, excluding files themselves. Files closer to the given ones
+// come first.
+func (g *Graph) ImportsOfFiles(files []string, depth int) []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	starts := make([]string, 0, len(files))
+	for _, file := range files {
+		starts = append(starts, g.abs(file))
+	}
+	return walk(g.imports, starts, depth)
+}
+
+// ImportedBy returns the project files that import file, directly and up to
+// depth levels of indirection. Files closer to file come first. depth <= 0
+// means 1.
+func (g *Graph) ImportedBy(file string, depth int) []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return walk(g.importedBy, []string{g.abs(file)}, depth)
+}
+
+// Contains reports whether file is a parsed source file of the project.
+func (g *Graph) Contains(file string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	_, ok := g.files[g.abs(file)]
+	return ok
+}
+
+// abs 相对路径按项目根目录解析
+func (g *Graph) abs(file string) string {
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(g.root, file)
+	}
+	return filepath.Clean(file)
+}
+
+// walk 按层广度优先遍历，同一层内保持边的排序
+func walk(edges map[string][]string, starts []string, depth int) []string {
+	if depth <= 0 {
+		depth = 1
+	}
+	visited := map[string]bool{}
+	for _, start := range starts {
+		visited[start] = true
+	}
+	var result []string
+	level := starts
+	for d := 0; d < depth && len(level) > 0; d++ {
+		var next []string
+		for _, file := range level {
+			for _, target := range edges[file] {
+				if !visited[target] {
+					visited[target] = true
+					result = append(result, target)
+					next = append(next, target)
+				}
+			}
+		}
+		level = next
+	}
+	return result
+}
+
+// isFile 判断路径是否是已解析的项目文件，调用方需要持有锁
+func (g *Graph) isFile(path string) bool {
+	_, ok := g.files[path]
+	return ok
+}
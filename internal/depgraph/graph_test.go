@@ -0,0 +1,182 @@
+package depgraph
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// rel 把绝对路径转换成相对项目根目录的路径，方便比较
+func rel(t *testing.T, root string, paths []string) []string {
+	t.Helper()
+	result := []string{}
+	for _, path := range paths {
+		r, err := filepath.Rel(root, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, filepath.ToSlash(r))
+	}
+	return result
+}
+
+func TestGoImports(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"go.mod":                     "module example.com/app\n\ngo 1.23\n",
+		"cmd/main.go":                "package main\n\nimport (\n\t\"fmt\"\n\tsvc \"example.com/app/internal/service\"\n)\n\nfunc main() { fmt.Println(svc.Run()) }\n",
+		"internal/service/a.go":      "package service\n\nimport \"example.com/app/internal/store\"\n\nfunc Run() string { return store.Get() }\n",
+		"internal/service/b.go":      "package service\n",
+		"internal/service/a_test.go": "package service\n",
+		"internal/store/store.go":    "package store\n\nfunc Get() string { return \"\" }\n",
+		"vendor/x/x.go":              "package x\n\nimport \"example.com/app/internal/store\"\n",
+	})
+	g, err := Build(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := rel(t, root, g.Imports("cmd/main.go", 1)); !reflect.DeepEqual(got, []string{"internal/service/a.go", "internal/service/b.go"}) {
+		t.Errorf("Expected the files of the imported package, got %v", got)
+	}
+	if got := rel(t, root, g.Imports("cmd/main.go", 2)); !reflect.DeepEqual(got, []string{"internal/service/a.go", "internal/service/b.go", "internal/store/store.go"}) {
+		t.Errorf("Expected indirect imports at depth 2, got %v", got)
+	}
+	if got := rel(t, root, g.ImportedBy(filepath.Join(root, "internal/store/store.go"), 2)); !reflect.DeepEqual(got, []string{"internal/service/a.go", "cmd/main.go"}) {
+		t.Errorf("Expected importers without vendored files, got %v", got)
+	}
+}
+
+func TestJSImports(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"tsconfig.json": `{
+  // 注释和末尾逗号
+  "compilerOptions": {
+    "baseUrl": ".",
+    "paths": { "@/*": ["src/*"], "@utils": ["src/utils/index.ts"], },
+  },
+}`,
+		"src/app.tsx": `import React from 'react'
+import { Button } from './components/Button'
+import {
+  format,
+} from '@utils'
+import type { User } from "@/models/user.js"
+// import { old } from './old'
+const lazy = () => import('./pages/home')
+`,
+		"src/components/Button.tsx": "export const Button = () => null\n",
+		"src/utils/index.ts":        "export * from './format'\n",
+		"src/utils/format.ts":       "export const format = (s: string) => s\n",
+		"src/models/user.ts":        "export interface User {}\n",
+		"src/pages/home/index.js":   "const config = require('../../../config')\n",
+		"src/old.ts":                "",
+		"config.js":                 "module.exports = {}\n",
+	})
+	g, err := Build(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"src/components/Button.tsx", "src/models/user.ts", "src/pages/home/index.js", "src/utils/index.ts"}
+	if got := rel(t, root, g.Imports("src/app.tsx", 1)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected relative, tsconfig paths and dynamic imports, got %v", got)
+	}
+	if got := rel(t, root, g.ImportedBy("src/utils/format.ts", 3)); !reflect.DeepEqual(got, []string{"src/utils/index.ts", "src/app.tsx"}) {
+		t.Errorf("Expected importers through the barrel file, got %v", got)
+	}
+	if got := rel(t, root, g.ImportedBy("config.js", 1)); !reflect.DeepEqual(got, []string{"src/pages/home/index.js"}) {
+		t.Errorf("Expected require to be resolved, got %v", got)
+	}
+}
+
+func TestPythonImports(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"app/__init__.py":          "",
+		"app/main.py":              "import os\nimport app.models as models\nfrom .services import (\n    billing,  # 计费\n    users,\n)\nfrom app.config import SETTINGS\n",
+		"app/models.py":            "from . import config\n",
+		"app/config.py":            "SETTINGS = {}\n",
+		"app/services/__init__.py": "",
+		"app/services/billing.py":  "from ..models import Model\n",
+		"app/services/users.py":    "",
+	})
+	g, err := Build(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"app/config.py", "app/models.py", "app/services/__init__.py", "app/services/billing.py", "app/services/users.py"}
+	if got := rel(t, root, g.Imports("app/main.py", 1)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected absolute, relative and multi-line imports, got %v", got)
+	}
+	if got := rel(t, root, g.Imports("app/services/billing.py", 1)); !reflect.DeepEqual(got, []string{"app/models.py"}) {
+		t.Errorf("Expected parent package import, got %v", got)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a.js": "import './b'\n",
+		"b.js": "",
+		"c.js": "",
+	})
+	g, err := Build(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rel(t, root, g.Imports("a.js", 1)); !reflect.DeepEqual(got, []string{"b.js"}) {
+		t.Fatalf("Expected a.js to import b.js, got %v", got)
+	}
+
+	writeFiles(t, root, map[string]string{"a.js": "import './c'\n"})
+	// 确保修改时间变化
+	later := time.Now().Add(time.Second)
+	os.Chtimes(filepath.Join(root, "a.js"), later, later)
+	os.Remove(filepath.Join(root, "b.js"))
+	if err := g.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if got := rel(t, root, g.Imports("a.js", 1)); !reflect.DeepEqual(got, []string{"c.js"}) {
+		t.Errorf("Expected the changed import after refresh, got %v", got)
+	}
+	if g.Contains("b.js") {
+		t.Errorf("Expected removed file to be dropped")
+	}
+}
+
+func TestImportsOfFiles(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a.ts":      "import './shared'\nimport './b'\n",
+		"b.ts":      "import './shared'\nimport './c'\n",
+		"c.ts":      "import './d'\n",
+		"d.ts":      "",
+		"shared.ts": "",
+	})
+	g, err := Build(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 起始文件本身不会出现在结果中，较近的依赖排在前面
+	if got := rel(t, root, g.ImportsOfFiles([]string{"a.ts", "b.ts"}, 2)); !reflect.DeepEqual(got, []string{"shared.ts", "c.ts", "d.ts"}) {
+		t.Errorf("Expected dependencies of both files ordered by distance, got %v", got)
+	}
+}
@@ -0,0 +1,205 @@
+package depgraph
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// 省略扩展名时依次尝试的扩展名，与 TypeScript/webpack 的默认解析顺序一致
+var jsExtensions = []string{".ts", ".tsx", ".mts", ".cts", ".js", ".jsx", ".mjs", ".cjs"}
+
+// tsConfig tsconfig.json/jsconfig.json 中与模块解析相关的配置
+type tsConfig struct {
+	baseURL string              // 绝对路径，未设置 baseUrl 时为 tsconfig 所在目录
+	paths   map[string][]string // 路径别名，例如 "@/*": ["src/*"]
+	hasBase bool                // 是否设置了 baseUrl，设置后非相对导入也按 baseUrl 查找
+}
+
+type rawTSConfig struct {
+	Extends         string `json:"extends"`
+	CompilerOptions struct {
+		BaseURL *string             `json:"baseUrl"`
+		Paths   map[string][]string `json:"paths"`
+	} `json:"compilerOptions"`
+}
+
+func loadTSConfigs(paths []string) map[string]*tsConfig {
+	configs := map[string]*tsConfig{}
+	for _, path := range paths {
+		dir := filepath.Dir(path)
+		// 同一目录同时有 tsconfig.json 和 jsconfig.json 时使用 tsconfig.json
+		if _, ok := configs[dir]; ok && filepath.Base(path) == "jsconfig.json" {
+			continue
+		}
+		if cfg := readTSConfig(path, 0); cfg != nil {
+			configs[dir] = cfg
+		}
+	}
+	return configs
+}
+
+// readTSConfig 读取配置，extends 指向的相对路径配置中的 baseUrl 和 paths 会被继承
+func readTSConfig(path string, depth int) *tsConfig {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var raw rawTSConfig
+	if err := json.Unmarshal(stripJSONC(data), &raw); err != nil {
+		return nil
+	}
+	dir := filepath.Dir(path)
+	cfg := &tsConfig{baseURL: dir}
+	if raw.Extends != "" && strings.HasPrefix(raw.Extends, ".") && depth < 5 {
+		parent := filepath.Join(dir, raw.Extends)
+		if filepath.Ext(parent) != ".json" {
+			parent += ".json"
+		}
+		// 继承的 baseUrl 和 paths 仍然相对于定义它们的配置文件
+		if base := readTSConfig(parent, depth+1); base != nil {
+			*cfg = *base
+		}
+	}
+	if raw.CompilerOptions.BaseURL != nil {
+		cfg.baseURL = filepath.Join(dir, *raw.CompilerOptions.BaseURL)
+		cfg.hasBase = true
+	}
+	if raw.CompilerOptions.Paths != nil {
+		cfg.paths = raw.CompilerOptions.Paths
+		if !cfg.hasBase {
+			cfg.baseURL = dir // 没有 baseUrl 时 paths 相对于配置文件所在目录
+		}
+	}
+	return cfg
+}
+
+var trailingComma = regexp.MustCompile(`,(\s*[}\]])`)
+
+// stripJSONC 去掉 tsconfig 中允许的注释和末尾逗号，字符串中的内容保持不变
+func stripJSONC(data []byte) []byte {
+	var out []byte
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			out = append(out, c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out = append(out, data[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+		if c == '"' {
+			inString = true
+		} else if c == '/' && i+1 < len(data) && data[i+1] == '/' {
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+		} else if c == '/' && i+1 < len(data) && data[i+1] == '*' {
+			end := strings.Index(string(data[i+2:]), "*/")
+			if end < 0 {
+				break
+			}
+			i += end + 3
+			continue
+		}
+		if i < len(data) {
+			out = append(out, data[i])
+		}
+	}
+	return trailingComma.ReplaceAll(out, []byte("$1"))
+}
+
+// resolveJS 解析 JS/TS 导入，找不到项目文件时返回空字符串
+func (g *Graph) resolveJS(from, spec string) string {
+	if strings.HasPrefix(spec, "./") || strings.HasPrefix(spec, "../") || spec == "." || spec == ".." {
+		return g.resolveJSFile(filepath.Join(filepath.Dir(from), spec))
+	}
+	if strings.HasPrefix(spec, "/") {
+		return g.resolveJSFile(filepath.Join(g.root, spec))
+	}
+
+	cfg := g.nearestTSConfig(from)
+	if cfg == nil {
+		return ""
+	}
+	for _, target := range matchTSPaths(cfg.paths, spec) {
+		if resolved := g.resolveJSFile(filepath.Join(cfg.baseURL, target)); resolved != "" {
+			return resolved
+		}
+	}
+	if cfg.hasBase {
+		return g.resolveJSFile(filepath.Join(cfg.baseURL, spec))
+	}
+	return ""
+}
+
+// resolveJSFile 依次尝试原路径、补全扩展名和目录下的 index 文件。
+// TS 中 import './a.js' 可以指向 a.ts，所以也会替换已有的扩展名
+func (g *Graph) resolveJSFile(path string) string {
+	if g.isFile(path) {
+		return path
+	}
+	candidates := []string{path}
+	if ext := filepath.Ext(path); ext != "" && Language(path) != "" {
+		candidates = append(candidates, strings.TrimSuffix(path, ext))
+	}
+	for _, base := range candidates {
+		for _, ext := range jsExtensions {
+			if g.isFile(base + ext) {
+				return base + ext
+			}
+		}
+	}
+	for _, ext := range jsExtensions {
+		if index := filepath.Join(path, "index"+ext); g.isFile(index) {
+			return index
+		}
+	}
+	return ""
+}
+
+// nearestTSConfig 返回离文件最近的上级目录中的配置
+func (g *Graph) nearestTSConfig(file string) *tsConfig {
+	for dir := filepath.Dir(file); ; dir = filepath.Dir(dir) {
+		if cfg, ok := g.tsConfigs[dir]; ok {
+			return cfg
+		}
+		if dir == g.root || dir == filepath.Dir(dir) {
+			return nil
+		}
+	}
+}
+
+// matchTSPaths 按 paths 规则替换导入路径，支持一个 * 通配符。
+// 精确匹配优先，多个通配规则匹配时使用前缀最长的
+func matchTSPaths(paths map[string][]string, spec string) []string {
+	if targets, ok := paths[spec]; ok {
+		return targets
+	}
+	bestPattern, bestPrefix := "", -1
+	for pattern := range paths {
+		prefix, suffix, ok := strings.Cut(pattern, "*")
+		if !ok || len(spec) < len(prefix)+len(suffix) || !strings.HasPrefix(spec, prefix) || !strings.HasSuffix(spec, suffix) {
+			continue
+		}
+		if len(prefix) > bestPrefix || (len(prefix) == bestPrefix && pattern < bestPattern) {
+			bestPattern, bestPrefix = pattern, len(prefix)
+		}
+	}
+	if bestPrefix < 0 {
+		return nil
+	}
+	prefix, suffix, _ := strings.Cut(bestPattern, "*")
+	star := spec[len(prefix) : len(spec)-len(suffix)]
+	var targets []string
+	for _, target := range paths[bestPattern] {
+		targets = append(targets, strings.Replace(target, "*", star, 1))
+	}
+	return targets
+}
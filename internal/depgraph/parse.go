@@ -0,0 +1,180 @@
+package depgraph
+
+import (
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 支持解析导入的语言
+const (
+	LangGo         = "go"
+	LangJavaScript = "javascript"
+	LangTypeScript = "typescript"
+	LangPython     = "python"
+)
+
+// Language returns the language of a file whose imports can be parsed, or ""
+// when the file is not supported.
+func Language(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".go":
+		return LangGo
+	case ".js", ".jsx", ".mjs", ".cjs":
+		return LangJavaScript
+	case ".ts", ".tsx", ".mts", ".cts":
+		return LangTypeScript
+	case ".py":
+		return LangPython
+	}
+	return ""
+}
+
+var (
+	// import x from 'a'、import 'a'、export * from 'a'，from 前可以跨多行
+	jsFromImport = regexp.MustCompile(`(?m)^\s*(?:import|export)\b[^'"` + "`" + `;]*?\bfrom\s*['"]([^'"]+)['"]`)
+	jsSideEffect = regexp.MustCompile(`(?m)^\s*import\s*['"]([^'"]+)['"]`)
+	// require('a')、import('a')
+	jsCallImport = regexp.MustCompile(`\b(?:require|import)\s*\(\s*['"]([^'"]+)['"]\s*\)`)
+
+	pyImport     = regexp.MustCompile(`^import\s+(.+)$`)
+	pyFromImport = regexp.MustCompile(`^from\s+(\.*[\w.]*)\s+import\s+(.+)$`)
+)
+
+// ParseImports returns the import specifiers of a file. Go and JS/TS
+// specifiers are the imported paths. Python specifiers are module names, with
+// leading dots for relative imports; "from a import b" yields "a.b" followed
+// by "a", since b may be a submodule or a name defined in a.
+func ParseImports(path, content string) []string {
+	switch Language(path) {
+	case LangGo:
+		return parseGoImports(content)
+	case LangJavaScript, LangTypeScript:
+		return parseJSImports(content)
+	case LangPython:
+		return parsePythonImports(content)
+	}
+	return nil
+}
+
+func parseGoImports(content string) []string {
+	file, err := parser.ParseFile(token.NewFileSet(), "", content, parser.ImportsOnly)
+	if err != nil && file == nil {
+		return nil
+	}
+	var imports []string
+	for _, spec := range file.Imports {
+		if path, err := strconv.Unquote(spec.Path.Value); err == nil {
+			imports = append(imports, path)
+		}
+	}
+	return imports
+}
+
+func parseJSImports(content string) []string {
+	content = stripJSComments(content)
+	seen := map[string]bool{}
+	var imports []string
+	for _, re := range []*regexp.Regexp{jsFromImport, jsSideEffect, jsCallImport} {
+		for _, match := range re.FindAllStringSubmatch(content, -1) {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				imports = append(imports, match[1])
+			}
+		}
+	}
+	return imports
+}
+
+// stripJSComments 去掉块注释和行注释，避免注释掉的导入被解析。
+// 简化处理：字符串中的 // (如 URL) 只在前面是空白或行首时才当作注释
+func stripJSComments(content string) string {
+	var b strings.Builder
+	for len(content) > 0 {
+		start := strings.Index(content, "/*")
+		if start < 0 {
+			b.WriteString(content)
+			break
+		}
+		b.WriteString(content[:start])
+		end := strings.Index(content[start+2:], "*/")
+		if end < 0 {
+			break
+		}
+		// 保留换行，行首匹配仍然正确
+		b.WriteString(strings.Repeat("\n", strings.Count(content[start:start+2+end], "\n")))
+		content = content[start+2+end+2:]
+	}
+	lines := strings.Split(b.String(), "\n")
+	for i, line := range lines {
+		if idx := strings.Index(line, "//"); idx >= 0 && (idx == 0 || line[idx-1] == ' ' || line[idx-1] == '\t') {
+			lines[i] = line[:idx]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func parsePythonImports(content string) []string {
+	var imports []string
+	for _, stmt := range pythonStatements(content) {
+		if match := pyFromImport.FindStringSubmatch(stmt); match != nil {
+			module := match[1]
+			names := strings.Trim(strings.TrimSpace(match[2]), "()")
+			for _, name := range strings.Split(names, ",") {
+				name = strings.TrimSpace(strings.SplitN(strings.TrimSpace(name), " ", 2)[0])
+				if name == "" || name == "*" {
+					continue
+				}
+				if strings.HasSuffix(module, ".") {
+					imports = append(imports, module+name) // from . import x
+				} else {
+					imports = append(imports, module+"."+name)
+				}
+			}
+			// 导入的名称也可能定义在模块本身(包的 __init__.py)中
+			imports = append(imports, module)
+			continue
+		}
+		if match := pyImport.FindStringSubmatch(stmt); match != nil {
+			for _, name := range strings.Split(match[1], ",") {
+				if name = strings.TrimSpace(strings.SplitN(strings.TrimSpace(name), " ", 2)[0]); name != "" {
+					imports = append(imports, name)
+				}
+			}
+		}
+	}
+	return imports
+}
+
+// pythonStatements 返回去掉缩进和注释的 import 语句，括号和反斜杠续行会被合并成一行
+func pythonStatements(content string) []string {
+	var stmts []string
+	var current strings.Builder
+	open := false
+	for _, line := range strings.Split(content, "\n") {
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+		if !open && current.Len() == 0 && !strings.HasPrefix(line, "import ") && !strings.HasPrefix(line, "from ") {
+			continue
+		}
+		continued := strings.HasSuffix(line, "\\")
+		line = strings.TrimSuffix(line, "\\")
+		current.WriteString(line)
+		current.WriteString(" ")
+		if strings.Contains(line, "(") && !strings.Contains(line, ")") {
+			open = true
+		} else if open && strings.Contains(line, ")") {
+			open = false
+		}
+		if !open && !continued {
+			stmts = append(stmts, strings.Join(strings.Fields(current.String()), " "))
+			current.Reset()
+		}
+	}
+	return stmts
+}
@@ -0,0 +1,50 @@
+package depgraph
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// 绝对导入除了项目根目录外还会在这些目录下查找，对应常见的 src 布局
+var pythonSourceRoots = []string{"", "src"}
+
+// resolvePython 把模块名映射到 a/b.py 或 a/b/__init__.py
+func (g *Graph) resolvePython(from, module string) []string {
+	if strings.HasPrefix(module, ".") {
+		// 相对导入：一个点表示当前包，每多一个点向上一级
+		dots := len(module) - len(strings.TrimLeft(module, "."))
+		dir := filepath.Dir(from)
+		for i := 1; i < dots; i++ {
+			dir = filepath.Dir(dir)
+		}
+		if target := g.pythonModuleFile(dir, module[dots:]); target != "" {
+			return []string{target}
+		}
+		return nil
+	}
+
+	// 绝对导入先在项目的源码根目录查找，再在当前文件所在目录查找(直接运行的脚本导入同目录的模块)
+	for _, root := range pythonSourceRoots {
+		if target := g.pythonModuleFile(filepath.Join(g.root, root), module); target != "" {
+			return []string{target}
+		}
+	}
+	if target := g.pythonModuleFile(filepath.Dir(from), module); target != "" {
+		return []string{target}
+	}
+	return nil
+}
+
+func (g *Graph) pythonModuleFile(dir, module string) string {
+	path := dir
+	if module != "" {
+		path = filepath.Join(dir, filepath.FromSlash(strings.ReplaceAll(module, ".", "/")))
+	}
+	if module != "" && g.isFile(path+".py") {
+		return path + ".py"
+	}
+	if init := filepath.Join(path, "__init__.py"); g.isFile(init) {
+		return init
+	}
+	return ""
+}
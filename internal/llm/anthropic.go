@@ -0,0 +1,230 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultAnthropicBaseURL   = "https://api.anthropic.com"
+	anthropicVersion          = "2023-06-01"
+	defaultAnthropicMaxTokens = 4096
+)
+
+// AnthropicProvider talks to the native Anthropic Messages API.
+type AnthropicProvider struct {
+	baseURL string
+	apiKey  string
+	extra   map[string]string
+	client  *http.Client
+}
+
+type anthropicRequest struct {
+	Model       string    `json:"model"`
+	System      string    `json:"system,omitempty"`
+	Messages    []Message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	TopP        float64   `json:"top_p,omitempty"`
+	Stream      bool      `json:"stream,omitempty"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string         `json:"stop_reason"`
+	Usage      anthropicUsage `json:"usage"`
+}
+
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage anthropicUsage `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func NewAnthropicProvider(opts Options) *AnthropicProvider {
+	baseURL := opts.BaseURL
+	if baseURL == "" {
+		baseURL = defaultAnthropicBaseURL
+	}
+	return &AnthropicProvider{
+		baseURL: baseURL,
+		apiKey:  opts.APIKey,
+		extra:   opts.Headers,
+		client:  opts.HTTPClient,
+	}
+}
+
+func (p *AnthropicProvider) Name() string {
+	return ProviderAnthropic
+}
+
+func (p *AnthropicProvider) headers() map[string]string {
+	return mergeHeaders(map[string]string{
+		"x-api-key":         p.apiKey,
+		"anthropic-version": anthropicVersion,
+	}, p.extra)
+}
+
+// buildRequest moves system messages into the top level system field and merges
+// consecutive messages of the same role, as the Messages API requires alternation.
+func (p *AnthropicProvider) buildRequest(req *ChatRequest, stream bool) anthropicRequest {
+	var systemParts []string
+	messages := make([]Message, 0, len(req.Messages))
+	for _, msg := range req.Messages {
+		if msg.Role == RoleSystem {
+			systemParts = append(systemParts, msg.Content)
+			continue
+		}
+		if n := len(messages); n > 0 && messages[n-1].Role == msg.Role {
+			messages[n-1].Content += "\n\n" + msg.Content
+			continue
+		}
+		messages = append(messages, Message{Role: msg.Role, Content: msg.Content})
+	}
+
+	maxTokens := req.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultAnthropicMaxTokens
+	}
+	temperature := req.Temperature
+
+	return anthropicRequest{
+		Model:       req.Model,
+		System:      strings.Join(systemParts, "\n\n"),
+		Messages:    messages,
+		MaxTokens:   maxTokens,
+		Temperature: &temperature,
+		TopP:        req.TopP,
+		Stream:      stream,
+	}
+}
+
+func (p *AnthropicProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	var result anthropicResponse
+	err := decodeJSON(ctx, p.client, http.MethodPost, p.baseURL+"/v1/messages", p.headers(), p.buildRequest(req, false), &result)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Content) == 0 {
+		return nil, errors.New("no completion content returned")
+	}
+
+	var content strings.Builder
+	for _, block := range result.Content {
+		if block.Type == "text" {
+			content.WriteString(block.Text)
+		}
+	}
+
+	return &ChatResponse{
+		Content:      content.String(),
+		FinishReason: result.StopReason,
+		Usage:        result.Usage.toUsage(),
+	}, nil
+}
+
+func (p *AnthropicProvider) ChatStream(ctx context.Context, req *ChatRequest, handler StreamHandler) (*Usage, error) {
+	resp, err := doJSON(ctx, p.client, http.MethodPost, p.baseURL+"/v1/messages", p.headers(), p.buildRequest(req, true))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	usage := anthropicUsage{}
+	err = readSSE(resp.Body, func(event, data string) error {
+		var ev anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return nil // Skip malformed chunks
+		}
+
+		switch ev.Type {
+		case "message_start":
+			usage.InputTokens = ev.Message.Usage.InputTokens
+		case "content_block_delta":
+			if ev.Delta.Type == "text_delta" && ev.Delta.Text != "" {
+				return handler(StreamEvent{Content: ev.Delta.Text})
+			}
+		case "message_delta":
+			usage.OutputTokens = ev.Usage.OutputTokens
+			return handler(StreamEvent{FinishReason: ev.Delta.StopReason})
+		case "message_stop":
+			return errStreamDone
+		case "error":
+			return fmt.Errorf("anthropic stream error: %s: %s", ev.Error.Type, ev.Error.Message)
+		}
+		return nil
+	})
+
+	return usage.toUsage(), err
+}
+
+func (p *AnthropicProvider) CountTokens(ctx context.Context, req *ChatRequest) (int, error) {
+	body := p.buildRequest(req, false)
+	countReq := struct {
+		Model    string    `json:"model"`
+		System   string    `json:"system,omitempty"`
+		Messages []Message `json:"messages"`
+	}{
+		Model:    body.Model,
+		System:   body.System,
+		Messages: body.Messages,
+	}
+
+	var result struct {
+		InputTokens int `json:"input_tokens"`
+	}
+	if err := decodeJSON(ctx, p.client, http.MethodPost, p.baseURL+"/v1/messages/count_tokens", p.headers(), countReq, &result); err != nil {
+		// 网关不一定支持计数接口，退回到本地估算
+		return estimateTokens(req), nil
+	}
+	return result.InputTokens, nil
+}
+
+func (p *AnthropicProvider) ListModels(ctx context.Context) ([]string, error) {
+	var result struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := decodeJSON(ctx, p.client, http.MethodGet, p.baseURL+"/v1/models", p.headers(), nil, &result); err != nil {
+		return nil, err
+	}
+
+	models := make([]string, 0, len(result.Data))
+	for _, m := range result.Data {
+		models = append(models, m.ID)
+	}
+	return models, nil
+}
+
+func (u anthropicUsage) toUsage() *Usage {
+	return &Usage{
+		PromptTokens:     u.InputTokens,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      u.InputTokens + u.OutputTokens,
+	}
+}
@@ -0,0 +1,208 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// ProviderLocal is the embedder kind that hashes tokens locally without calling
+// any endpoint. It only matches shared words but needs no model.
+const ProviderLocal = "local"
+
+// EmbeddingRequest asks for one vector per input text.
+type EmbeddingRequest struct {
+	Model      string
+	Input      []string
+	Dimensions int // 向量维度，0 表示使用模型默认值
+}
+
+// Embedder turns texts into vectors for semantic search.
+type Embedder interface {
+	// Embed returns the vectors of req.Input in the same order.
+	Embed(ctx context.Context, req *EmbeddingRequest) ([][]float32, error)
+}
+
+// NewEmbedder creates an embedder of the given kind. An empty kind means an
+// OpenAI compatible /v1/embeddings endpoint.
+func NewEmbedder(kind string, opts Options) (Embedder, error) {
+	if strings.ToLower(kind) == ProviderLocal {
+		return HashEmbedder{}, nil
+	}
+	provider, err := NewProvider(kind, opts)
+	if err != nil {
+		return nil, err
+	}
+	embedder, ok := provider.(Embedder)
+	if !ok {
+		return nil, fmt.Errorf("llm provider %s has no embedding API", provider.Name())
+	}
+	return embedder, nil
+}
+
+type openAIEmbeddingRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+func (p *OpenAIProvider) Embed(ctx context.Context, req *EmbeddingRequest) ([][]float32, error) {
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	body := openAIEmbeddingRequest{Model: req.Model, Input: req.Input, Dimensions: req.Dimensions}
+	if err := decodeJSON(ctx, p.client, http.MethodPost, p.baseURL+"/v1/embeddings", p.headers(), body, &result); err != nil {
+		return nil, err
+	}
+
+	// 返回的顺序不一定和输入一致，按 index 放回对应位置
+	vectors := make([][]float32, len(req.Input))
+	for _, item := range result.Data {
+		if item.Index < 0 || item.Index >= len(vectors) {
+			return nil, fmt.Errorf("embedding index %d out of range", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	return checkEmbeddings(vectors)
+}
+
+func (p *OllamaProvider) Embed(ctx context.Context, req *EmbeddingRequest) ([][]float32, error) {
+	var result struct {
+		Embeddings [][]float32 `json:"embeddings"`
+		Error      string      `json:"error"`
+	}
+	body := map[string]any{"model": req.Model, "input": req.Input}
+	if err := decodeJSON(ctx, p.client, http.MethodPost, p.baseURL+"/api/embed", p.headers(), body, &result); err != nil {
+		return nil, err
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("ollama error: %s", result.Error)
+	}
+	if len(result.Embeddings) != len(req.Input) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(req.Input), len(result.Embeddings))
+	}
+	return checkEmbeddings(result.Embeddings)
+}
+
+func checkEmbeddings(vectors [][]float32) ([][]float32, error) {
+	for i, vector := range vectors {
+		if len(vector) == 0 {
+			return nil, fmt.Errorf("no embedding returned for input %d", i)
+		}
+	}
+	return vectors, nil
+}
+
+// 本地哈希向量的默认维度
+const defaultHashDimensions = 512
+
+// HashEmbedder maps the words of a text into a fixed size vector with the
+// hashing trick. camelCase and snake_case identifiers are split into words, so
+// "refundRetry" and "refund retries" share dimensions. It is used when no
+// embedding endpoint is configured and in tests.
+type HashEmbedder struct{}
+
+func (HashEmbedder) Embed(ctx context.Context, req *EmbeddingRequest) ([][]float32, error) {
+	dimensions := req.Dimensions
+	if dimensions <= 0 {
+		dimensions = defaultHashDimensions
+	}
+	vectors := make([][]float32, len(req.Input))
+	for i, text := range req.Input {
+		vector := make([]float32, dimensions)
+		for _, word := range splitWords(text) {
+			if stopWords[word] {
+				continue
+			}
+			h := fnv.New32a()
+			h.Write([]byte(stemWord(word)))
+			sum := h.Sum32()
+			// 用最高位决定符号，减少哈希冲突带来的偏差
+			sign := float32(1)
+			if sum&0x80000000 != 0 {
+				sign = -1
+			}
+			vector[int(sum%uint32(dimensions))] += sign
+		}
+		vectors[i] = normalize(vector)
+	}
+	return vectors, nil
+}
+
+// stopWords 几乎每段代码或每个问题里都会出现的词，不参与计算
+var stopWords = map[string]bool{
+	"the": true, "and": true, "or": true, "of": true, "to": true, "in": true, "is": true, "it": true,
+	"we": true, "do": true, "does": true, "where": true, "what": true, "how": true, "which": true,
+	"func": true, "function": true, "def": true, "return": true, "if": true, "else": true, "for": true,
+	"var": true, "let": true, "const": true, "nil": true, "null": true, "err": true, "self": true, "this": true,
+}
+
+// splitWords 按非字母数字字符和驼峰边界拆分，转为小写，忽略单个字符
+func splitWords(text string) []string {
+	var words []string
+	var current []rune
+	flush := func() {
+		if len(current) > 1 {
+			words = append(words, strings.ToLower(string(current)))
+		}
+		current = current[:0]
+	}
+	runes := []rune(text)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		// fooBar 和 HTTPServer 中的 B、S 开始一个新单词
+		if unicode.IsUpper(r) && len(current) > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	return words
+}
+
+// stemWord 去掉常见的英文词尾，让 retries、retrying、retried 和 handle、handles、handled 分别落到同一个维度
+func stemWord(word string) string {
+	for _, suffix := range []string{"ies", "ied", "ing", "ed", "s"} {
+		if len(word) <= len(suffix)+2 || !strings.HasSuffix(word, suffix) || strings.HasSuffix(word, "ss") {
+			continue
+		}
+		word = strings.TrimSuffix(word, suffix)
+		if suffix == "ies" || suffix == "ied" {
+			word += "y"
+		}
+		break
+	}
+	if len(word) > 3 {
+		word = strings.TrimSuffix(word, "e")
+	}
+	return word
+}
+
+func normalize(vector []float32) []float32 {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return vector
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range vector {
+		vector[i] /= norm
+	}
+	return vector
+}
@@ -0,0 +1,101 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestProviderEmbed(t *testing.T) {
+	tests := []struct {
+		name string
+		kind string
+		path string
+		body string
+	}{
+		{
+			name: "openai",
+			kind: ProviderOpenAI,
+			path: "/v1/embeddings",
+			// 返回顺序与输入不同
+			body: `{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`,
+		},
+		{
+			name: "ollama",
+			kind: ProviderOllama,
+			path: "/api/embed",
+			body: `{"embeddings":[[1,0],[0,1]]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.path {
+					http.Error(w, "unexpected path "+r.URL.Path, http.StatusNotFound)
+					return
+				}
+				var req struct {
+					Model string   `json:"model"`
+					Input []string `json:"input"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Model != "embed-model" || len(req.Input) != 2 {
+					http.Error(w, fmt.Sprintf("unexpected request %+v %v", req, err), http.StatusBadRequest)
+					return
+				}
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			embedder, err := NewEmbedder(tt.kind, Options{BaseURL: server.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+			vectors, err := embedder.Embed(context.Background(), &EmbeddingRequest{Model: "embed-model", Input: []string{"a", "b"}})
+			if err != nil {
+				t.Fatalf("Embed failed: %v", err)
+			}
+			if want := [][]float32{{1, 0}, {0, 1}}; !reflect.DeepEqual(vectors, want) {
+				t.Errorf("Expected %v, got %v", want, vectors)
+			}
+		})
+	}
+
+	if _, err := NewEmbedder(ProviderAnthropic, Options{}); err == nil {
+		t.Error("Expected an error for a provider without embedding API")
+	}
+}
+
+func TestHashEmbedder(t *testing.T) {
+	if got, want := splitWords("HTTPServer.retryRefund(order_id)"), []string{"http", "server", "retry", "refund", "order", "id"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	vectors, err := HashEmbedder{}.Embed(context.Background(), &EmbeddingRequest{Input: []string{
+		"where do we handle refund retries",
+		"func (s *PaymentService) retryRefund(ctx context.Context, refund *Refund) error",
+		"func renderTemplate(w io.Writer, name string) error",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors[0]) != defaultHashDimensions {
+		t.Fatalf("Expected %d dimensions, got %d", defaultHashDimensions, len(vectors[0]))
+	}
+	related, unrelated := dot(vectors[0], vectors[1]), dot(vectors[0], vectors[2])
+	if related <= unrelated || related <= 0.3 {
+		t.Errorf("Expected the refund code to be closer, got %.3f vs %.3f", related, unrelated)
+	}
+}
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
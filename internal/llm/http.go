@@ -0,0 +1,165 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errStreamDone is returned by SSE callbacks to stop reading without an error.
+var errStreamDone = errors.New("stream done")
+
+// APIError is returned when the provider answers with a non-200 status.
+type APIError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // 上游 Retry-After 头，没有时为 0
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+// mergeHeaders copies extra into base, extra wins on conflicts.
+func mergeHeaders(base, extra map[string]string) map[string]string {
+	for k, v := range extra {
+		base[k] = v
+	}
+	return base
+}
+
+// doJSON sends body as JSON and returns the response if the status is 200.
+// The caller must close the response body.
+func doJSON(ctx context.Context, client *http.Client, method, url string, headers map[string]string, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		jsonData, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewBuffer(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Body:       string(bodyBytes),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+
+	return resp, nil
+}
+
+// parseRetryAfter accepts both forms of the Retry-After header: seconds or an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// decodeJSON sends a request and decodes the JSON answer into out.
+func decodeJSON(ctx context.Context, client *http.Client, method, url string, headers map[string]string, body any, out any) error {
+	resp, err := doJSON(ctx, client, method, url, headers, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// readSSE reads a server-sent event stream and calls fn for every data line
+// together with the most recent event name. Returning errStreamDone from fn
+// ends the stream successfully.
+func readSSE(r io.Reader, fn func(event, data string) error) error {
+	reader := bufio.NewReader(r)
+	event := ""
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			// 空行表示一个事件结束
+			event = ""
+		case strings.HasPrefix(trimmed, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(trimmed, "event:"))
+		case strings.HasPrefix(trimmed, "data:"):
+			data := strings.TrimSpace(strings.TrimPrefix(trimmed, "data:"))
+			if cbErr := fn(event, data); cbErr != nil {
+				if cbErr == errStreamDone {
+					return nil
+				}
+				return cbErr
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// readLines calls fn for every non-empty line, used by NDJSON streams.
+func readLines(r io.Reader, fn func(line string) error) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			if cbErr := fn(trimmed); cbErr != nil {
+				if cbErr == errStreamDone {
+					return nil
+				}
+				return cbErr
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const defaultOllamaBaseURL = "http://localhost:11434"
+
+// OllamaProvider talks to the native Ollama /api/chat endpoint.
+type OllamaProvider struct {
+	baseURL string
+	apiKey  string
+	extra   map[string]string
+	client  *http.Client
+}
+
+type ollamaOptions struct {
+	Temperature float64 `json:"temperature"`
+	TopP        float64 `json:"top_p,omitempty"`
+	NumPredict  int     `json:"num_predict,omitempty"`
+}
+
+type ollamaRequest struct {
+	Model    string        `json:"model"`
+	Messages []Message     `json:"messages"`
+	Stream   bool          `json:"stream"`
+	Options  ollamaOptions `json:"options"`
+}
+
+type ollamaResponse struct {
+	Message struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	} `json:"message"`
+	Done            bool   `json:"done"`
+	DoneReason      string `json:"done_reason"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+	Error           string `json:"error"`
+}
+
+func NewOllamaProvider(opts Options) *OllamaProvider {
+	baseURL := opts.BaseURL
+	if baseURL == "" {
+		baseURL = defaultOllamaBaseURL
+	}
+	return &OllamaProvider{
+		baseURL: baseURL,
+		apiKey:  opts.APIKey,
+		extra:   opts.Headers,
+		client:  opts.HTTPClient,
+	}
+}
+
+func (p *OllamaProvider) Name() string {
+	return ProviderOllama
+}
+
+func (p *OllamaProvider) headers() map[string]string {
+	// Ollama 本身不需要鉴权，但经常被放在带鉴权的反向代理后面
+	headers := map[string]string{}
+	if p.apiKey != "" {
+		headers["Authorization"] = fmt.Sprintf("Bearer %s", p.apiKey)
+	}
+	return mergeHeaders(headers, p.extra)
+}
+
+func (p *OllamaProvider) buildRequest(req *ChatRequest, stream bool) ollamaRequest {
+	return ollamaRequest{
+		Model:    req.Model,
+		Messages: req.Messages,
+		Stream:   stream,
+		Options: ollamaOptions{
+			Temperature: req.Temperature,
+			TopP:        req.TopP,
+			NumPredict:  req.MaxTokens,
+		},
+	}
+}
+
+func (p *OllamaProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	var result ollamaResponse
+	err := decodeJSON(ctx, p.client, http.MethodPost, p.baseURL+"/api/chat", p.headers(), p.buildRequest(req, false), &result)
+	if err != nil {
+		return nil, err
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("ollama error: %s", result.Error)
+	}
+
+	return &ChatResponse{
+		Content:      result.Message.Content,
+		FinishReason: result.DoneReason,
+		Usage:        result.usage(),
+	}, nil
+}
+
+// ChatStream reads the newline delimited JSON stream returned by Ollama.
+func (p *OllamaProvider) ChatStream(ctx context.Context, req *ChatRequest, handler StreamHandler) (*Usage, error) {
+	resp, err := doJSON(ctx, p.client, http.MethodPost, p.baseURL+"/api/chat", p.headers(), p.buildRequest(req, true))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var usage *Usage
+	err = readLines(resp.Body, func(line string) error {
+		var chunk ollamaResponse
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			return nil // Skip malformed chunks
+		}
+		if chunk.Error != "" {
+			return fmt.Errorf("ollama error: %s", chunk.Error)
+		}
+
+		event := StreamEvent{Content: chunk.Message.Content}
+		if chunk.Done {
+			event.FinishReason = chunk.DoneReason
+			usage = chunk.usage()
+		}
+		if err := handler(event); err != nil {
+			return err
+		}
+		if chunk.Done {
+			return errStreamDone
+		}
+		return nil
+	})
+
+	return usage, err
+}
+
+// CountTokens estimates locally, Ollama has no counting endpoint.
+func (p *OllamaProvider) CountTokens(ctx context.Context, req *ChatRequest) (int, error) {
+	return estimateTokens(req), nil
+}
+
+func (p *OllamaProvider) ListModels(ctx context.Context) ([]string, error) {
+	var result struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := decodeJSON(ctx, p.client, http.MethodGet, p.baseURL+"/api/tags", p.headers(), nil, &result); err != nil {
+		return nil, err
+	}
+
+	models := make([]string, 0, len(result.Models))
+	for _, m := range result.Models {
+		models = append(models, m.Name)
+	}
+	return models, nil
+}
+
+func (r ollamaResponse) usage() *Usage {
+	return &Usage{
+		PromptTokens:     r.PromptEvalCount,
+		CompletionTokens: r.EvalCount,
+		TotalTokens:      r.PromptEvalCount + r.EvalCount,
+	}
+}
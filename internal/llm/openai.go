@@ -0,0 +1,177 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const defaultOpenAIBaseURL = "https://api.openai.com"
+
+// OpenAIProvider talks to any OpenAI compatible /v1/chat/completions endpoint
+// (OpenAI, DeepSeek, one-api/new-api gateways ...).
+type OpenAIProvider struct {
+	baseURL string
+	apiKey  string
+	extra   map[string]string
+	client  *http.Client
+}
+
+type openAIRequest struct {
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Temperature float64   `json:"temperature"`
+	TopP        float64   `json:"top_p,omitempty"`
+	Stream      bool      `json:"stream"`
+	// 流式请求时要求在最后一个 chunk 中返回 usage
+	StreamOptions *openAIStreamOptions `json:"stream_options,omitempty"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIResponse struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	Choices []struct {
+		Index   int `json:"index"`
+		Message struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *Usage `json:"usage"`
+}
+
+type openAIStreamChunk struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		Index        int    `json:"index"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *Usage `json:"usage"`
+}
+
+func NewOpenAIProvider(opts Options) *OpenAIProvider {
+	baseURL := opts.BaseURL
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+	return &OpenAIProvider{
+		baseURL: baseURL,
+		apiKey:  opts.APIKey,
+		extra:   opts.Headers,
+		client:  opts.HTTPClient,
+	}
+}
+
+func (p *OpenAIProvider) Name() string {
+	return ProviderOpenAI
+}
+
+func (p *OpenAIProvider) headers() map[string]string {
+	return mergeHeaders(map[string]string{
+		"Authorization": fmt.Sprintf("Bearer %s", p.apiKey),
+	}, p.extra)
+}
+
+func (p *OpenAIProvider) buildRequest(req *ChatRequest, stream bool) openAIRequest {
+	body := openAIRequest{
+		Model:       req.Model,
+		Messages:    req.Messages,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		Stream:      stream,
+	}
+	if stream {
+		body.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	}
+	return body
+}
+
+func (p *OpenAIProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	var result openAIResponse
+	err := decodeJSON(ctx, p.client, http.MethodPost, p.baseURL+"/v1/chat/completions", p.headers(), p.buildRequest(req, false), &result)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Choices) == 0 {
+		return nil, errors.New("no completion choices returned")
+	}
+
+	return &ChatResponse{
+		Content:      result.Choices[0].Message.Content,
+		FinishReason: result.Choices[0].FinishReason,
+		Usage:        result.Usage,
+	}, nil
+}
+
+func (p *OpenAIProvider) ChatStream(ctx context.Context, req *ChatRequest, handler StreamHandler) (*Usage, error) {
+	resp, err := doJSON(ctx, p.client, http.MethodPost, p.baseURL+"/v1/chat/completions", p.headers(), p.buildRequest(req, true))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var usage *Usage
+	err = readSSE(resp.Body, func(event, data string) error {
+		// Check for end of stream
+		if data == "[DONE]" {
+			return errStreamDone
+		}
+
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil // Skip malformed chunks
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
+			return nil
+		}
+
+		return handler(StreamEvent{
+			Content:      chunk.Choices[0].Delta.Content,
+			FinishReason: chunk.Choices[0].FinishReason,
+		})
+	})
+
+	return usage, err
+}
+
+// CountTokens estimates locally, the OpenAI API has no counting endpoint.
+func (p *OpenAIProvider) CountTokens(ctx context.Context, req *ChatRequest) (int, error) {
+	return estimateTokens(req), nil
+}
+
+func (p *OpenAIProvider) ListModels(ctx context.Context) ([]string, error) {
+	var result struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := decodeJSON(ctx, p.client, http.MethodGet, p.baseURL+"/v1/models", p.headers(), nil, &result); err != nil {
+		return nil, err
+	}
+
+	models := make([]string, 0, len(result.Data))
+	for _, m := range result.Data {
+		models = append(models, m.ID)
+	}
+	return models, nil
+}
@@ -0,0 +1,121 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Provider kinds, selected per config.ModelInfo entry.
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+	ProviderOllama    = "ollama"
+)
+
+// Message is a single chat message sent to a provider.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatRequest is the provider independent form of a chat completion request.
+type ChatRequest struct {
+	Model       string
+	Messages    []Message
+	MaxTokens   int
+	Temperature float64
+	TopP        float64
+}
+
+// Usage holds token usage reported by the provider.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Add accumulates other into u, used when one answer spans several requests.
+func (u *Usage) Add(other *Usage) {
+	if other == nil {
+		return
+	}
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+}
+
+// ChatResponse is the result of a non-streaming chat call.
+type ChatResponse struct {
+	Content      string
+	FinishReason string
+	Usage        *Usage
+}
+
+// StreamEvent is one delta of a streaming chat call.
+// Content may be empty, e.g. for the final event carrying FinishReason.
+type StreamEvent struct {
+	Content      string
+	FinishReason string
+}
+
+// StreamHandler receives stream events in order. Returning an error aborts the stream.
+type StreamHandler func(event StreamEvent) error
+
+// Provider is implemented by every LLM backend AIService can talk to.
+type Provider interface {
+	// Name returns the provider kind, e.g. "openai".
+	Name() string
+	// Chat sends a request and waits for the full answer.
+	Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error)
+	// ChatStream sends a request and hands each delta to handler.
+	ChatStream(ctx context.Context, req *ChatRequest, handler StreamHandler) (*Usage, error)
+	// CountTokens returns the number of prompt tokens req would consume.
+	CountTokens(ctx context.Context, req *ChatRequest) (int, error)
+	// ListModels returns the model names served by the endpoint.
+	ListModels(ctx context.Context) ([]string, error)
+}
+
+// Options configures a provider instance.
+type Options struct {
+	BaseURL    string
+	APIKey     string
+	Headers    map[string]string // 附加到每个请求上的额外请求头
+	HTTPClient *http.Client
+}
+
+// NewProvider creates a provider of the given kind. An empty kind means OpenAI compatible.
+func NewProvider(kind string, opts Options) (Provider, error) {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{}
+	}
+	opts.BaseURL = strings.TrimRight(opts.BaseURL, "/")
+
+	switch strings.ToLower(kind) {
+	case "", ProviderOpenAI:
+		return NewOpenAIProvider(opts), nil
+	case ProviderAnthropic:
+		return NewAnthropicProvider(opts), nil
+	case ProviderOllama:
+		return NewOllamaProvider(opts), nil
+	default:
+		return nil, fmt.Errorf("unknown llm provider: %s", kind)
+	}
+}
+
+// estimateTokens is a rough local estimate used when the endpoint has no counting API.
+func estimateTokens(req *ChatRequest) int {
+	total := 0
+	for _, msg := range req.Messages {
+		// 每条消息额外的格式开销按4个token计算
+		total += len(msg.Content)/4 + 4
+	}
+	return total
+}
@@ -0,0 +1,138 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func collectStream(t *testing.T, p Provider) (string, *Usage) {
+	t.Helper()
+	var sb strings.Builder
+	usage, err := p.ChatStream(context.Background(), &ChatRequest{
+		Model:    "test-model",
+		Messages: []Message{{Role: RoleSystem, Content: "sys"}, {Role: RoleUser, Content: "hi"}},
+	}, func(event StreamEvent) error {
+		sb.WriteString(event.Content)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	return sb.String(), usage
+}
+
+func TestProviderChatStream(t *testing.T) {
+	tests := []struct {
+		name      string
+		kind      string
+		path      string
+		body      string
+		wantText  string
+		wantUsage int
+	}{
+		{
+			name: "openai sse",
+			kind: ProviderOpenAI,
+			path: "/v1/chat/completions",
+			body: "data: {\"choices\":[{\"delta\":{\"content\":\"Hello\"}}]}\n\n" +
+				"data: {\"choices\":[{\"delta\":{\"content\":\" world\"}}]}\n\n" +
+				"data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n" +
+				"data: {\"choices\":[],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":2,\"total_tokens\":5}}\n\n" +
+				"data: [DONE]\n\n",
+			wantText:  "Hello world",
+			wantUsage: 5,
+		},
+		{
+			name: "anthropic sse",
+			kind: ProviderAnthropic,
+			path: "/v1/messages",
+			body: "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":4}}}\n\n" +
+				"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"Hi\"}}\n\n" +
+				"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\" there\"}}\n\n" +
+				"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":2}}\n\n" +
+				"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n",
+			wantText:  "Hi there",
+			wantUsage: 6,
+		},
+		{
+			name: "ollama ndjson",
+			kind: ProviderOllama,
+			path: "/api/chat",
+			body: "{\"message\":{\"role\":\"assistant\",\"content\":\"Go\"},\"done\":false}\n" +
+				"{\"message\":{\"role\":\"assistant\",\"content\":\"pher\"},\"done\":false}\n" +
+				"{\"message\":{\"role\":\"assistant\",\"content\":\"\"},\"done\":true,\"prompt_eval_count\":7,\"eval_count\":2}\n",
+			wantText:  "Gopher",
+			wantUsage: 9,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.path {
+					http.Error(w, "unexpected path "+r.URL.Path, http.StatusNotFound)
+					return
+				}
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			p, err := NewProvider(tt.kind, Options{BaseURL: server.URL, APIKey: "key"})
+			if err != nil {
+				t.Fatalf("NewProvider failed: %v", err)
+			}
+
+			text, usage := collectStream(t, p)
+			if text != tt.wantText {
+				t.Errorf("Expected text %q, got %q", tt.wantText, text)
+			}
+			if usage == nil || usage.TotalTokens != tt.wantUsage {
+				t.Errorf("Expected total tokens %d, got %+v", tt.wantUsage, usage)
+			}
+		})
+	}
+}
+
+func TestProviderAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad key", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	p, _ := NewProvider(ProviderOpenAI, Options{BaseURL: server.URL})
+	_, err := p.Chat(context.Background(), &ChatRequest{Model: "m"})
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		t.Fatalf("Expected *APIError, got %T: %v", err, err)
+	}
+	if apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", apiErr.StatusCode)
+	}
+}
+
+func TestAnthropicBuildRequest(t *testing.T) {
+	p := NewAnthropicProvider(Options{})
+	req := p.buildRequest(&ChatRequest{
+		Model: "claude",
+		Messages: []Message{
+			{Role: RoleSystem, Content: "be nice"},
+			{Role: RoleUser, Content: "a"},
+			{Role: RoleUser, Content: "b"},
+			{Role: RoleAssistant, Content: "c"},
+		},
+	}, false)
+
+	if req.System != "be nice" {
+		t.Errorf("Expected system prompt to be moved, got %q", req.System)
+	}
+	if len(req.Messages) != 2 || req.Messages[0].Content != "a\n\nb" {
+		t.Errorf("Expected consecutive user messages to be merged, got %+v", req.Messages)
+	}
+	if req.MaxTokens != defaultAnthropicMaxTokens {
+		t.Errorf("Expected default max tokens, got %d", req.MaxTokens)
+	}
+}
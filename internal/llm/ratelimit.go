@@ -0,0 +1,272 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimitError is returned when a call is rejected by the local limiter or
+// the upstream kept answering 429 after all retries.
+type RateLimitError struct {
+	RetryAfter time.Duration // 建议的重试等待时间
+	Err        error         // 上游返回的错误，本地限流时为 nil
+}
+
+func (e *RateLimitError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("rate limited by upstream, retry after %s: %v", e.RetryAfter, e.Err)
+	}
+	return fmt.Sprintf("rate limit exceeded, retry after %s", e.RetryAfter)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// IsRateLimited reports whether err is a local or upstream rate limit error.
+func IsRateLimited(err error) bool {
+	var rateErr *RateLimitError
+	if errors.As(err, &rateErr) {
+		return true
+	}
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests
+}
+
+// tokenBucket refills continuously at ratePerSec up to capacity. available may
+// go negative, which represents callers already queued for future capacity.
+type tokenBucket struct {
+	capacity   float64
+	available  float64
+	ratePerSec float64
+	last       time.Time
+}
+
+func newTokenBucket(perMinute int, now time.Time) *tokenBucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &tokenBucket{
+		capacity:   float64(perMinute),
+		available:  float64(perMinute),
+		ratePerSec: float64(perMinute) / 60,
+		last:       now,
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.available = math.Min(b.capacity, b.available+elapsed*b.ratePerSec)
+		b.last = now
+	}
+}
+
+// waitFor returns how long a caller must wait until n units are available.
+func (b *tokenBucket) waitFor(n float64) time.Duration {
+	if b.available >= n {
+		return 0
+	}
+	return time.Duration((n - b.available) / b.ratePerSec * float64(time.Second))
+}
+
+// RateLimiter enforces requests per minute and tokens per minute with two token buckets.
+// A nil *RateLimiter allows everything.
+type RateLimiter struct {
+	mu       sync.Mutex
+	requests *tokenBucket
+	tokens   *tokenBucket
+	maxWait  time.Duration
+	now      func() time.Time
+}
+
+// NewRateLimiter returns nil when both limits are disabled. Callers that would have
+// to queue longer than maxWait are rejected with a *RateLimitError.
+func NewRateLimiter(requestsPerMinute, tokensPerMinute int, maxWait time.Duration) *RateLimiter {
+	if requestsPerMinute <= 0 && tokensPerMinute <= 0 {
+		return nil
+	}
+	now := time.Now()
+	return &RateLimiter{
+		requests: newTokenBucket(requestsPerMinute, now),
+		tokens:   newTokenBucket(tokensPerMinute, now),
+		maxWait:  maxWait,
+		now:      time.Now,
+	}
+}
+
+// reserve takes one request and n tokens from the buckets and returns how long the
+// caller has to wait before using them. Nothing is taken if the wait exceeds maxWait.
+func (l *RateLimiter) reserve(n int) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	var wait time.Duration
+	if l.requests != nil {
+		l.requests.refill(now)
+		wait = l.requests.waitFor(1)
+	}
+
+	need := float64(n)
+	if l.tokens != nil {
+		l.tokens.refill(now)
+		// 单个请求超过桶容量时按容量计算，否则永远无法发出
+		need = math.Min(need, l.tokens.capacity)
+		if w := l.tokens.waitFor(need); w > wait {
+			wait = w
+		}
+	}
+
+	if wait > l.maxWait {
+		return 0, &RateLimitError{RetryAfter: wait}
+	}
+
+	if l.requests != nil {
+		l.requests.available--
+	}
+	if l.tokens != nil {
+		l.tokens.available -= need
+	}
+	return wait, nil
+}
+
+// Wait blocks until the call may proceed, the context ends or the wait would exceed maxWait.
+func (l *RateLimiter) Wait(ctx context.Context, tokens int) error {
+	if l == nil {
+		return nil
+	}
+	wait, err := l.reserve(tokens)
+	if err != nil {
+		return err
+	}
+	return sleepContext(ctx, wait)
+}
+
+// Adjust corrects the token bucket once the real usage is known, delta may be negative.
+func (l *RateLimiter) Adjust(delta int) {
+	if l == nil || l.tokens == nil || delta == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens.refill(l.now())
+	l.tokens.available = math.Min(l.tokens.capacity, l.tokens.available-float64(delta))
+}
+
+// RetryPolicy controls how upstream 429 responses are retried.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// backoff returns the delay before retry number attempt (starting at 0) and whether
+// err should be retried at all. Retry-After from the upstream wins over the exponential
+// delay, but a Retry-After longer than MaxDelay is not waited for.
+func (p RetryPolicy) backoff(attempt int, err error) (time.Duration, bool) {
+	var apiErr *APIError
+	if attempt >= p.MaxRetries || !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if apiErr.RetryAfter > 0 {
+		// 提前重试只会再次被拒绝，直接返回错误，调用方可以从 RetryAfter 得知要等多久
+		if p.MaxDelay > 0 && apiErr.RetryAfter > p.MaxDelay {
+			return 0, false
+		}
+		return apiErr.RetryAfter, true
+	}
+
+	delay := p.BaseDelay << attempt
+	if p.MaxDelay > 0 && (delay > p.MaxDelay || delay <= 0) {
+		delay = p.MaxDelay
+	}
+	return delay, true
+}
+
+// rateLimitedProvider runs every chat call through the limiter and retries 429s.
+type rateLimitedProvider struct {
+	Provider
+	limiter *RateLimiter
+	retry   RetryPolicy
+}
+
+// WithRateLimit wraps p so that Chat and ChatStream wait for the limiter and
+// retry upstream 429 responses according to retry.
+func WithRateLimit(p Provider, limiter *RateLimiter, retry RetryPolicy) Provider {
+	return &rateLimitedProvider{
+		Provider: p,
+		limiter:  limiter,
+		retry:    retry,
+	}
+}
+
+func (p *rateLimitedProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	var resp *ChatResponse
+	err := p.do(ctx, req, func() (*Usage, error) {
+		var err error
+		resp, err = p.Provider.Chat(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return resp.Usage, nil
+	})
+	return resp, err
+}
+
+// ChatStream is only retried when the upstream rejects the request before
+// streaming, so the handler never sees duplicated content.
+func (p *rateLimitedProvider) ChatStream(ctx context.Context, req *ChatRequest, handler StreamHandler) (*Usage, error) {
+	var usage *Usage
+	err := p.do(ctx, req, func() (*Usage, error) {
+		var err error
+		usage, err = p.Provider.ChatStream(ctx, req, handler)
+		return usage, err
+	})
+	return usage, err
+}
+
+func (p *rateLimitedProvider) do(ctx context.Context, req *ChatRequest, call func() (*Usage, error)) error {
+	reserved := estimateTokens(req)
+	for attempt := 0; ; attempt++ {
+		if err := p.limiter.Wait(ctx, reserved); err != nil {
+			return err
+		}
+
+		usage, err := call()
+		if usage != nil {
+			p.limiter.Adjust(usage.TotalTokens - reserved)
+		}
+
+		delay, retry := p.retry.backoff(attempt, err)
+		if !retry {
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests {
+				return &RateLimitError{RetryAfter: apiErr.RetryAfter, Err: err}
+			}
+			return err
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
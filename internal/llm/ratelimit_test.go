@@ -0,0 +1,139 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterReject(t *testing.T) {
+	limiter := NewRateLimiter(2, 0, 0)
+
+	for i := 0; i < 2; i++ {
+		if err := limiter.Wait(context.Background(), 1); err != nil {
+			t.Fatalf("Expected request %d to pass, got %v", i, err)
+		}
+	}
+
+	err := limiter.Wait(context.Background(), 1)
+	var rateErr *RateLimitError
+	if !errors.As(err, &rateErr) {
+		t.Fatalf("Expected *RateLimitError, got %v", err)
+	}
+	if rateErr.RetryAfter <= 0 || rateErr.RetryAfter > 30*time.Second {
+		t.Errorf("Expected retry after about 30s, got %s", rateErr.RetryAfter)
+	}
+}
+
+func TestRateLimiterTokens(t *testing.T) {
+	now := time.Now()
+	limiter := NewRateLimiter(0, 600, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	tests := []struct {
+		tokens   int
+		wantWait time.Duration
+	}{
+		{tokens: 500, wantWait: 0},
+		{tokens: 100, wantWait: 0},
+		{tokens: 100, wantWait: 10 * time.Second},  // 600/min = 10 token/s
+		{tokens: 5000, wantWait: 70 * time.Second}, // 超过容量按容量计算，超出 maxWait
+	}
+
+	for i, tt := range tests {
+		wait, err := limiter.reserve(tt.tokens)
+		if tt.wantWait > time.Minute {
+			if err == nil {
+				t.Errorf("case %d: Expected rejection, got wait %s", i, wait)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("case %d: Expected no error, got %v", i, err)
+		}
+		if wait != tt.wantWait {
+			t.Errorf("case %d: Expected wait %s, got %s", i, tt.wantWait, wait)
+		}
+	}
+}
+
+func TestRateLimitedProviderRetry(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`)
+	}))
+	defer server.Close()
+
+	inner, _ := NewProvider(ProviderOpenAI, Options{BaseURL: server.URL})
+
+	tests := []struct {
+		name        string
+		maxRetries  int
+		wantErr     bool
+		wantCalls   int32
+		wantContent string
+	}{
+		{name: "gives up", maxRetries: 1, wantErr: true, wantCalls: 2},
+		{name: "succeeds after retries", maxRetries: 3, wantCalls: 3, wantContent: "ok"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&calls, 0)
+			p := WithRateLimit(inner, nil, RetryPolicy{MaxRetries: tt.maxRetries, BaseDelay: time.Millisecond})
+
+			resp, err := p.Chat(context.Background(), &ChatRequest{Model: "m"})
+			if tt.wantErr {
+				if !IsRateLimited(err) {
+					t.Errorf("Expected rate limit error, got %v", err)
+				}
+			} else if err != nil || resp.Content != tt.wantContent {
+				t.Errorf("Expected content %q, got %+v, %v", tt.wantContent, resp, err)
+			}
+			if got := atomic.LoadInt32(&calls); got != tt.wantCalls {
+				t.Errorf("Expected %d calls, got %d", tt.wantCalls, got)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 10, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	rateLimited := func(retryAfter time.Duration) error {
+		return &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: retryAfter}
+	}
+
+	tests := []struct {
+		name      string
+		attempt   int
+		err       error
+		wantDelay time.Duration
+		wantRetry bool
+	}{
+		{name: "exponential", attempt: 2, err: rateLimited(0), wantDelay: 4 * time.Second, wantRetry: true},
+		{name: "capped exponential", attempt: 5, err: rateLimited(0), wantDelay: 10 * time.Second, wantRetry: true},
+		{name: "retry after", attempt: 0, err: rateLimited(5 * time.Second), wantDelay: 5 * time.Second, wantRetry: true},
+		{name: "retry after too long", attempt: 0, err: rateLimited(time.Hour), wantRetry: false},
+		{name: "out of retries", attempt: 10, err: rateLimited(0), wantRetry: false},
+		{name: "other error", attempt: 0, err: &APIError{StatusCode: http.StatusInternalServerError}, wantRetry: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, retry := policy.backoff(tt.attempt, tt.err)
+			if retry != tt.wantRetry || delay != tt.wantDelay {
+				t.Errorf("Expected (%s, %v), got (%s, %v)", tt.wantDelay, tt.wantRetry, delay, retry)
+			}
+		})
+	}
+}
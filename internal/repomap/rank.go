@@ -0,0 +1,201 @@
+package repomap
+
+import (
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"mind-weaver/internal/treesitter"
+)
+
+const (
+	damping       = 0.85
+	maxIterations = 50
+	tolerance     = 1e-6
+	// 没有被其他文件引用的定义按所在文件排名的一小部分计算，排在被引用的定义之后
+	unreferencedWeight = 1e-3
+)
+
+type rankedDefinition struct {
+	file string
+	tag  treesitter.Tag
+	rank float64
+}
+
+// reference 文件 from 通过标识符 ident 引用了文件 to 中的定义
+type reference struct {
+	from, to int
+	ident    string
+	weight   float64
+}
+
+// rankDefinitions 在文件之间的引用图上计算 PageRank，再把每个文件的排名按引用权重分配给它引用的定义。
+// focus 中的文件作为随机跳转的目标，mentioned 中的标识符权重更高
+func rankDefinitions(files map[string]*treesitter.FileTags, focus, mentioned map[string]bool) []rankedDefinition {
+	names := make([]string, 0, len(files))
+	for rel := range files {
+		names = append(names, rel)
+	}
+	sort.Strings(names)
+
+	// 标识符 -> 定义它的文件
+	defines := map[string][]int{}
+	for i, rel := range names {
+		seen := map[string]bool{}
+		for _, tag := range files[rel].Definitions {
+			if !seen[tag.Name] {
+				seen[tag.Name] = true
+				defines[tag.Name] = append(defines[tag.Name], i)
+			}
+		}
+	}
+
+	// 遍历每个文件引用的标识符，复杂度与引用总数成正比
+	var refs []reference
+	outWeight := make([]float64, len(names))
+	for i, rel := range names {
+		for ident, count := range files[rel].References {
+			definers := defines[ident]
+			if len(definers) == 0 || count == 0 {
+				continue
+			}
+			multiplier := identifierWeight(ident, len(definers), mentioned)
+			for _, to := range definers {
+				// 只连接同一种语言的文件，避免 Go 的 close 链接到 Python 的 close
+				if to == i || languageGroup(rel) != languageGroup(names[to]) {
+					continue
+				}
+				weight := multiplier * math.Sqrt(float64(count))
+				refs = append(refs, reference{from: i, to: to, ident: ident, weight: weight})
+				outWeight[i] += weight
+			}
+		}
+	}
+
+	ranks := pageRank(len(names), refs, outWeight, personalization(names, focus))
+
+	defRanks := map[int]map[string]float64{}
+	for _, ref := range refs {
+		if defRanks[ref.to] == nil {
+			defRanks[ref.to] = map[string]float64{}
+		}
+		defRanks[ref.to][ref.ident] += ranks[ref.from] * ref.weight / outWeight[ref.from]
+	}
+
+	var defs []rankedDefinition
+	for i, rel := range names {
+		for _, tag := range files[rel].Definitions {
+			rank := defRanks[i][tag.Name]
+			if rank == 0 {
+				rank = ranks[i] * unreferencedWeight
+			}
+			defs = append(defs, rankedDefinition{file: rel, tag: tag, rank: rank})
+		}
+	}
+	sort.SliceStable(defs, func(i, j int) bool {
+		if defs[i].rank != defs[j].rank {
+			return defs[i].rank > defs[j].rank
+		}
+		if defs[i].file != defs[j].file {
+			return defs[i].file < defs[j].file
+		}
+		return defs[i].tag.StartLine < defs[j].tag.StartLine
+	})
+	return defs
+}
+
+// identifierWeight 参考 aider 的做法：请求中提到的、较长的驼峰或下划线命名的标识符更重要，
+// 私有的和在很多文件中都有定义的(如 String、New)不重要
+func identifierWeight(ident string, definers int, mentioned map[string]bool) float64 {
+	weight := 1.0
+	if mentioned[ident] {
+		weight *= 10
+	}
+	if len(ident) >= 8 && (strings.Contains(ident, "_") || hasInnerUpper(ident)) {
+		weight *= 10
+	}
+	if strings.HasPrefix(ident, "_") {
+		weight *= 0.1
+	}
+	if definers > 5 {
+		weight *= 0.1
+	}
+	return weight
+}
+
+// languageGroup 返回可以互相引用的一组语言，例如 js 和 ts、c 和 cpp
+func languageGroup(path string) string {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".js", ".jsx", ".ts", ".tsx", ".mjs", ".cjs":
+		return "js"
+	case ".c", ".h", ".cpp", ".hpp", ".cc":
+		return "c"
+	case ".kt", ".kts":
+		return "kotlin"
+	default:
+		return ext
+	}
+}
+
+func hasInnerUpper(ident string) bool {
+	for i, r := range ident {
+		if i > 0 && unicode.IsUpper(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// personalization 有关注的文件时随机跳转只跳到这些文件，否则均匀分布
+func personalization(names []string, focus map[string]bool) []float64 {
+	p := make([]float64, len(names))
+	total := 0.0
+	for i, rel := range names {
+		if focus[rel] {
+			p[i] = 1
+			total++
+		}
+	}
+	if total == 0 {
+		for i := range p {
+			p[i] = 1
+		}
+		total = float64(len(p))
+	}
+	for i := range p {
+		p[i] /= total
+	}
+	return p
+}
+
+func pageRank(n int, refs []reference, outWeight, p []float64) []float64 {
+	ranks := append([]float64(nil), p...)
+	for iter := 0; iter < maxIterations; iter++ {
+		next := make([]float64, n)
+		// 没有引用其他文件的节点把排名按跳转分布分给所有节点
+		dangling := 0.0
+		for i := 0; i < n; i++ {
+			if outWeight[i] == 0 {
+				dangling += ranks[i]
+			}
+		}
+		for i := 0; i < n; i++ {
+			next[i] = (1-damping)*p[i] + damping*dangling*p[i]
+		}
+		for _, ref := range refs {
+			next[ref.to] += damping * ranks[ref.from] * ref.weight / outWeight[ref.from]
+		}
+
+		diff := 0.0
+		for i := range next {
+			diff += math.Abs(next[i] - ranks[i])
+		}
+		ranks = next
+		if diff < tolerance {
+			break
+		}
+	}
+	return ranks
+}
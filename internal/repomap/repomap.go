@@ -0,0 +1,243 @@
+// Package repomap builds a repository map: an outline of the most important
+// definitions of a project, ranked by how often they are referenced from other
+// files, that fits in a token budget. Definitions and references come from the
+// tree-sitter queries in internal/treesitter.
+package repomap
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mind-weaver/internal/third/ignore"
+	"mind-weaver/internal/tokenizer"
+	"mind-weaver/internal/treesitter"
+	"mind-weaver/internal/utils"
+)
+
+const (
+	// 单个文件超过该大小时不解析，通常是生成或打包后的文件
+	maxFileSize = 512 * 1024
+	// 定义所在行超过该长度时截断
+	maxLineLength = 120
+)
+
+// Map caches the tags of every source file of a project. It is safe for
+// concurrent use; call Refresh to pick up changed files.
+type Map struct {
+	root     string
+	maxFiles int
+
+	mu    sync.Mutex
+	files map[string]*fileEntry // 相对项目根目录的路径(使用 /) -> 标签
+	// 请求没有提到文件和标识符时的排名，文件变化后重新计算
+	ranked      []rankedDefinition
+	rankedValid bool
+}
+
+type fileEntry struct {
+	modTime time.Time
+	size    int64
+	tags    *treesitter.FileTags
+}
+
+// Options controls what Render outputs.
+type Options struct {
+	MaxTokens int                 // 地图的token上限
+	Tokenizer tokenizer.Tokenizer // 计算token使用的分词器，为空时使用默认分词器
+	// Request is the user's request. Files and identifiers mentioned in it are
+	// ranked higher.
+	Request string
+}
+
+// New returns a map of the project at root that parses at most maxFiles files.
+func New(root string, maxFiles int) *Map {
+	return &Map{root: root, maxFiles: maxFiles, files: map[string]*fileEntry{}}
+}
+
+// Refresh parses files that changed since the last refresh and drops files that
+// were removed or are ignored by the project's .rooignore.
+func (m *Map) Refresh() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rooIgnore := ignore.NewRooIgnoreController(m.root)
+	if err := rooIgnore.Initialize(); err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	err := filepath.WalkDir(m.root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if path == m.root {
+				return err
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if path != m.root && (utils.IsSkippedProjectDir(d.Name()) || !rooIgnore.ValidateAccess(path)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if m.maxFiles > 0 && len(seen) >= m.maxFiles {
+			return filepath.SkipAll
+		}
+		if !treesitter.SupportsTags(path) || strings.Contains(d.Name(), ".min.") || !rooIgnore.ValidateAccess(path) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > maxFileSize {
+			return nil
+		}
+		rel, err := filepath.Rel(m.root, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		seen[rel] = true
+		if old, ok := m.files[rel]; ok && old.modTime.Equal(info.ModTime()) && old.size == info.Size() {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		var tags *treesitter.FileTags
+		if !utils.IsMinified(content) {
+			tags, err = treesitter.ParseTags(path, content)
+		}
+		if err != nil || tags == nil {
+			// 解析失败和压缩后的文件也记录下来，没有修改时不再重复读取
+			tags = &treesitter.FileTags{}
+		}
+		m.files[rel] = &fileEntry{modTime: info.ModTime(), size: info.Size(), tags: tags}
+		m.rankedValid = false
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for rel := range m.files {
+		if !seen[rel] {
+			delete(m.files, rel)
+			m.rankedValid = false
+		}
+	}
+	return nil
+}
+
+var identifierPattern = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+// Render returns the outline of the highest ranked definitions that fits in
+// opts.MaxTokens, or an empty string when nothing fits.
+func (m *Map) Render(opts Options) string {
+	tk := opts.Tokenizer
+	if tk == nil {
+		tk = tokenizer.Default()
+	}
+	defs := m.rank(opts.Request)
+	if len(defs) == 0 || opts.MaxTokens <= 0 {
+		return ""
+	}
+
+	// 二分查找能放进token上限的最多定义数
+	best := ""
+	low, high := 1, len(defs)
+	for low <= high {
+		mid := (low + high) / 2
+		output := render(defs[:mid])
+		if tk.Count(output) <= opts.MaxTokens {
+			best = output
+			low = mid + 1
+		} else {
+			high = mid - 1
+		}
+	}
+	return best
+}
+
+// rank 返回排好序的定义。请求没有提到文件和标识符时排名只取决于文件，
+// 使用上次计算的结果，直到 Refresh 发现文件变化
+func (m *Map) rank(request string) []rankedDefinition {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	files := make(map[string]*treesitter.FileTags, len(m.files))
+	for rel, entry := range m.files {
+		files[rel] = entry.tags
+	}
+
+	focus, mentioned := mentions(files, request)
+	if len(focus) > 0 || len(mentioned) > 0 {
+		return rankDefinitions(files, focus, mentioned)
+	}
+	if !m.rankedValid {
+		m.ranked = rankDefinitions(files, focus, mentioned)
+		m.rankedValid = true
+	}
+	return m.ranked
+}
+
+// mentions 找出请求中提到的项目文件(完整路径或文件名)和项目中定义的标识符
+func mentions(files map[string]*treesitter.FileTags, request string) (map[string]bool, map[string]bool) {
+	focus := map[string]bool{}
+	mentioned := map[string]bool{}
+	if request == "" {
+		return focus, mentioned
+	}
+	for rel := range files {
+		if strings.Contains(request, rel) || strings.Contains(request, filepath.Base(rel)) {
+			focus[rel] = true
+		}
+	}
+	words := map[string]bool{}
+	for _, word := range identifierPattern.FindAllString(request, -1) {
+		words[word] = true
+	}
+	// 只保留有定义的标识符，其他词不影响排名
+	for _, tags := range files {
+		for _, tag := range tags.Definitions {
+			if words[tag.Name] {
+				mentioned[tag.Name] = true
+			}
+		}
+	}
+	return focus, mentioned
+}
+
+// render 按文件分组输出定义，排名高的定义所在的文件排在前面，文件内按行号排序
+func render(defs []rankedDefinition) string {
+	var order []string
+	byFile := map[string][]treesitter.Tag{}
+	for _, def := range defs {
+		if _, ok := byFile[def.file]; !ok {
+			order = append(order, def.file)
+		}
+		byFile[def.file] = append(byFile[def.file], def.tag)
+	}
+
+	var b strings.Builder
+	for _, file := range order {
+		tags := byFile[file]
+		sort.Slice(tags, func(i, j int) bool { return tags[i].StartLine < tags[j].StartLine })
+		fmt.Fprintf(&b, "# %s\n", file)
+		for i, tag := range tags {
+			// 同一行的多个定义只输出一次
+			if i > 0 && tag.StartLine == tags[i-1].StartLine {
+				continue
+			}
+			line := tag.Line
+			if len(line) > maxLineLength {
+				line = strings.ToValidUTF8(line[:maxLineLength], "") + "..."
+			}
+			fmt.Fprintf(&b, "%d--%d | %s\n", tag.StartLine+1, tag.EndLine+1, line)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
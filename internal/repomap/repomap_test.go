@@ -0,0 +1,113 @@
+package repomap
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mind-weaver/internal/tokenizer"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+const storeSource = `package store
+
+// OrderRepository 订单存储
+type OrderRepository struct {
+	db string
+}
+
+func (r *OrderRepository) FindOrder(id int) string {
+	return r.db
+}
+`
+
+func handlerSource(name string) string {
+	return `package api
+
+func Handle` + name + `(repo *store.OrderRepository) string {
+	return repo.FindOrder(1)
+}
+`
+}
+
+func TestRender(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"store/order.go": storeSource,
+		"api/a.go":       handlerSource("A"),
+		"api/b.go":       handlerSource("B"),
+		"api/c.go":       handlerSource("C"),
+		"billing/refund.py": `class RefundRetryPolicy:
+    def next_delay(self, attempt):
+        return attempt * 2
+
+
+def unused_helper():
+    pass
+`,
+		"node_modules/lib/index.js": "function Ignored() {\n  return 1\n}\n",
+		".rooignore":                "internal/\n",
+		"internal/secret.go":        "package internal\n\nfunc RotateSecretKeys() {\n\tpanic(1)\n}\n",
+	})
+	m := New(root, 0)
+	if err := m.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	tk := tokenizer.Default()
+	output := m.Render(Options{MaxTokens: 1000, Tokenizer: tk})
+	// 被多个文件引用的定义排在最前面
+	if !strings.HasPrefix(output, "# store/order.go\n4--6 | type OrderRepository struct {\n8--10 | func (r *OrderRepository) FindOrder(id int) string {\n") {
+		t.Errorf("Expected the most referenced file first, got:\n%s", output)
+	}
+	for _, want := range []string{"# api/a.go", "3--5 | func HandleA(repo *store.OrderRepository) string {", "# billing/refund.py", "1--3 | class RefundRetryPolicy:"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "node_modules") {
+		t.Errorf("Expected dependencies to be skipped, got:\n%s", output)
+	}
+	if strings.Contains(output, "RotateSecretKeys") || strings.Contains(output, "internal/secret.go") {
+		t.Errorf("Expected files ignored by .rooignore to be skipped, got:\n%s", output)
+	}
+
+	// token上限很小时只保留排名最高的定义
+	small := m.Render(Options{MaxTokens: 25, Tokenizer: tk})
+	if small == "" || tk.Count(small) > 25 || !strings.HasPrefix(small, "# store/order.go") {
+		t.Errorf("Expected the top definitions within 25 tokens, got (%d tokens):\n%s", tk.Count(small), small)
+	}
+
+	// 请求中提到的文件排名更高
+	focused := m.Render(Options{MaxTokens: 1000, Tokenizer: tk, Request: "where do we handle refund retries? see billing/refund.py"})
+	if !strings.HasPrefix(focused, "# billing/refund.py") {
+		t.Errorf("Expected the mentioned file first, got:\n%s", focused)
+	}
+
+	// 修改和删除的文件在刷新后生效
+	writeFiles(t, root, map[string]string{"billing/refund.py": "def refund_order(order):\n    pass\n    pass\n    return order\n"})
+	later := time.Now().Add(time.Second)
+	os.Chtimes(filepath.Join(root, "billing/refund.py"), later, later)
+	os.Remove(filepath.Join(root, "api/c.go"))
+	if err := m.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	output = m.Render(Options{MaxTokens: 1000, Tokenizer: tk})
+	if strings.Contains(output, "RefundRetryPolicy") || strings.Contains(output, "api/c.go") || !strings.Contains(output, "def refund_order(order):") {
+		t.Errorf("Expected refreshed definitions, got:\n%s", output)
+	}
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"mind-weaver/internal/llm"
+	"mind-weaver/internal/third/assistantmessage"
+	"mind-weaver/internal/third/tools"
+)
+
+// 自动循环结束的原因
+const (
+	AgentStopCompletion    = "attempt_completion"    // 模型认为任务已完成
+	AgentStopFollowup      = "ask_followup_question" // 模型需要用户回答问题
+	AgentStopStepBudget    = "step_budget"           // 达到步数上限
+	AgentStopNeedsApproval = "needs_approval"        // 下一个工具需要用户确认
+	AgentStopNoTool        = "no_tool"               // 回复中没有完整的工具调用
+	AgentStopError         = "error"                 // 模型请求失败
+	AgentStopStopped       = "stopped"               // 用户停止或客户端断开
+)
+
+// AgentStep is one tool executed by the agent loop.
+type AgentStep struct {
+	Step    int                      `json:"step"`
+	Tool    assistantmessage.ToolUse `json:"tool"`
+	Result  string                   `json:"result"`
+	IsError bool                     `json:"is_error"`
+}
+
+// AgentResult describes why and where an agent run ended.
+type AgentResult struct {
+	StopReason string      `json:"stop_reason"`
+	Steps      []AgentStep `json:"steps"`
+	// Tool 是结束循环的工具调用：attempt_completion、ask_followup_question 或等待确认的工具
+	Tool *assistantmessage.ToolUse `json:"tool,omitempty"`
+	// MessageID 是最后一条模型回复的消息ID
+	MessageID int64     `json:"message_id"`
+	Content   string    `json:"-"`
+	Usage     llm.Usage `json:"usage"`
+}
+
+// FindToolUse returns the first complete tool call in an assistant reply, or nil.
+func FindToolUse(content string) *assistantmessage.ToolUse {
+	for _, block := range assistantmessage.ParseAssistantMessage(content) {
+		if toolUse, ok := block.(*assistantmessage.ToolUse); ok && !toolUse.Partial {
+			return toolUse
+		}
+	}
+	return nil
+}
+
+// NextAgentAction 根据模型回复决定自动循环的下一步，返回要执行的工具；
+// stopReason 不为空时循环结束，此时返回的工具(如果有)就是结束循环的工具调用
+func NextAgentAction(content string, steps, maxSteps int, approved func(toolUse *assistantmessage.ToolUse) bool) (*assistantmessage.ToolUse, string) {
+	toolUse := FindToolUse(content)
+	switch {
+	case toolUse == nil:
+		return nil, AgentStopNoTool
+	case toolUse.Name == assistantmessage.AttemptCompletion:
+		return toolUse, AgentStopCompletion
+	case toolUse.Name == assistantmessage.AskFollowupQuestion:
+		return toolUse, AgentStopFollowup
+	case steps >= maxSteps:
+		return toolUse, AgentStopStepBudget
+	case !approved(toolUse):
+		return toolUse, AgentStopNeedsApproval
+	}
+	return toolUse, ""
+}
+
+// 工具结果消息的结尾，压缩历史时据此识别工具结果
+const toolResultSuffix = "。一次回复只能使用一个工具"
+
+// ToolResultMessage 把工具执行结果组装成回传给模型的用户消息，执行失败时同样带上结尾，压缩历史时也能缩短
+func ToolResultMessage(res *tools.ExecutorResult, err error) string {
+	if err != nil {
+		return err.Error() + toolResultSuffix
+	}
+	return res.Result + toolResultSuffix
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"mind-weaver/internal/third/assistantmessage"
+	"mind-weaver/internal/third/tools"
+)
+
+func TestNextAgentAction(t *testing.T) {
+	readOnly := func(toolUse *assistantmessage.ToolUse) bool { return toolUse.Name == assistantmessage.ReadFile }
+
+	tests := []struct {
+		name       string
+		content    string
+		steps      int
+		wantTool   string
+		wantReason string
+	}{
+		{
+			name:       "plain text",
+			content:    "任务已经完成了",
+			wantReason: AgentStopNoTool,
+		},
+		{
+			name:       "partial tool use",
+			content:    "<read_file>\n<path>main.go</path>\n",
+			wantReason: AgentStopNoTool,
+		},
+		{
+			name:     "approved tool",
+			content:  "先看一下文件\n<read_file>\n<path>main.go</path>\n</read_file>",
+			wantTool: "read_file",
+		},
+		{
+			name:       "tool needs approval",
+			content:    "<execute_command>\n<command>go test ./...</command>\n</execute_command>",
+			wantTool:   "execute_command",
+			wantReason: AgentStopNeedsApproval,
+		},
+		{
+			name:       "step budget",
+			content:    "<read_file>\n<path>main.go</path>\n</read_file>",
+			steps:      3,
+			wantTool:   "read_file",
+			wantReason: AgentStopStepBudget,
+		},
+		{
+			name:       "attempt completion ignores budget",
+			content:    "<attempt_completion>\n<result>done</result>\n</attempt_completion>",
+			steps:      3,
+			wantTool:   "attempt_completion",
+			wantReason: AgentStopCompletion,
+		},
+		{
+			name:       "followup question",
+			content:    "<ask_followup_question>\n<question>用哪个数据库？</question>\n</ask_followup_question>",
+			wantTool:   "ask_followup_question",
+			wantReason: AgentStopFollowup,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toolUse, reason := NextAgentAction(tt.content, tt.steps, 3, readOnly)
+			if reason != tt.wantReason {
+				t.Errorf("Expected stop reason %q, got %q", tt.wantReason, reason)
+			}
+			gotTool := ""
+			if toolUse != nil {
+				gotTool = string(toolUse.Name)
+			}
+			if gotTool != tt.wantTool {
+				t.Errorf("Expected tool %q, got %q", tt.wantTool, gotTool)
+			}
+		})
+	}
+}
+
+func TestToolResultMessage(t *testing.T) {
+	// 成功和失败的工具结果都要能被压缩历史时识别
+	for _, msg := range []string{
+		ToolResultMessage(&tools.ExecutorResult{Result: "file content"}, nil),
+		ToolResultMessage(nil, errors.New("stating path: permission denied")),
+	} {
+		if !strings.HasSuffix(msg, toolResultSuffix) {
+			t.Errorf("Expected %q to end with the tool result suffix", msg)
+		}
+	}
+}
@@ -1,24 +1,22 @@
 package services
 
 import (
-	"bufio"
-	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"mind-weaver/config"
 	"mind-weaver/internal/db"
+	"mind-weaver/internal/llm"
 	"mind-weaver/internal/third/assistantmessage"
 	"mind-weaver/internal/third/tools"
+	"mind-weaver/internal/utils"
 	"mind-weaver/pkg/logger"
-
-	"github.com/gin-gonic/gin"
 )
 
 const (
@@ -31,11 +29,18 @@ const (
 )
 
 type AIService struct {
-	apiKey    string
-	model     string
-	maxTokens int
-	cfg       config.Config
-	database  *db.Database
+	apiKey     string
+	model      string
+	maxTokens  int
+	cfg        config.Config
+	database   *db.Database
+	httpClient *http.Client
+
+	// 按模型名称缓存的实例，每个模型可以有独立的地址、密钥和请求头
+	providers  map[string]llm.Provider
+	providerMu sync.Mutex
+	// 所有模型共享的限流器，多人共用一个API key时避免被上游限流
+	limiter *llm.RateLimiter
 }
 
 type Message struct {
@@ -44,118 +49,109 @@ type Message struct {
 	Content string `json:"content"`
 }
 
-type CompletionRequest struct {
-	Model       string    `json:"model"`
-	Messages    []Message `json:"messages"`
-	MaxTokens   int       `json:"max_tokens,omitempty"`
-	Temperature float64   `json:"temperature"`
-	Stream      bool      `json:"stream"`
-}
-
-type CompletionResponse struct {
-	ID      string `json:"id"`
-	Object  string `json:"object"`
-	Created int64  `json:"created"`
-	Choices []struct {
-		Index   int `json:"index"`
-		Message struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
-		} `json:"message"`
-		FinishReason string `json:"finish_reason"`
-	} `json:"choices"`
-}
-
-type StreamChunk struct {
-	ID      string `json:"id"`
-	Object  string `json:"object"`
-	Created int64  `json:"created"`
-	Model   string `json:"model"`
-	Choices []struct {
-		Delta struct {
-			Content string `json:"content"`
-		} `json:"delta"`
-		Index        int    `json:"index"`
-		FinishReason string `json:"finish_reason"`
-	} `json:"choices"`
-}
+func NewAIService(database *db.Database, cfg *config.Config) (*AIService, error) {
+	// 所有模型共用一个 http client，统一处理代理、超时和TLS设置
+	httpClient, err := newLLMHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-func NewAIService(database *db.Database, cfg *config.Config) *AIService {
 	return &AIService{
-		database:  database,
-		apiKey:    cfg.LLM.APIKey,
-		model:     cfg.LLM.Model,
-		maxTokens: cfg.LLM.MaxTokens,
-		cfg:       *cfg,
-	}
+		database:   database,
+		apiKey:     cfg.LLM.APIKey,
+		model:      cfg.LLM.Model,
+		maxTokens:  cfg.LLM.MaxTokens,
+		cfg:        *cfg,
+		httpClient: httpClient,
+		providers:  make(map[string]llm.Provider),
+		limiter: llm.NewRateLimiter(
+			cfg.LLM.RateLimit.RequestsPerMinute,
+			cfg.LLM.RateLimit.TokensPerMinute,
+			cfg.LLM.RateLimit.GetMaxWait(),
+		),
+	}, nil
 }
 
-func (s *AIService) GenerateCompletion(prompt string, contextFiles []*FileContext) (string, error) {
-	// Prepare system message with context
-	systemMsg := "You are MindWeaver AI, an intelligent coding assistant. "
-	systemMsg += "Help the user with their coding tasks based on the following context:\n\n"
-
-	// Add file contexts
-	for _, file := range contextFiles {
-		systemMsg += fmt.Sprintf("File: %s (%s)\n```%s\n%s\n```\n\n",
-			file.FilePath, file.Language, file.Language, file.Content)
+// newLLMHTTPClient 按 llm 下的代理、超时和TLS设置创建请求模型接口的 http client
+func newLLMHTTPClient(cfg *config.Config) (*http.Client, error) {
+	connectTimeout := cfg.LLM.ConnectTimeout
+	if connectTimeout <= 0 {
+		connectTimeout = 10
+	}
+	httpClient, err := llm.NewHTTPClient(llm.TransportOptions{
+		Proxy:              cfg.LLM.Proxy,
+		Timeout:            time.Duration(cfg.LLM.Timeout) * time.Second,
+		ConnectTimeout:     time.Duration(connectTimeout) * time.Second,
+		ReadTimeout:        time.Duration(cfg.LLM.ReadTimeout) * time.Second,
+		InsecureSkipVerify: cfg.LLM.TLS.InsecureSkipVerify,
+		CACertFile:         cfg.LLM.TLS.CACert,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create llm http client: %w", err)
 	}
+	return httpClient, nil
+}
 
-	systemMsg += "Provide concise, working code solutions. Explain your approach briefly if needed."
-
-	// Prepare request
-	messages := []Message{
-		{Role: MsgTypeSystem, Content: systemMsg},
-		{Role: MsgTypeUser, Content: prompt},
+// getModelInfo returns the configured entry for modelName, falling back to the defaults in LLMConfig.
+func (s *AIService) getModelInfo(modelName string) config.ModelInfo {
+	if modelName == "" {
+		modelName = s.model
 	}
-
-	reqBody := CompletionRequest{
-		Model:       s.model,
-		Messages:    messages,
-		Temperature: 0.3,
-		MaxTokens:   s.maxTokens,
-		Stream:      false,
+	for _, model := range s.cfg.LLM.Models {
+		if model.Name == modelName {
+			return model
+		}
 	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", err
+	return config.ModelInfo{
+		Name:       modelName,
+		MaxContext: s.cfg.LLM.MaxContext,
+		MaxTokens:  s.maxTokens,
 	}
+}
 
-	// Make request to OpenAI
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", err
-	}
+// getProvider returns the provider serving modelName, built from the model's own
+// base_url/api_key/headers with the global llm settings as fallback.
+func (s *AIService) getProvider(modelName string) (llm.Provider, error) {
+	modelInfo := s.getModelInfo(modelName)
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.apiKey))
+	s.providerMu.Lock()
+	defer s.providerMu.Unlock()
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
+	if provider, ok := s.providers[modelInfo.Name]; ok {
+		return provider, nil
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(bodyBytes))
-	}
+	provider, err := llm.NewProvider(modelInfo.Provider, llm.Options{
+		BaseURL:    modelInfo.GetBaseURL(s.cfg.LLM.BaseURL),
+		APIKey:     modelInfo.GetAPIKey(s.apiKey),
+		Headers:    modelInfo.Headers,
+		HTTPClient: s.httpClient,
+	})
+	if err != nil {
+		return nil, err
+	}
+	provider = llm.WithRateLimit(provider, s.limiter, llm.RetryPolicy{
+		MaxRetries: s.cfg.LLM.RateLimit.GetMaxRetries(),
+		BaseDelay:  time.Second,
+		MaxDelay:   30 * time.Second,
+	})
+	s.providers[modelInfo.Name] = provider
+	return provider, nil
+}
 
-	var result CompletionResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+// writeAndFlush writes p to writer and flushes it if the writer supports it (like http.ResponseWriter).
+func writeAndFlush(writer io.Writer, p []byte) error {
+	if _, err := writer.Write(p); err != nil {
+		return err
 	}
-
-	if len(result.Choices) == 0 {
-		return "", errors.New("no completion choices returned")
+	if flusher, ok := writer.(http.Flusher); ok {
+		flusher.Flush()
 	}
-
-	return result.Choices[0].Message.Content, nil
+	return nil
 }
 
-func (s *AIService) GenerateCompletionStream(prompt string, contextFiles []*FileContext, writer io.Writer) error {
+// buildCompletionMessages prepares the messages used by GenerateCompletion and GenerateCompletionStream.
+func (s *AIService) buildCompletionMessages(prompt string, contextFiles []*FileContext) []*Message {
 	// Prepare system message with context
 	systemMsg := "You are MindWeaver AI, an intelligent coding assistant. "
 	systemMsg += "Help the user with their coding tasks based on the following context:\n\n"
@@ -168,95 +164,45 @@ func (s *AIService) GenerateCompletionStream(prompt string, contextFiles []*File
 
 	systemMsg += "Provide concise, working code solutions. Explain your approach briefly if needed."
 
-	// Prepare request
-	messages := []Message{
+	return []*Message{
 		{Role: MsgTypeSystem, Content: systemMsg},
 		{Role: MsgTypeUser, Content: prompt},
 	}
+}
 
-	reqBody := CompletionRequest{
-		Model:       s.model,
-		Messages:    messages,
-		Temperature: 0.3,
-		MaxTokens:   s.maxTokens,
-		Stream:      true,
-	}
+func (s *AIService) GenerateCompletion(ctx context.Context, prompt string, contextFiles []*FileContext) (string, *llm.Usage, error) {
+	req := s.buildChatRequest("", "", s.buildCompletionMessages(prompt, contextFiles), s.model)
+	req.Temperature = 0.3
 
-	jsonData, err := json.Marshal(reqBody)
+	provider, err := s.getProvider(req.Model)
 	if err != nil {
-		return err
+		return "", nil, err
 	}
 
-	// Make request to OpenAI
-	req, err := http.NewRequest("POST", "http://192.168.0.113:8020/v1/chat/completions", bytes.NewBuffer(jsonData))
+	resp, err := provider.Chat(ctx, req)
 	if err != nil {
-		return err
+		return "", nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.apiKey))
+	return resp.Content, resp.Usage, nil
+}
+
+func (s *AIService) GenerateCompletionStream(ctx context.Context, prompt string, contextFiles []*FileContext, writer io.Writer) error {
+	req := s.buildChatRequest("", "", s.buildCompletionMessages(prompt, contextFiles), s.model)
+	req.Temperature = 0.3
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	provider, err := s.getProvider(req.Model)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(bodyBytes))
-	}
 
-	reader := bufio.NewReader(resp.Body)
-	for {
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return err
-		}
-
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
+	_, err = provider.ChatStream(ctx, req, func(event llm.StreamEvent) error {
+		if event.Content == "" {
+			return nil
 		}
-
-		// SSE format: each message starts with "data: "
-		if !strings.HasPrefix(line, "data: ") {
-			continue
-		}
-
-		// Remove the "data: " prefix
-		line = strings.TrimPrefix(line, "data: ")
-
-		// Check for end of stream
-		if line == "[DONE]" {
-			break
-		}
-
-		var chunk StreamChunk
-		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
-			continue // Skip malformed chunks
-		}
-
-		if len(chunk.Choices) > 0 {
-			content := chunk.Choices[0].Delta.Content
-			if content != "" {
-				// Write the content to the response writer
-				if _, err := writer.Write([]byte(content)); err != nil {
-					return err
-				}
-				// Flush the writer if it's a flusher (like http.ResponseWriter)
-				if flusher, ok := writer.(http.Flusher); ok {
-					flusher.Flush()
-				}
-			}
-		}
-	}
-
-	return nil
+		return writeAndFlush(writer, []byte(event.Content))
+	})
+	return err
 }
 
 // Optimize the prompt for better code generation
@@ -273,8 +219,8 @@ func (s *AIService) OptimizePrompt(userPrompt string, language string) string {
 	return optimizedPrompt
 }
 
-// buildChatRequest creates a CompletionRequest with the provided parameters
-func (s *AIService) buildChatRequest(sysPrompt string, prompt string, historyMsgs []*Message, modelName string, stream bool) CompletionRequest {
+// buildChatRequest creates a provider request with the provided parameters
+func (s *AIService) buildChatRequest(sysPrompt string, prompt string, historyMsgs []*Message, modelName string) *llm.ChatRequest {
 	// Use specified model or fall back to default
 	model := s.model
 	if modelName != "" {
@@ -282,336 +228,145 @@ func (s *AIService) buildChatRequest(sysPrompt string, prompt string, historyMsg
 	}
 
 	// Start with system message if provided
-	messages := []Message{}
+	messages := []llm.Message{}
 	if sysPrompt != "" {
-		messages = append(messages, Message{Role: MsgTypeSystem, Content: sysPrompt})
+		messages = append(messages, llm.Message{Role: MsgTypeSystem, Content: sysPrompt})
 	}
 
 	// Add history messages
 	if len(historyMsgs) > 0 {
 		for _, msg := range historyMsgs {
-			messages = append(messages, Message{Role: msg.Role, Content: msg.Content})
+			messages = append(messages, llm.Message{Role: msg.Role, Content: msg.Content})
 		}
 	}
 
 	// Add current user prompt
 	if prompt != "" {
-		messages = append(messages, Message{Role: MsgTypeUser, Content: prompt})
+		messages = append(messages, llm.Message{Role: MsgTypeUser, Content: prompt})
 	}
 
-	// Get config values for temperature and max tokens
-	temperature := s.cfg.LLM.Temperature
-	maxTokens := s.maxTokens
-	if maxTokens == 0 {
-		maxTokens = s.cfg.LLM.MaxTokens
-	}
-	// 如果不是默认模型，则使用配置文件中的参数
-	if modelName != s.cfg.LLM.Model {
-		for _, modelInfo := range s.cfg.LLM.Models {
-			if modelInfo.Name == modelName {
-				maxTokens = modelInfo.MaxTokens
-				break
-			}
-		}
+	// 模型自身配置优先，未配置时使用全局配置
+	modelInfo := s.getModelInfo(model)
+	maxTokens := modelInfo.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = s.maxTokens
 	}
 
-	return CompletionRequest{
+	return &llm.ChatRequest{
 		Model:       model,
 		Messages:    messages,
-		Temperature: temperature,
+		Temperature: modelInfo.GetTemperature(s.cfg.LLM.Temperature),
+		TopP:        modelInfo.GetTopP(),
 		MaxTokens:   maxTokens,
-		Stream:      stream,
 	}
 }
 
 // Chat sends a chat request with custom parameters and returns a non-streaming response
-func (s *AIService) Chat(sysPrompt string, prompt string, historyMsgs []*Message, modelName string) (string, error) {
-	reqBody := s.buildChatRequest(sysPrompt, prompt, historyMsgs, modelName, false)
+// together with the token usage reported by the provider (nil if not reported).
+func (s *AIService) Chat(ctx context.Context, sysPrompt string, prompt string, historyMsgs []*Message, modelName string) (string, *llm.Usage, error) {
+	req := s.buildChatRequest(sysPrompt, prompt, historyMsgs, modelName)
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		logger.Errorf("Chatson.Marshal(reqBody) error: %v", reqBody)
-		return "", err
-	}
+	jsonData, _ := json.Marshal(req)
 	logger.Infof("Chat  request data: %v", string(jsonData))
 
-	// Use base URL from config if available
-	baseURL := "https://api.openai.com/v1/chat/completions"
-	if s.cfg.LLM.BaseURL != "" {
-		baseURL = s.cfg.LLM.BaseURL + "/v1/chat/completions"
-	}
-
-	req, err := http.NewRequest("POST", baseURL, bytes.NewBuffer(jsonData))
+	provider, err := s.getProvider(req.Model)
 	if err != nil {
-		logger.Errorf("Chat http.NewRequest error: %v", err.Error())
-		return "", err
+		logger.Errorf("Chat getProvider error: %v", err.Error())
+		return "", nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.apiKey))
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := provider.Chat(ctx, req)
 	if err != nil {
 		logger.Errorf("Chat do request error: %v", err.Error())
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		logger.Errorf("ChatStreamByLine resp.StatusCode is not: %v, but is %v", 200, resp.StatusCode)
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	bodyBytes, _ := io.ReadAll(resp.Body)
-	logger.Infof("Chat response: %v", string(bodyBytes))
-
-	var result CompletionResponse
-	if err := json.Unmarshal([]byte(bodyBytes), &result); err != nil {
-		logger.Errorf("ChatStreamByLine json.NewDecoder error: %v,  response body: %v", err.Error(), resp.Body)
-		return "", err
-	}
-
-	if len(result.Choices) == 0 {
-		logger.Errorf("ChatStreamByLine len(result.Choices) == 0")
-		return "", errors.New("no completion choices returned")
+		return "", nil, err
 	}
+	logger.Infof("Chat response: %v", resp.Content)
 
-	return result.Choices[0].Message.Content, nil
+	return resp.Content, resp.Usage, nil
 }
 
 // ChatStream sends a chat request with custom parameters and streams the response
-func (s *AIService) ChatStream(sysPrompt string, prompt string, historyMsgs []*Message, modelName string, writer io.Writer) error {
-	reqBody := s.buildChatRequest(sysPrompt, prompt, historyMsgs, modelName, true)
+func (s *AIService) ChatStream(ctx context.Context, sysPrompt string, prompt string, historyMsgs []*Message, modelName string, writer io.Writer) (*llm.Usage, error) {
+	req := s.buildChatRequest(sysPrompt, prompt, historyMsgs, modelName)
 
-	jsonData, err := json.Marshal(reqBody)
+	provider, err := s.getProvider(req.Model)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Use base URL from config if available
-	baseURL := "https://api.openai.com/v1/chat/completions"
-	if s.cfg.LLM.BaseURL != "" {
-		baseURL = s.cfg.LLM.BaseURL + "/v1/chat/completions"
-	}
-
-	req, err := http.NewRequest("POST", baseURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.apiKey))
-
-	client := &http.Client{
-		Timeout: time.Duration(s.cfg.LLM.Timeout) * time.Second,
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	reader := bufio.NewReader(resp.Body)
-	for {
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return err
-		}
-
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		// SSE format: each message starts with "data: "
-		if !strings.HasPrefix(line, "data: ") {
-			continue
-		}
-
-		// Remove the "data: " prefix
-		line = strings.TrimPrefix(line, "data: ")
-
-		// Check for end of stream
-		if line == "[DONE]" {
-			break
-		}
-
-		var chunk StreamChunk
-		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
-			continue // Skip malformed chunks
-		}
-
-		if len(chunk.Choices) > 0 {
-			content := chunk.Choices[0].Delta.Content
-			if content != "" {
-				// Write the content to the response writer
-				if _, err := writer.Write([]byte(content)); err != nil {
-					return err
-				}
-				// Flush the writer if it's a flusher (like http.ResponseWriter)
-				if flusher, ok := writer.(http.Flusher); ok {
-					flusher.Flush()
-				}
-			}
+	return provider.ChatStream(ctx, req, func(event llm.StreamEvent) error {
+		if event.Content == "" {
+			return nil
 		}
-	}
-
-	return nil
+		// Write the content to the response writer
+		return writeAndFlush(writer, []byte(event.Content))
+	})
 }
 
 // ChatStreamByLine sends a chat request and streams the response line by line
-func (s *AIService) ChatStreamByLine(sysPrompt string, prompt string, historyMsgs []*Message, modelName string, writer io.Writer, mode string, isContinue bool) error {
-	reqBody := s.buildChatRequest(sysPrompt, prompt, historyMsgs, modelName, true)
+func (s *AIService) ChatStreamByLine(ctx context.Context, sysPrompt string, prompt string, historyMsgs []*Message, modelName string, writer io.Writer, mode string, isContinue bool) (*llm.Usage, error) {
+	req := s.buildChatRequest(sysPrompt, prompt, historyMsgs, modelName)
 
-	jsonData, err := json.Marshal(reqBody)
+	provider, err := s.getProvider(req.Model)
 	if err != nil {
-		return err
+		logger.Errorf("ChatStreamByLine getProvider error: %v", err.Error())
+		return nil, err
 	}
 
-	// Use base URL from config if available
-	baseURL := "https://api.openai.com/v1/chat/completions"
-	if s.cfg.LLM.BaseURL != "" {
-		baseURL = s.cfg.LLM.BaseURL + "/v1/chat/completions"
-	}
-
-	req, err := http.NewRequest("POST", baseURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		logger.Errorf("ChatStreamByLine http.NewRequest error: %v", err.Error())
-		return err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.apiKey))
-
-	client := &http.Client{
-		Timeout: time.Duration(s.cfg.LLM.Timeout) * time.Second,
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		logger.Errorf("ChatStreamByLine do request error: %v", err.Error())
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		logger.Errorf("ChatStreamByLine resp.StatusCode is not: %v, but is %v", 200, resp.StatusCode)
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	reader := bufio.NewReader(resp.Body)
 	var lineBuffer strings.Builder
-	var fullResponse strings.Builder
 	lineNum := 1
 
-	for {
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			if err == io.EOF {
-				// Send any remaining content in the buffer at the end
-				if lineBuffer.Len() > 0 {
-					// 判断模式是否为single-html，这个模式需要将开头的```这种文本去掉
-					if isContinue && lineNum < 5 && assistantmessage.StartsWithCodeBlock(lineBuffer.String()) {
-						logger.Infof("ChatStreamByLine line string: %v, line number: %v", lineBuffer.String(), lineNum)
-						continue
-					}
-
-					if _, err := writer.Write([]byte(lineBuffer.String())); err != nil {
-						return err
-					}
-					if flusher, ok := writer.(http.Flusher); ok {
-						flusher.Flush()
-					}
-					lineNum += 1
-				}
-				break
+	usage, err := provider.ChatStream(ctx, req, func(event llm.StreamEvent) error {
+		content := event.Content
+		if content == "" {
+			if mode != SessionModeSingleHtml {
+				// SessionModeSingleHtml模式如果最后没有数据，那么就丢弃最后一行数据
+				content = "\n"
+			} else if mode == SessionModeSingleHtml && strings.Contains(lineBuffer.String(), "</write_to_file>") {
+				// 判断最后一行里面是否有 write_to_file 闭合标签
+				content = "\n"
 			}
-			return err
-		}
-
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
 		}
-
-		// SSE format: each message starts with "data: "
-		if !strings.HasPrefix(line, "data: ") {
-			continue
+		if content == "" {
+			return nil
 		}
 
-		// Remove the "data: " prefix
-		line = strings.TrimPrefix(line, "data: ")
+		// Add to line buffer
+		lineBuffer.WriteString(content)
 
-		// Check for end of stream
-		if line == "[DONE]" {
-			break
+		// If we have a complete line, send it
+		if !strings.Contains(content, "\n") {
+			return nil
 		}
 
-		var chunk StreamChunk
-		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
-			continue // Skip malformed chunks
-		}
-
-		if len(chunk.Choices) > 0 {
-			content := chunk.Choices[0].Delta.Content
-			if content == "" {
-				if mode != SessionModeSingleHtml {
-					// SessionModeSingleHtml模式如果最后没有数据，那么就丢弃最后一行数据
-					content = "\n"
-				} else if mode == SessionModeSingleHtml && strings.Contains(lineBuffer.String(), "</write_to_file>") {
-					// 判断最后一行里面是否有 write_to_file 闭合标签
-					content = "\n"
-				}
+		lines := strings.Split(lineBuffer.String(), "\n")
+		for i := 0; i < len(lines)-1; i++ {
+			// 判断模式是否为single-html，这个模式需要将开头的```这种文本去掉
+			if isContinue && lineNum < 5 && assistantmessage.StartsWithCodeBlock(lines[i]) {
+				logger.Infof("ChatStreamByLine line string: %v, line number: %v", lines[i], lineNum)
+				continue
 			}
-			if content != "" {
-				// Add to full response
-				fullResponse.WriteString(content)
-
-				// Add to line buffer
-				lineBuffer.WriteString(content)
-
-				// If we have a complete line, send it
-				if strings.Contains(content, "\n") {
-					lines := strings.Split(lineBuffer.String(), "\n")
-					for i := 0; i < len(lines)-1; i++ {
-						// 判断模式是否为single-html，这个模式需要将开头的```这种文本去掉
-						if isContinue && lineNum < 5 && assistantmessage.StartsWithCodeBlock(lines[i]) {
-							logger.Infof("ChatStreamByLine line string: %v, line number: %v", lines[i], lineNum)
-							continue
-						}
-						if _, err := writer.Write([]byte(lines[i] + "\n")); err != nil {
-							return err
-						}
-						if flusher, ok := writer.(http.Flusher); ok {
-							flusher.Flush()
-						}
-						lineNum += 1
-					}
-
-					// Keep the remainder in the buffer
-					lineBuffer.Reset()
-					lineBuffer.WriteString(lines[len(lines)-1])
-				}
+			if err := writeAndFlush(writer, []byte(lines[i]+"\n")); err != nil {
+				return err
 			}
+			lineNum += 1
 		}
+
+		// Keep the remainder in the buffer
+		lineBuffer.Reset()
+		lineBuffer.WriteString(lines[len(lines)-1])
+		return nil
+	})
+	if err != nil {
+		logger.Errorf("ChatStreamByLine do request error: %v", err.Error())
 	}
 
-	return nil
+	return usage, err
 }
 
 // SSE 写入器，格式化为 SSE 事件
 type SseLineWriter struct {
-	ResponseWriter gin.ResponseWriter
+	ResponseWriter utils.FlushWriter
 	Buffer         *strings.Builder
 	LastSendText   string
 	Mode           string
@@ -619,6 +374,9 @@ type SseLineWriter struct {
 	LastSaveAt     *time.Time
 	MsgId          int64
 	UserMsgId      int64
+	Approval       *tools.ApprovalPolicy // single-html 模式写文件时使用的批准策略
+	// ExecuteTool single-html 模式执行写文件工具，由调用方负责保存检查点，为空时直接执行
+	ExecuteTool func(input tools.ExecutorInput) (*tools.ExecutorResult, error)
 }
 type StreamLineChunk struct {
 	Filename   string                                     `json:"filename"`
@@ -731,15 +489,21 @@ func (w *SseLineWriter) WriteModeSingleHtml(p []byte) (n int, err error) {
 					c.Params["line_count"] = "10"
 				}
 
-				// 执行工具
+				// 执行工具，选择 single-html 模式即同意写入页面文件，但仍然要经过批准策略
 				executeParams := tools.ExecutorInput{
 					ToolUse:             *c,
 					Ctx:                 context.Background(),
 					Cwd:                 w.Cwd,
 					DiffStrategy:        nil,
 					RooIgnoreController: nil,
+					Confirmed:           true,
+					Approval:            w.Approval,
+				}
+				execute := w.ExecuteTool
+				if execute == nil {
+					execute = tools.ExecuteTool
 				}
-				executeRes, err := tools.ExecuteTool(executeParams)
+				executeRes, err := execute(executeParams)
 				if err != nil {
 					logger.Infof("WriteModeSingleHtml execute: %v, error: %v", executeRes, err.Error())
 				}
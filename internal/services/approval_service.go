@@ -0,0 +1,92 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"mind-weaver/config"
+	"mind-weaver/internal/db"
+	"mind-weaver/internal/third/tools"
+)
+
+// 批准策略的来源
+const (
+	PolicySourceSession = "session"
+	PolicySourceProject = "project"
+	PolicySourceDefault = "default"
+)
+
+// ApprovalService 管理工具的自动批准策略，会话策略优先于项目策略，都没有时使用配置中的默认值
+type ApprovalService struct {
+	database *db.Database
+	cfg      config.Config
+}
+
+// EffectivePolicy is the policy applied to a session and where it comes from.
+type EffectivePolicy struct {
+	Source string                `json:"source"` // "session", "project" or "default"
+	Policy *tools.ApprovalPolicy `json:"policy"`
+}
+
+func NewApprovalService(database *db.Database, cfg *config.Config) *ApprovalService {
+	return &ApprovalService{
+		database: database,
+		cfg:      *cfg,
+	}
+}
+
+// DefaultPolicy allows the tools listed in agent.auto_approve and asks for everything else.
+func (s *ApprovalService) DefaultPolicy() *tools.ApprovalPolicy {
+	return &tools.ApprovalPolicy{AllowTools: s.cfg.Agent.GetAutoApprove()}
+}
+
+// GetPolicy returns the policy stored for scope, nil when there is none.
+func (s *ApprovalService) GetPolicy(scope string, scopeID int64) (*tools.ApprovalPolicy, error) {
+	record, err := s.database.GetToolApprovalPolicy(scope, scopeID)
+	if err != nil || record == nil {
+		return nil, err
+	}
+
+	policy := &tools.ApprovalPolicy{}
+	if err := json.Unmarshal([]byte(record.Policy), policy); err != nil {
+		return nil, fmt.Errorf("invalid %s approval policy %d: %w", scope, scopeID, err)
+	}
+	return policy, nil
+}
+
+func (s *ApprovalService) SavePolicy(scope string, scopeID int64, policy *tools.ApprovalPolicy) error {
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	return s.database.SaveToolApprovalPolicy(scope, scopeID, string(data))
+}
+
+func (s *ApprovalService) DeletePolicy(scope string, scopeID int64) error {
+	return s.database.DeleteToolApprovalPolicy(scope, scopeID)
+}
+
+// GetSessionPolicy resolves the policy of a session: its own, then its project's, then the default.
+func (s *ApprovalService) GetSessionPolicy(sessionID int64) (*EffectivePolicy, error) {
+	policy, err := s.GetPolicy(db.PolicyScopeSession, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if policy != nil {
+		return &EffectivePolicy{Source: PolicySourceSession, Policy: policy}, nil
+	}
+
+	session, err := s.database.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	policy, err = s.GetPolicy(db.PolicyScopeProject, session.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if policy != nil {
+		return &EffectivePolicy{Source: PolicySourceProject, Policy: policy}, nil
+	}
+
+	return &EffectivePolicy{Source: PolicySourceDefault, Policy: s.DefaultPolicy()}, nil
+}
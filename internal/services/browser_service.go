@@ -0,0 +1,110 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"mind-weaver/config"
+	"mind-weaver/internal/third/tools"
+	"mind-weaver/pkg/logger"
+)
+
+// BrowserService 为每个会话保存一个 browser_action 使用的浏览器，空闲一段时间后自动关闭
+type BrowserService struct {
+	mu          sync.Mutex
+	enabled     bool
+	options     tools.BrowserOptions
+	idleTimeout time.Duration
+	sessions    map[int64]*browserEntry
+}
+
+type browserEntry struct {
+	session *tools.BrowserSession
+	timer   *time.Timer
+}
+
+func NewBrowserService(cfg *config.Config) *BrowserService {
+	width, height := cfg.Browser.GetViewportSize()
+	storagePath := cfg.Server.TempStoragePath
+	if storagePath == "" {
+		storagePath = "./data/temp"
+	}
+	return &BrowserService{
+		enabled: cfg.Browser.Enabled,
+		options: tools.BrowserOptions{
+			Bin:            cfg.Browser.Bin,
+			ViewportWidth:  width,
+			ViewportHeight: height,
+			ScreenshotDir:  filepath.Join(storagePath, "screenshots"),
+		},
+		idleTimeout: cfg.Browser.GetIdleTimeout(),
+		sessions:    map[int64]*browserEntry{},
+	}
+}
+
+// Enabled reports whether the model may use browser_action.
+func (s *BrowserService) Enabled() bool {
+	return s.enabled
+}
+
+// ViewportSize returns the viewport in the "900x600" form used by the prompts.
+func (s *BrowserService) ViewportSize() string {
+	return fmt.Sprintf("%dx%d", s.options.ViewportWidth, s.options.ViewportHeight)
+}
+
+// Session returns the browser of a chat session, nil when the browser is disabled.
+// Every call restarts the idle timer of the session.
+func (s *BrowserService) Session(sessionID int64) *tools.BrowserSession {
+	if !s.enabled {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.sessions[sessionID]
+	if !ok {
+		entry = &browserEntry{session: tools.NewBrowserSession(s.options)}
+		s.sessions[sessionID] = entry
+	}
+	if entry.timer != nil {
+		entry.timer.Stop()
+	}
+	entry.timer = time.AfterFunc(s.idleTimeout, func() {
+		s.Close(sessionID)
+	})
+	return entry.session
+}
+
+// Close closes the browser of a session if it is open.
+func (s *BrowserService) Close(sessionID int64) {
+	s.mu.Lock()
+	entry, ok := s.sessions[sessionID]
+	delete(s.sessions, sessionID)
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	entry.timer.Stop()
+	if entry.session.Running() {
+		if err := entry.session.Close(); err != nil {
+			logger.Errorf("Failed to close browser of session %d: %v", sessionID, err)
+		}
+	}
+}
+
+// CloseAll closes every open browser, used on shutdown.
+func (s *BrowserService) CloseAll() {
+	s.mu.Lock()
+	ids := make([]int64, 0, len(s.sessions))
+	for id := range s.sessions {
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+
+	for _, id := range ids {
+		s.Close(id)
+	}
+}
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"mind-weaver/config"
+	"mind-weaver/internal/db"
+	"mind-weaver/internal/third/prompts"
+)
+
+// 文件相对检查点的变化
+const (
+	CheckpointFileModified = "modified" // 检查点之后被修改
+	CheckpointFileCreated  = "created"  // 检查点时不存在，之后被创建
+	CheckpointFileDeleted  = "deleted"  // 检查点之后被删除
+)
+
+// ErrCheckpointNotFound is returned when a checkpoint does not exist.
+var ErrCheckpointNotFound = errors.New("checkpoint not found")
+
+// CheckpointService 在编辑类工具执行前保存被修改文件的快照，用于查看差异和恢复。
+// 文件内容按 sha256 保存在 temp_storage_path/checkpoints 下，相同内容只保存一份
+type CheckpointService struct {
+	database *db.Database
+	storeDir string
+}
+
+// CheckpointFileChange is how a file differs between a checkpoint and the workspace.
+type CheckpointFileChange struct {
+	Path    string `json:"path"` // 相对项目根目录的路径，不在项目内时为绝对路径
+	Status  string `json:"status"`
+	Diff    string `json:"diff"`
+	Existed bool   `json:"existed"`
+}
+
+// CheckpointDiff lists the workspace changes made since a checkpoint.
+type CheckpointDiff struct {
+	Checkpoint *db.Checkpoint          `json:"checkpoint"`
+	Files      []*CheckpointFileChange `json:"files"`
+}
+
+func NewCheckpointService(database *db.Database, cfg *config.Config) *CheckpointService {
+	storagePath := cfg.Server.TempStoragePath
+	if storagePath == "" {
+		storagePath = "./data/temp"
+	}
+	return &CheckpointService{
+		database: database,
+		storeDir: filepath.Join(storagePath, "checkpoints"),
+	}
+}
+
+// Snapshot records the current content of paths before a tool modifies them.
+// Returns nil when none of the paths is a regular file or a missing file.
+func (s *CheckpointService) Snapshot(sessionID, messageID int64, toolName string, paths []string) (*db.Checkpoint, error) {
+	checkpoint := &db.Checkpoint{SessionID: sessionID, MessageID: messageID, ToolName: toolName, Files: []*db.CheckpointFile{}}
+	seen := map[string]bool{}
+	for _, filePath := range paths {
+		if filePath == "" || seen[filePath] {
+			continue
+		}
+		seen[filePath] = true
+
+		info, err := os.Stat(filePath)
+		if os.IsNotExist(err) {
+			checkpoint.Files = append(checkpoint.Files, &db.CheckpointFile{Path: filePath, Existed: false})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			continue
+		}
+
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := s.storeObject(content)
+		if err != nil {
+			return nil, fmt.Errorf("saving snapshot of %s: %w", filePath, err)
+		}
+		checkpoint.Files = append(checkpoint.Files, &db.CheckpointFile{Path: filePath, Existed: true, Hash: hash})
+	}
+	if len(checkpoint.Files) == 0 {
+		return nil, nil
+	}
+
+	id, err := s.database.CreateCheckpoint(checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	checkpoint.ID = id
+	return checkpoint, nil
+}
+
+func (s *CheckpointService) ListCheckpoints(sessionID int64) ([]*db.Checkpoint, error) {
+	return s.database.ListSessionCheckpoints(sessionID)
+}
+
+func (s *CheckpointService) DeleteCheckpoint(id int64) error {
+	return s.database.DeleteCheckpoint(id)
+}
+
+// Diff compares the workspace with the state right before the checkpoint's tool ran.
+// Files touched by later checkpoints of the same session are included as well.
+func (s *CheckpointService) Diff(checkpointID int64) (*CheckpointDiff, error) {
+	checkpoint, files, err := s.filesSince(checkpointID)
+	if err != nil {
+		return nil, err
+	}
+	projectPath := s.projectPath(checkpoint.SessionID)
+
+	result := &CheckpointDiff{Checkpoint: checkpoint, Files: []*CheckpointFileChange{}}
+	for _, file := range files {
+		before, err := s.snapshotContent(file)
+		if err != nil {
+			return nil, err
+		}
+		current, err := os.ReadFile(file.Path)
+		exists := err == nil
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if exists == file.Existed && string(current) == before {
+			continue
+		}
+
+		change := &CheckpointFileChange{Path: displayPath(projectPath, file.Path), Status: CheckpointFileModified, Existed: file.Existed}
+		switch {
+		case !file.Existed:
+			change.Status = CheckpointFileCreated
+		case !exists:
+			change.Status = CheckpointFileDeleted
+		}
+		change.Diff = prompts.CreatePrettyPatch(change.Path, before, string(current))
+		result.Files = append(result.Files, change)
+	}
+	return result, nil
+}
+
+// Restore puts every file touched since the checkpoint back to its content before
+// the checkpoint's tool ran; files that did not exist then are deleted.
+func (s *CheckpointService) Restore(checkpointID int64) ([]string, error) {
+	checkpoint, files, err := s.filesSince(checkpointID)
+	if err != nil {
+		return nil, err
+	}
+	projectPath := s.projectPath(checkpoint.SessionID)
+
+	restored := []string{}
+	for _, file := range files {
+		if !file.Existed {
+			if err := os.Remove(file.Path); err != nil && !os.IsNotExist(err) {
+				return restored, err
+			}
+			restored = append(restored, displayPath(projectPath, file.Path))
+			continue
+		}
+
+		content, err := s.snapshotContent(file)
+		if err != nil {
+			return restored, err
+		}
+		mode := os.FileMode(0644)
+		if info, err := os.Stat(file.Path); err == nil {
+			mode = info.Mode()
+		}
+		if err := os.MkdirAll(filepath.Dir(file.Path), 0755); err != nil {
+			return restored, err
+		}
+		if err := os.WriteFile(file.Path, []byte(content), mode); err != nil {
+			return restored, err
+		}
+		restored = append(restored, displayPath(projectPath, file.Path))
+	}
+	return restored, nil
+}
+
+// filesSince returns the checkpoint and, for every file touched by it or a later
+// checkpoint of the session, the earliest snapshot taken from then on.
+func (s *CheckpointService) filesSince(checkpointID int64) (*db.Checkpoint, []*db.CheckpointFile, error) {
+	checkpoint, err := s.database.GetCheckpoint(checkpointID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if checkpoint == nil {
+		return nil, nil, ErrCheckpointNotFound
+	}
+
+	checkpoints, err := s.database.ListSessionCheckpoints(checkpoint.SessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files := []*db.CheckpointFile{}
+	seen := map[string]bool{}
+	for _, item := range checkpoints {
+		if item.ID < checkpointID {
+			continue
+		}
+		for _, file := range item.Files {
+			if !seen[file.Path] {
+				seen[file.Path] = true
+				files = append(files, file)
+			}
+		}
+	}
+	return checkpoint, files, nil
+}
+
+func (s *CheckpointService) snapshotContent(file *db.CheckpointFile) (string, error) {
+	if !file.Existed {
+		return "", nil
+	}
+	content, err := os.ReadFile(s.objectPath(file.Hash))
+	if err != nil {
+		return "", fmt.Errorf("reading snapshot of %s: %w", file.Path, err)
+	}
+	return string(content), nil
+}
+
+// storeObject saves content under its sha256 and returns the hash.
+func (s *CheckpointService) storeObject(content []byte) (string, error) {
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	objectPath := s.objectPath(hash)
+	if _, err := os.Stat(objectPath); err == nil {
+		return hash, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(objectPath), 0755); err != nil {
+		return "", err
+	}
+	// 先写临时文件再重命名，避免留下不完整的快照
+	tmp, err := os.CreateTemp(filepath.Dir(objectPath), hash+".tmp*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	return hash, os.Rename(tmp.Name(), objectPath)
+}
+
+func (s *CheckpointService) objectPath(hash string) string {
+	return filepath.Join(s.storeDir, "objects", hash[:2], hash)
+}
+
+func (s *CheckpointService) projectPath(sessionID int64) string {
+	session, err := s.database.GetSession(sessionID)
+	if err != nil {
+		return ""
+	}
+	project, err := s.database.GetProject(session.ProjectID)
+	if err != nil || project == nil {
+		return ""
+	}
+	return project.Path
+}
+
+func displayPath(projectPath, filePath string) string {
+	if projectPath == "" {
+		return filePath
+	}
+	rel, err := filepath.Rel(projectPath, filePath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filePath
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mind-weaver/config"
+	"mind-weaver/internal/db"
+)
+
+func TestCheckpointRestore(t *testing.T) {
+	dir := t.TempDir()
+	database, err := db.InitDB(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	projectDir := filepath.Join(dir, "project")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	projectID, err := database.CreateProject("demo", projectDir, "go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionID, err := database.CreateSession(projectID, "demo", "auto", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{}
+	cfg.Server.TempStoragePath = filepath.Join(dir, "temp")
+	service := NewCheckpointService(database, cfg)
+
+	mainPath := filepath.Join(projectDir, "main.go")
+	newPath := filepath.Join(projectDir, "util.go")
+	if err := os.WriteFile(mainPath, []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一次编辑修改 main.go，第二次编辑新建 util.go 并再次修改 main.go
+	first, err := service.Snapshot(sessionID, 1, "write_to_file", []string{mainPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(mainPath, []byte("package main\n\nfunc main() {}\n"), 0644)
+	second, err := service.Snapshot(sessionID, 2, "batch_edit", []string{mainPath, newPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(mainPath, []byte("package main\n\nfunc main() { run() }\n"), 0644)
+	os.WriteFile(newPath, []byte("package main\n\nfunc run() {}\n"), 0644)
+
+	checkpoints, err := service.ListCheckpoints(sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checkpoints) != 2 || len(checkpoints[1].Files) != 2 {
+		t.Fatalf("Expected 2 checkpoints with 1 and 2 files, got %+v", checkpoints)
+	}
+
+	diff, err := service.Diff(first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := map[string]string{}
+	for _, file := range diff.Files {
+		statuses[file.Path] = file.Status
+	}
+	if statuses["main.go"] != CheckpointFileModified || statuses["util.go"] != CheckpointFileCreated {
+		t.Errorf("Expected main.go modified and util.go created, got %v", statuses)
+	}
+	if !strings.Contains(diff.Files[0].Diff, "+func main() { run() }") {
+		t.Errorf("Expected diff to contain the new line, got %q", diff.Files[0].Diff)
+	}
+
+	// 恢复到第二个检查点：main.go 回到第一次编辑后的内容，util.go 被删除
+	if _, err := service.Restore(second.ID); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := os.ReadFile(mainPath)
+	if string(content) != "package main\n\nfunc main() {}\n" {
+		t.Errorf("Expected main.go restored to second checkpoint, got %q", string(content))
+	}
+	if _, err := os.Stat(newPath); !os.IsNotExist(err) {
+		t.Errorf("Expected util.go to be deleted, got %v", err)
+	}
+
+	// 恢复到第一个检查点
+	restored, err := service.Restore(first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ = os.ReadFile(mainPath)
+	if string(content) != "package main\n" {
+		t.Errorf("Expected main.go restored to first checkpoint, got %q", string(content))
+	}
+	if len(restored) != 2 {
+		t.Errorf("Expected 2 restored files, got %v", restored)
+	}
+
+	if _, err := service.Diff(9999); err != ErrCheckpointNotFound {
+		t.Errorf("Expected ErrCheckpointNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,336 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"mind-weaver/config"
+	"mind-weaver/internal/codeindex"
+	"mind-weaver/internal/db"
+	"mind-weaver/internal/llm"
+	"mind-weaver/pkg/logger"
+)
+
+// 代码索引两次增量更新之间的最短间隔
+const codeIndexRefreshInterval = 5 * time.Second
+
+var ErrCodeIndexDisabled = errors.New("code index is not enabled")
+
+// CodeIndexService keeps a vector index of the chunks of every project file in
+// SQLite and searches it by meaning. Only files whose modification time and
+// content changed are embedded again.
+type CodeIndexService struct {
+	database *db.Database
+	cfg      config.CodeIndex
+	embedder llm.Embedder
+	model    string // 保存在索引中的模型标识，接口、模型或维度变化后文件会被重新索引
+
+	mu      sync.Mutex
+	indexes map[string]*projectCodeIndex // 项目绝对路径 -> 内存中的向量
+}
+
+type projectCodeIndex struct {
+	mu          sync.Mutex
+	entries     []codeindex.Entry
+	loaded      bool
+	refreshedAt time.Time
+}
+
+// CodeIndexStats describes the index of a project after an update.
+type CodeIndexStats struct {
+	Files    int   `json:"files"`    // 索引中的文件数
+	Chunks   int   `json:"chunks"`   // 索引中的分块数
+	Indexed  int   `json:"indexed"`  // 本次重新生成向量的文件数
+	Removed  int   `json:"removed"`  // 本次移除的已删除文件数
+	Duration int64 `json:"duration"` // 本次更新耗时(毫秒)
+}
+
+// pendingFile 等待生成向量的文件
+type pendingFile struct {
+	file   *db.CodeIndexFile
+	chunks []codeindex.Chunk
+}
+
+func NewCodeIndexService(database *db.Database, cfg *config.Config) (*CodeIndexService, error) {
+	s := &CodeIndexService{database: database, cfg: cfg.CodeIndex, indexes: map[string]*projectCodeIndex{}}
+	if !cfg.CodeIndex.Enabled {
+		return s, nil
+	}
+
+	opts := llm.Options{BaseURL: cfg.CodeIndex.BaseURL, APIKey: cfg.CodeIndex.GetAPIKey("")}
+	provider := strings.ToLower(cfg.CodeIndex.Provider)
+	if provider == "" || provider == llm.ProviderOpenAI {
+		// 兼容openai的网关通常同时提供对话和向量接口
+		if opts.BaseURL == "" {
+			opts.BaseURL = cfg.LLM.BaseURL
+		}
+		opts.APIKey = cfg.CodeIndex.GetAPIKey(cfg.LLM.APIKey)
+	}
+	if provider != llm.ProviderLocal {
+		httpClient, err := newLLMHTTPClient(cfg)
+		if err != nil {
+			return nil, err
+		}
+		opts.HTTPClient = httpClient
+	}
+	embedder, err := llm.NewEmbedder(provider, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedder: %w", err)
+	}
+	s.embedder = embedder
+	s.model = fmt.Sprintf("%s:%s:%d", provider, cfg.CodeIndex.GetModel(), cfg.CodeIndex.Dimensions)
+	return s, nil
+}
+
+// Enabled reports whether the code index and codebase_search are available.
+func (s *CodeIndexService) Enabled() bool {
+	return s.embedder != nil
+}
+
+// Update embeds the files of a project that changed since the last update and
+// drops removed files.
+func (s *CodeIndexService) Update(ctx context.Context, projectPath string) (*CodeIndexStats, error) {
+	if !s.Enabled() {
+		return nil, ErrCodeIndexDisabled
+	}
+	abs, index, err := s.get(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	return s.update(ctx, abs, index)
+}
+
+// Rebuild drops the index of a project and embeds all files again.
+func (s *CodeIndexService) Rebuild(ctx context.Context, projectPath string) (*CodeIndexStats, error) {
+	if !s.Enabled() {
+		return nil, ErrCodeIndexDisabled
+	}
+	abs, index, err := s.get(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	if err := s.database.DeleteCodeIndex(abs); err != nil {
+		return nil, err
+	}
+	index.entries, index.loaded = nil, false
+	return s.update(ctx, abs, index)
+}
+
+// Search returns the chunks of a project most similar to query, limited to
+// files under pathPrefix when it is not empty. The index is updated first.
+func (s *CodeIndexService) Search(ctx context.Context, projectPath, query, pathPrefix string, limit int) ([]codeindex.Result, error) {
+	if !s.Enabled() {
+		return nil, ErrCodeIndexDisabled
+	}
+	abs, index, err := s.get(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	index.mu.Lock()
+	if !index.loaded || time.Since(index.refreshedAt) > codeIndexRefreshInterval {
+		if _, err := s.update(ctx, abs, index); err != nil {
+			index.mu.Unlock()
+			return nil, err
+		}
+	}
+	entries := index.entries
+	index.mu.Unlock()
+
+	vectors, err := s.embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = s.cfg.GetMaxResults()
+	}
+	return codeindex.Search(entries, vectors[0], pathPrefix, limit), nil
+}
+
+func (s *CodeIndexService) get(projectPath string) (string, *projectCodeIndex, error) {
+	abs, err := filepath.Abs(projectPath)
+	if err != nil {
+		return "", nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	index, ok := s.indexes[abs]
+	if !ok {
+		index = &projectCodeIndex{}
+		s.indexes[abs] = index
+	}
+	return abs, index, nil
+}
+
+// update 调用方需要持有 index.mu
+func (s *CodeIndexService) update(ctx context.Context, root string, index *projectCodeIndex) (*CodeIndexStats, error) {
+	start := time.Now()
+	files, err := codeindex.ScanFiles(root, s.cfg.GetMaxFiles())
+	if err != nil {
+		return nil, err
+	}
+	indexed, err := s.database.ListCodeIndexFiles(root)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]*db.CodeIndexFile, len(indexed))
+	for _, file := range indexed {
+		known[file.Path] = file
+	}
+
+	stats := &CodeIndexStats{Files: len(files)}
+	var pending []pendingFile
+	pendingChunks := 0
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		if err := s.embedFiles(ctx, pending); err != nil {
+			return err
+		}
+		stats.Indexed += len(pending)
+		pending, pendingChunks = nil, 0
+		return nil
+	}
+
+	seen := make(map[string]bool, len(files))
+	for _, file := range files {
+		seen[file.Path] = true
+		old := known[file.Path]
+		if old != nil && old.Model == s.model && old.ModTime == file.ModTime && old.Size == file.Size {
+			continue
+		}
+		content, err := codeindex.ReadFile(root, file.Path)
+		if err != nil {
+			logger.Errorf("Failed to read %s for the code index: %v", file.Path, err)
+			continue
+		}
+		sum := sha256.Sum256(content)
+		hash := hex.EncodeToString(sum[:])
+		if old != nil && old.Model == s.model && old.Hash == hash {
+			// 内容没有变化，只记录新的修改时间
+			if err := s.database.UpdateCodeIndexFileStat(old.ID, file.ModTime, file.Size); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// 压缩后的代码没有分块，同样记录下来，没有修改时不再重复读取
+		var chunks []codeindex.Chunk
+		if content != nil {
+			chunks = codeindex.ChunkFile(file.Path, content, s.cfg.GetMaxChunkLines())
+		}
+		pending = append(pending, pendingFile{
+			file:   &db.CodeIndexFile{ProjectPath: root, Path: file.Path, ModTime: file.ModTime, Size: file.Size, Hash: hash, Model: s.model},
+			chunks: chunks,
+		})
+		pendingChunks += len(chunks)
+		if pendingChunks >= s.cfg.GetBatchSize() {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	for path, old := range known {
+		if !seen[path] {
+			if err := s.database.DeleteCodeIndexFile(old.ID); err != nil {
+				return nil, err
+			}
+			stats.Removed++
+		}
+	}
+
+	if !index.loaded || stats.Indexed > 0 || stats.Removed > 0 {
+		chunks, err := s.database.ListCodeIndexChunks(root)
+		if err != nil {
+			return nil, err
+		}
+		index.entries = make([]codeindex.Entry, len(chunks))
+		for i, chunk := range chunks {
+			index.entries[i] = codeindex.Entry{
+				Chunk: codeindex.Chunk{
+					Path: chunk.Path, StartLine: chunk.StartLine, EndLine: chunk.EndLine,
+					Symbol: chunk.Symbol, Kind: chunk.Kind, Content: chunk.Content,
+				},
+				Vector: chunk.Vector,
+			}
+		}
+		index.loaded = true
+	}
+	index.refreshedAt = time.Now()
+
+	stats.Chunks = len(index.entries)
+	stats.Duration = time.Since(start).Milliseconds()
+	if stats.Indexed > 0 || stats.Removed > 0 {
+		logger.Infof("Updated code index of %s: %d files embedded, %d removed in %v", root, stats.Indexed, stats.Removed, time.Since(start))
+	}
+	return stats, nil
+}
+
+// embedFiles 为等待的文件生成向量并保存，每个文件单独保存，中途失败时已保存的文件不需要重新生成
+func (s *CodeIndexService) embedFiles(ctx context.Context, pending []pendingFile) error {
+	var texts []string
+	for _, p := range pending {
+		for _, chunk := range p.chunks {
+			texts = append(texts, chunk.EmbeddingText())
+		}
+	}
+	vectors, err := s.embed(ctx, texts)
+	if err != nil {
+		return err
+	}
+
+	offset := 0
+	for _, p := range pending {
+		chunks := make([]*db.CodeIndexChunk, len(p.chunks))
+		for i, chunk := range p.chunks {
+			chunks[i] = &db.CodeIndexChunk{
+				StartLine: chunk.StartLine, EndLine: chunk.EndLine,
+				Symbol: chunk.Symbol, Kind: chunk.Kind, Content: chunk.Content,
+				Vector: vectors[offset+i],
+			}
+		}
+		offset += len(p.chunks)
+		if _, err := s.database.SaveCodeIndexFile(p.file, chunks); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// embed 按 batch_size 分批请求向量接口
+func (s *CodeIndexService) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	batchSize := s.cfg.GetBatchSize()
+	for start := 0; start < len(texts); start += batchSize {
+		batch := texts[start:min(start+batchSize, len(texts))]
+		result, err := s.embedder.Embed(ctx, &llm.EmbeddingRequest{
+			Model:      s.cfg.GetModel(),
+			Input:      batch,
+			Dimensions: s.cfg.Dimensions,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed chunks: %w", err)
+		}
+		if len(result) != len(batch) {
+			return nil, fmt.Errorf("expected %d embeddings, got %d", len(batch), len(result))
+		}
+		vectors = append(vectors, result...)
+	}
+	return vectors, nil
+}
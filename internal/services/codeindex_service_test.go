@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mind-weaver/config"
+	"mind-weaver/internal/db"
+	"mind-weaver/internal/llm"
+	"mind-weaver/pkg/logger"
+)
+
+// countingEmbedder 记录生成向量的文本数
+type countingEmbedder struct {
+	llm.HashEmbedder
+	texts int
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, req *llm.EmbeddingRequest) ([][]float32, error) {
+	e.texts += len(req.Input)
+	return e.HashEmbedder.Embed(ctx, req)
+}
+
+func TestCodeIndexSearch(t *testing.T) {
+	dir := t.TempDir()
+	logger.Setup(config.Logger{Filename: filepath.Join(dir, "test.log")})
+	database, err := db.InitDB(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	root := filepath.Join(dir, "project")
+	write := func(name, content string) {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("billing/refund.go", `package billing
+
+// RetryFailedRefunds 重新提交失败的退款，每次重试间隔翻倍
+func RetryFailedRefunds(queue *RefundQueue) error {
+	for _, refund := range queue.Failed() {
+		queue.Retry(refund)
+	}
+	return nil
+}
+`)
+	write("web/render.go", `package web
+
+func RenderTemplate(name string, data any) string {
+	return name
+}
+`)
+	write("auth/token.py", `def validate_api_key(key):
+    return key.startswith("sk-")
+`)
+
+	service, err := NewCodeIndexService(database, &config.Config{CodeIndex: config.CodeIndex{Enabled: true, Provider: "local"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	embedder := &countingEmbedder{}
+	service.embedder = embedder
+
+	results, err := service.Search(context.Background(), root, "where do we handle refund retries", "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == 0 || results[0].Path != "billing/refund.go" || results[0].Symbol != "RetryFailedRefunds" || results[0].StartLine != 3 {
+		t.Fatalf("Expected RetryFailedRefunds first, got %+v", results)
+	}
+	if results, _ := service.Search(context.Background(), root, "validate api key", "web", 0); len(results) != 0 {
+		t.Errorf("Expected no results outside of web, got %+v", results)
+	}
+
+	// 没有修改时不重新生成向量
+	indexed := embedder.texts
+	stats, err := service.Update(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Indexed != 0 || stats.Files != 3 || embedder.texts != indexed {
+		t.Errorf("Expected nothing to be embedded again, got %+v after %d texts", stats, embedder.texts-indexed)
+	}
+
+	// 只修改时间变化的文件不重新生成向量，修改和删除的文件在更新后生效
+	later := time.Now().Add(time.Second)
+	os.Chtimes(filepath.Join(root, "web/render.go"), later, later)
+	write("auth/token.py", "def validate_api_key(key):\n    return key.startswith(\"sk-\") and len(key) > 20\n")
+	os.Remove(filepath.Join(root, "billing/refund.go"))
+	stats, err = service.Update(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Indexed != 1 || stats.Removed != 1 || stats.Files != 2 {
+		t.Errorf("Expected 1 file embedded and 1 removed, got %+v", stats)
+	}
+	results, err = service.Search(context.Background(), root, "refund retries", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range results {
+		if result.Path == "billing/refund.go" {
+			t.Errorf("Expected the removed file to be dropped, got %+v", result)
+		}
+	}
+
+	// 重启后从数据库读取索引
+	restarted, _ := NewCodeIndexService(database, &config.Config{CodeIndex: config.CodeIndex{Enabled: true, Provider: "local"}})
+	results, err = restarted.Search(context.Background(), root, "validate the api key", "", 1)
+	if err != nil || len(results) != 1 || results[0].Path != "auth/token.py" {
+		t.Errorf("Expected the stored index to be searched, got %+v %v", results, err)
+	}
+}
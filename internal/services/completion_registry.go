@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrCompletionStopped is the cancel cause used when a user stops a running completion.
+	ErrCompletionStopped = errors.New("completion stopped by user")
+	// ErrClientGone is the cancel cause used when no client reconnected in time.
+	ErrClientGone = errors.New("client disconnected")
+)
+
+const (
+	// 所有客户端断开后，等待重连的时间，超时后取消上游请求
+	reconnectGracePeriod = 30 * time.Second
+	// 回复结束后事件缓存保留的时间，方便刚好断线的客户端补齐最后的内容
+	finishedRetention = 2 * time.Minute
+)
+
+type completionKey struct {
+	SessionID int64
+	UserMsgID int64
+}
+
+// RunningCompletion is a completion that is currently streaming from the model.
+type RunningCompletion struct {
+	SessionID int64     `json:"session_id"`
+	UserMsgID int64     `json:"user_msg_id"`
+	StartedAt time.Time `json:"started_at"`
+
+	// Stream buffers the SSE events so clients can resume with Last-Event-ID.
+	Stream *EventStream `json:"-"`
+
+	cancel      context.CancelCauseFunc
+	mu          sync.Mutex
+	finished    bool
+	subscribers int
+	graceTimer  *time.Timer
+}
+
+// Attach registers a client reading the stream.
+func (c *RunningCompletion) Attach() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.subscribers++
+	if c.graceTimer != nil {
+		c.graceTimer.Stop()
+		c.graceTimer = nil
+	}
+}
+
+// Detach unregisters a client. When the last client leaves, the completion is
+// cancelled unless someone reconnects within the grace period.
+func (c *RunningCompletion) Detach() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.subscribers--
+	if c.subscribers > 0 || c.finished {
+		return
+	}
+	c.graceTimer = time.AfterFunc(reconnectGracePeriod, func() {
+		c.cancel(ErrClientGone)
+	})
+}
+
+// CompletionRegistry 记录正在进行中的模型回复，按会话ID和用户消息ID索引，
+// 用于服务端停止生成以及断线重连
+type CompletionRegistry struct {
+	mu          sync.Mutex
+	completions map[completionKey]*RunningCompletion
+}
+
+func NewCompletionRegistry() *CompletionRegistry {
+	return &CompletionRegistry{
+		completions: make(map[completionKey]*RunningCompletion),
+	}
+}
+
+// Start registers a completion and returns a context that is cancelled with
+// ErrCompletionStopped when Stop is called, or ErrClientGone when all clients left.
+// The context does not depend on the HTTP request, so generation survives short
+// network interruptions. The returned release func must be called once the
+// completion has finished.
+func (r *CompletionRegistry) Start(sessionID, userMsgID int64) (context.Context, *RunningCompletion, func()) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	key := completionKey{SessionID: sessionID, UserMsgID: userMsgID}
+	completion := &RunningCompletion{
+		SessionID: sessionID,
+		UserMsgID: userMsgID,
+		StartedAt: time.Now(),
+		Stream:    NewEventStream(),
+		cancel:    cancel,
+	}
+
+	r.mu.Lock()
+	// 同一条用户消息重复请求时(如重试)，停止旧的回复
+	if old, ok := r.completions[key]; ok {
+		old.cancel(ErrCompletionStopped)
+	}
+	r.completions[key] = completion
+	r.mu.Unlock()
+
+	release := func() {
+		completion.mu.Lock()
+		completion.finished = true
+		if completion.graceTimer != nil {
+			completion.graceTimer.Stop()
+		}
+		completion.mu.Unlock()
+
+		completion.Stream.Close()
+		cancel(nil)
+
+		time.AfterFunc(finishedRetention, func() {
+			r.mu.Lock()
+			if r.completions[key] == completion {
+				delete(r.completions, key)
+			}
+			r.mu.Unlock()
+		})
+	}
+	return ctx, completion, release
+}
+
+// Find returns the completion of a user message, or the latest one of the session
+// when userMsgID is 0. Finished completions are kept for a short while for replay.
+func (r *CompletionRegistry) Find(sessionID, userMsgID int64) *RunningCompletion {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var found *RunningCompletion
+	for key, completion := range r.completions {
+		if key.SessionID != sessionID || (userMsgID != 0 && key.UserMsgID != userMsgID) {
+			continue
+		}
+		if found == nil || completion.StartedAt.After(found.StartedAt) {
+			found = completion
+		}
+	}
+	return found
+}
+
+// Stop cancels the running completions of a session. userMsgID 0 stops all of them.
+// It returns the completions that were stopped.
+func (r *CompletionRegistry) Stop(sessionID, userMsgID int64) []*RunningCompletion {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stopped := []*RunningCompletion{}
+	for key, completion := range r.completions {
+		if key.SessionID != sessionID || (userMsgID != 0 && key.UserMsgID != userMsgID) {
+			continue
+		}
+		completion.mu.Lock()
+		finished := completion.finished
+		completion.mu.Unlock()
+		if finished {
+			continue
+		}
+		completion.cancel(ErrCompletionStopped)
+		stopped = append(stopped, completion)
+	}
+	return stopped
+}
+
+// IsStopped reports whether ctx was cancelled through Stop.
+func IsStopped(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrCompletionStopped)
+}
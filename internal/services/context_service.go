@@ -2,14 +2,28 @@ package services
 
 import (
 	"errors"
-	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
+
+	"mind-weaver/internal/depgraph"
 )
 
+// 依赖图两次刷新之间的最短间隔，避免每次请求都遍历整个项目
+const graphRefreshInterval = 5 * time.Second
+
 type ContextService struct {
 	fileService *FileService
+
+	mu     sync.Mutex
+	graphs map[string]*projectGraph // 项目绝对路径 -> 依赖图
+}
+
+type projectGraph struct {
+	graph       *depgraph.Graph
+	refreshedAt time.Time
 }
 
 type FileContext struct {
@@ -18,11 +32,14 @@ type FileContext struct {
 	Language     string   `json:"language"`
 	LineCount    int      `json:"line_count"`
 	ImportedDeps []string `json:"imported_deps,omitempty"`
+	// 导入的项目文件，只有知道项目路径时才会解析
+	DependencyFiles []string `json:"dependency_files,omitempty"`
 }
 
 func NewContextService(fileService *FileService) *ContextService {
 	return &ContextService{
 		fileService: fileService,
+		graphs:      map[string]*projectGraph{},
 	}
 }
 
@@ -48,8 +65,8 @@ func (cs *ContextService) GetFileContext(filePath string) (*FileContext, error)
 		LineCount: lineCount,
 	}
 
-	// Extract imports (basic implementation - would be more sophisticated in a real app)
-	fileContext.ImportedDeps = cs.extractImports(content, language)
+	// Extract import paths (Go, JS/TS and Python)
+	fileContext.ImportedDeps = depgraph.ParseImports(filePath, content)
 
 	return fileContext, nil
 }
@@ -62,105 +79,112 @@ func (cs *ContextService) GetCurrentFileContext(projectPath, relativePath string
 		return nil, errors.New("file does not exist")
 	}
 
-	return cs.GetFileContext(fullPath)
+	fileContext, err := cs.GetFileContext(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	if graph, err := cs.DependencyGraph(projectPath); err == nil {
+		fileContext.DependencyFiles = graph.Imports(fullPath, 1)
+	}
+	return fileContext, nil
 }
 
+// GetRelatedFiles returns the project files that filePath imports, followed by
+// the files that import it, up to maxFiles. Files of languages without import
+// parsing fall back to code files in the same directory.
 func (cs *ContextService) GetRelatedFiles(projectPath, filePath string, maxFiles int) ([]*FileContext, error) {
-	// Get the current file context
-	currentContext, err := cs.GetFileContext(filePath)
+	graph, err := cs.DependencyGraph(projectPath)
 	if err != nil {
 		return nil, err
 	}
-	fmt.Println(currentContext)
 
-	// Get directory of the current file
-	dir := filepath.Dir(filePath)
-
-	// List files in the same directory
-	files, err := os.ReadDir(dir)
-	if err != nil {
-		return nil, err
+	var related []string
+	if graph.Contains(filePath) {
+		related = append(graph.Imports(filePath, 1), graph.ImportedBy(filePath, 1)...)
+	} else {
+		related, err = siblingCodeFiles(filePath)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	relatedContexts := []*FileContext{}
-	count := 0
-
-	// Add files from the same directory (excluding the current file)
-	for _, file := range files {
-		if count >= maxFiles {
+	seen := map[string]bool{}
+	for _, path := range related {
+		if len(relatedContexts) >= maxFiles {
 			break
 		}
-
-		if file.IsDir() {
-			continue
-		}
-
-		fullPath := filepath.Join(dir, file.Name())
-		if fullPath == filePath {
-			continue // Skip the current file
-		}
-
-		// Only include files with code extensions
-		ext := strings.ToLower(filepath.Ext(file.Name()))
-		if !isCodeFile(ext) {
+		// 循环导入的文件同时出现在两个方向中
+		if seen[path] {
 			continue
 		}
-
-		fileContext, err := cs.GetFileContext(fullPath)
+		seen[path] = true
+		fileContext, err := cs.GetFileContext(path)
 		if err == nil {
 			relatedContexts = append(relatedContexts, fileContext)
-			count++
 		}
 	}
-
-	// In a real implementation, we would also follow imports and include related files
-	// based on the dependency graph, but that's beyond the scope of this example
-
 	return relatedContexts, nil
 }
 
-func (cs *ContextService) extractImports(content, language string) []string {
-	// This is a simplified implementation
-	// In a real app, we would use language-specific parsers
-	imports := []string{}
-
-	switch language {
-	case "go":
-		lines := strings.Split(content, "\n")
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
-			if strings.HasPrefix(line, "import") {
-				// Extract import paths
-				if strings.Contains(line, "(") {
-					continue // Multi-line import, would need more complex parsing
-				}
-				parts := strings.Fields(line)
-				if len(parts) >= 2 {
-					importPath := strings.Trim(parts[1], "\"")
-					imports = append(imports, importPath)
-				}
-			}
-		}
-	case "javascript", "typescript":
-		lines := strings.Split(content, "\n")
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
-			if strings.HasPrefix(line, "import ") || strings.HasPrefix(line, "require(") {
-				// Very basic extraction - would need a proper parser in a real app
-				imports = append(imports, line)
-			}
+// DependencyGraph returns the import graph of a project. The graph is built on
+// first use and refreshed at most once per graphRefreshInterval, re-parsing
+// only files that changed.
+func (cs *ContextService) DependencyGraph(projectPath string) (*depgraph.Graph, error) {
+	abs, err := filepath.Abs(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cached, ok := cs.graphs[abs]
+	if !ok {
+		graph, err := depgraph.Build(abs)
+		if err != nil {
+			return nil, err
 		}
-	case "python":
-		lines := strings.Split(content, "\n")
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
-			if strings.HasPrefix(line, "import ") || strings.HasPrefix(line, "from ") {
-				imports = append(imports, line)
-			}
+		cached = &projectGraph{graph: graph, refreshedAt: time.Now()}
+		cs.graphs[abs] = cached
+	} else if time.Since(cached.refreshedAt) > graphRefreshInterval {
+		if err := cached.graph.Refresh(); err != nil {
+			return nil, err
 		}
+		cached.refreshedAt = time.Now()
 	}
+	return cached.graph, nil
+}
 
-	return imports
+// GetDependencyFiles returns the project files that files import, up to depth
+// levels and maxFiles files, excluding files themselves.
+func (cs *ContextService) GetDependencyFiles(projectPath string, files []string, depth, maxFiles int) ([]string, error) {
+	graph, err := cs.DependencyGraph(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	deps := graph.ImportsOfFiles(files, depth)
+	if maxFiles > 0 && len(deps) > maxFiles {
+		deps = deps[:maxFiles]
+	}
+	return deps, nil
+}
+
+// siblingCodeFiles 返回同一目录下的其他代码文件
+func siblingCodeFiles(filePath string) ([]string, error) {
+	dir := filepath.Dir(filePath)
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var siblings []string
+	for _, file := range files {
+		fullPath := filepath.Join(dir, file.Name())
+		if file.IsDir() || fullPath == filePath || !isCodeFile(strings.ToLower(filepath.Ext(file.Name()))) {
+			continue
+		}
+		siblings = append(siblings, fullPath)
+	}
+	return siblings, nil
 }
 
 func getLanguageFromExt(ext string) string {
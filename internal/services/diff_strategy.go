@@ -0,0 +1,24 @@
+package services
+
+import (
+	"mind-weaver/config"
+	"mind-weaver/internal/third/diff"
+	"mind-weaver/internal/third/diff/multireplace"
+	"mind-weaver/internal/third/diff/unified"
+)
+
+// apply_diff 支持的修改格式
+const (
+	DiffStrategyMultiReplace = "multireplace" // SEARCH/REPLACE 块
+	DiffStrategyUnified      = "unified"      // git 风格的统一diff
+)
+
+// NewDiffStrategy 根据配置创建模型使用的 apply_diff 修改策略，未知的格式使用 SEARCH/REPLACE
+func NewDiffStrategy(cfg config.Config, model string) diff.DiffStrategy {
+	threshold := cfg.ApplyDiff.GetFuzzyThreshold()
+	bufferLines := cfg.ApplyDiff.GetBufferLines()
+	if cfg.ApplyDiff.GetStrategy(cfg.LLM.Models, model) == DiffStrategyUnified {
+		return unified.NewUnifiedDiffStrategy(&threshold, &bufferLines)
+	}
+	return multireplace.NewMultiSearchReplaceDiffStrategy(&threshold, &bufferLines)
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"sync"
+)
+
+// StreamEvent is one buffered SSE frame, Data already contains the "data: ...\n\n" lines.
+type StreamEvent struct {
+	ID   int64
+	Data string
+}
+
+// EventStream 缓存一次回复的所有SSE事件，并为每个事件分配递增的ID，
+// 客户端断线重连时可以根据 Last-Event-ID 补发错过的事件
+type EventStream struct {
+	mu     sync.Mutex
+	events []StreamEvent
+	nextID int64
+	closed bool
+	notify chan struct{}
+}
+
+func NewEventStream() *EventStream {
+	return &EventStream{
+		nextID: 1,
+		notify: make(chan struct{}),
+	}
+}
+
+// Write stores p as a single event and wakes up all subscribers.
+func (s *EventStream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return len(p), nil
+	}
+	s.events = append(s.events, StreamEvent{ID: s.nextID, Data: string(p)})
+	s.nextID++
+	s.broadcast()
+	return len(p), nil
+}
+
+// Flush is a no-op, subscribers are notified on every Write.
+func (s *EventStream) Flush() {}
+
+// Close marks the stream as finished, no more events will be added.
+func (s *EventStream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.closed {
+		s.closed = true
+		s.broadcast()
+	}
+}
+
+// EventsAfter returns the events with an ID greater than lastID, whether the stream
+// is closed, and a channel that is closed when new events arrive.
+func (s *EventStream) EventsAfter(lastID int64) ([]StreamEvent, bool, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// ID 从1开始连续递增，可以直接计算下标
+	start := int(lastID)
+	if start < 0 {
+		start = 0
+	}
+	var events []StreamEvent
+	if start < len(s.events) {
+		events = append(events, s.events[start:]...)
+	}
+	return events, s.closed, s.notify
+}
+
+// broadcast must be called with s.mu held.
+func (s *EventStream) broadcast() {
+	close(s.notify)
+	s.notify = make(chan struct{})
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventStreamReplay(t *testing.T) {
+	stream := NewEventStream()
+	for _, data := range []string{"a", "b", "c"} {
+		stream.Write([]byte("data: " + data + "\n\n"))
+	}
+
+	tests := []struct {
+		lastID  int64
+		wantIDs []int64
+	}{
+		{lastID: 0, wantIDs: []int64{1, 2, 3}},
+		{lastID: 2, wantIDs: []int64{3}},
+		{lastID: 3, wantIDs: nil},
+		{lastID: 10, wantIDs: nil},
+	}
+
+	for _, tt := range tests {
+		events, closed, _ := stream.EventsAfter(tt.lastID)
+		if closed {
+			t.Errorf("Expected open stream")
+		}
+		if len(events) != len(tt.wantIDs) {
+			t.Errorf("lastID %d: Expected %d events, got %d", tt.lastID, len(tt.wantIDs), len(events))
+			continue
+		}
+		for i, event := range events {
+			if event.ID != tt.wantIDs[i] {
+				t.Errorf("lastID %d: Expected event ID %d, got %d", tt.lastID, tt.wantIDs[i], event.ID)
+			}
+		}
+	}
+}
+
+func TestEventStreamNotify(t *testing.T) {
+	stream := NewEventStream()
+	_, _, wait := stream.EventsAfter(0)
+
+	go func() {
+		stream.Write([]byte("data: x\n\n"))
+		stream.Close()
+	}()
+
+	select {
+	case <-wait:
+	case <-time.After(time.Second):
+		t.Fatal("Expected subscriber to be notified")
+	}
+
+	// 关闭后仍然可以读到之前的事件
+	time.Sleep(10 * time.Millisecond)
+	events, closed, _ := stream.EventsAfter(0)
+	if len(events) != 1 || !closed {
+		t.Errorf("Expected 1 event on a closed stream, got %d events, closed %v", len(events), closed)
+	}
+}
+
+func TestCompletionRegistryStop(t *testing.T) {
+	registry := NewCompletionRegistry()
+	ctx, completion, release := registry.Start(1, 10)
+	defer release()
+
+	if found := registry.Find(1, 0); found != completion {
+		t.Fatalf("Expected to find the running completion")
+	}
+	if stopped := registry.Stop(2, 0); len(stopped) != 0 {
+		t.Errorf("Expected no completion of another session to be stopped, got %d", len(stopped))
+	}
+	if stopped := registry.Stop(1, 10); len(stopped) != 1 {
+		t.Fatalf("Expected 1 stopped completion, got %d", len(stopped))
+	}
+	if !IsStopped(ctx) {
+		t.Errorf("Expected context to be cancelled with ErrCompletionStopped, got %v", ctx.Err())
+	}
+}
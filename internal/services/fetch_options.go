@@ -0,0 +1,15 @@
+package services
+
+import (
+	"mind-weaver/config"
+	"mind-weaver/internal/third/tools"
+)
+
+// NewFetchOptions 根据配置创建 fetch_url 工具的设置
+func NewFetchOptions(cfg config.Config) *tools.FetchOptions {
+	return &tools.FetchOptions{
+		AllowedDomains: cfg.FetchURL.AllowedDomains,
+		MaxTokens:      cfg.FetchURL.GetMaxTokens(),
+		Timeout:        cfg.FetchURL.GetTimeout(),
+	}
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"mind-weaver/config"
+	"mind-weaver/internal/db"
+	"mind-weaver/internal/llm"
+	"mind-weaver/internal/tokenizer"
+	"mind-weaver/pkg/logger"
+	"mind-weaver/pkg/prompts"
+)
+
+// 每条消息的角色、分隔符等格式开销，按token估算
+const messageOverheadTokens = 4
+
+// 总结时每条消息最多带入的token数
+const summaryMessageTokens = 2000
+
+// 缩短后的工具结果末尾的说明
+const shortenedToolResultNote = "\n\n[Earlier tool result shortened to save context.]"
+
+// 摘要消息的开头，作为系统消息插入在最近的消息之前
+const summaryMessagePrefix = "Summary of the earlier conversation (older messages were removed to fit the context window):\n\n"
+
+// HistoryService 在历史消息接近模型上下文上限时压缩历史：保留系统提示词和最近的消息，
+// 缩短较早的工具结果，再把较早的对话总结成摘要。摘要保存在数据库中，之后的请求直接复用
+type HistoryService struct {
+	database     *db.Database
+	aiService    *AIService
+	usageService *UsageService
+	cfg          config.History
+
+	// summarize 请求模型生成摘要，测试中可以替换
+	summarize func(ctx context.Context, prompt, modelName string) (string, *llm.Usage, error)
+}
+
+func NewHistoryService(database *db.Database, cfg *config.Config, aiService *AIService, usageService *UsageService) *HistoryService {
+	s := &HistoryService{
+		database:     database,
+		aiService:    aiService,
+		usageService: usageService,
+		cfg:          cfg.History,
+	}
+	s.summarize = func(ctx context.Context, prompt, modelName string) (string, *llm.Usage, error) {
+		return aiService.Chat(ctx, "", prompt, nil, modelName)
+	}
+	return s
+}
+
+// Fit returns the history to send with sysPrompt and prompt so that the request
+// stays within the context of modelName. The history is returned unchanged when
+// it already fits or the model has no max_context configured.
+func (s *HistoryService) Fit(ctx context.Context, sessionID int64, modelName, sysPrompt, prompt string, history []*Message) []*Message {
+	modelInfo := s.aiService.getModelInfo(modelName)
+	if modelInfo.MaxContext <= 0 || len(history) == 0 {
+		return history
+	}
+	maxTokens := modelInfo.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = s.aiService.maxTokens
+	}
+	tk := tokenizer.ForModel(modelInfo.Name, modelInfo.Tokenizer)
+	budget := modelInfo.MaxContext - maxTokens - countMessageTokens(tk, sysPrompt) - countMessageTokens(tk, prompt)
+	if countHistoryTokens(tk, history) <= budget {
+		return history
+	}
+
+	// 系统消息始终保留，其余消息分为较早的和最近的两部分
+	var system, rest []*Message
+	for _, msg := range history {
+		if msg.Role == MsgTypeSystem {
+			system = append(system, msg)
+		} else {
+			rest = append(rest, msg)
+		}
+	}
+	split := recentStart(rest, s.cfg.GetKeepRecentMessages())
+	old, recent := rest[:split], rest[split:]
+	logger.Infof("History of session %d exceeds %d tokens, compacting %d old messages", sessionID, budget, len(old))
+
+	// 1. 缩短较早的工具结果
+	old = s.shortenToolResults(tk, old)
+	compacted := joinMessages(system, nil, old, recent)
+	if countHistoryTokens(tk, compacted) <= budget {
+		return compacted
+	}
+
+	// 2. 用已保存的摘要替换它覆盖的消息，仍然放不下时重新总结
+	summary, err := s.database.GetLatestHistorySummary(sessionID)
+	if err != nil {
+		logger.Errorf("Failed to get history summary of session %d: %v", sessionID, err)
+	}
+	if summary != nil {
+		old = messagesAfter(old, summary.LastMessageID)
+		compacted = joinMessages(system, summary, old, recent)
+		if countHistoryTokens(tk, compacted) <= budget {
+			return compacted
+		}
+	}
+	if len(old) > 0 {
+		if updated, err := s.updateSummary(ctx, sessionID, modelInfo.Name, summary, old); err != nil {
+			logger.Errorf("Failed to summarize history of session %d: %v", sessionID, err)
+		} else {
+			summary, old = updated, nil
+			compacted = joinMessages(system, summary, old, recent)
+		}
+	}
+
+	// 3. 仍然放不下时从最早的消息开始丢弃，至少保留最后一条
+	for countHistoryTokens(tk, compacted) > budget && len(old)+len(recent) > 1 {
+		if len(old) > 0 {
+			old = old[1:]
+		} else {
+			recent = recent[1:]
+		}
+		compacted = joinMessages(system, summary, old, recent)
+	}
+	return compacted
+}
+
+// shortenToolResults 返回缩短了工具结果的消息副本，原消息不会被修改
+func (s *HistoryService) shortenToolResults(tk tokenizer.Tokenizer, messages []*Message) []*Message {
+	limit := s.cfg.GetToolResultTokens()
+	shortened := make([]*Message, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Role == MsgTypeUser && strings.HasSuffix(msg.Content, toolResultSuffix) {
+			if content, truncated := tokenizer.Truncate(tk, msg.Content, limit); truncated {
+				msg = &Message{Id: msg.Id, Role: msg.Role, Content: content + shortenedToolResultNote}
+			}
+		}
+		shortened = append(shortened, msg)
+	}
+	return shortened
+}
+
+// updateSummary 把之前的摘要和新的较早消息合并总结成新的摘要并保存
+func (s *HistoryService) updateSummary(ctx context.Context, sessionID int64, modelName string, previous *db.HistorySummary, messages []*Message) (*db.HistorySummary, error) {
+	var lastID int64
+	for _, msg := range messages {
+		lastID = max(lastID, msg.Id)
+	}
+	if lastID == 0 {
+		return nil, fmt.Errorf("messages to summarize are not saved")
+	}
+
+	summaryModel := s.cfg.SummaryModel
+	if summaryModel == "" {
+		summaryModel = modelName
+	}
+	summaryInfo := s.aiService.getModelInfo(summaryModel)
+	tk := tokenizer.ForModel(summaryInfo.Name, summaryInfo.Tokenizer)
+
+	var conversation strings.Builder
+	for _, msg := range messages {
+		content, _ := tokenizer.Truncate(tk, msg.Content, summaryMessageTokens)
+		fmt.Fprintf(&conversation, "%s:\n%s\n\n", strings.ToUpper(msg.Role), content)
+	}
+	previousSummary := ""
+	if previous != nil {
+		previousSummary = previous.Content
+	}
+	prompt, err := prompts.GetPromptWithParams("history_summary", map[string]string{
+		"previous_summary": previousSummary,
+		"conversation":     strings.TrimSpace(conversation.String()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 总结请求本身也不能超出总结模型的上下文
+	if summaryInfo.MaxContext > 0 {
+		prompt, _ = tokenizer.Truncate(tk, prompt, summaryInfo.MaxContext-summaryInfo.MaxTokens-messageOverheadTokens)
+	}
+
+	content, usage, err := s.summarize(ctx, prompt, summaryModel)
+	if err != nil {
+		return nil, err
+	}
+	s.usageService.RecordUsage(sessionID, 0, summaryModel, usage)
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, fmt.Errorf("summary model %s returned an empty summary", summaryModel)
+	}
+
+	summary := &db.HistorySummary{SessionID: sessionID, LastMessageID: lastID, Content: content, Model: summaryModel}
+	if summary.ID, err = s.database.AddHistorySummary(summary); err != nil {
+		// 保存失败时本次请求仍然可以使用摘要，下次会重新总结
+		logger.Errorf("Failed to save history summary of session %d: %v", sessionID, err)
+	}
+	return summary, nil
+}
+
+// recentStart 返回最近 keep 条消息的起始位置，尽量从用户消息开始，避免回复和它的请求被分开
+func recentStart(messages []*Message, keep int) int {
+	start := max(len(messages)-keep, 0)
+	for start > 0 && messages[start].Role != MsgTypeUser {
+		start--
+	}
+	return start
+}
+
+// messagesAfter 去掉摘要已经覆盖的消息，没有保存过的消息(Id为0)不会被去掉
+func messagesAfter(messages []*Message, lastID int64) []*Message {
+	var after []*Message
+	for _, msg := range messages {
+		if msg.Id == 0 || msg.Id > lastID {
+			after = append(after, msg)
+		}
+	}
+	return after
+}
+
+func joinMessages(system []*Message, summary *db.HistorySummary, old, recent []*Message) []*Message {
+	messages := make([]*Message, 0, len(system)+1+len(old)+len(recent))
+	messages = append(messages, system...)
+	if summary != nil {
+		messages = append(messages, &Message{Role: MsgTypeSystem, Content: summaryMessagePrefix + summary.Content})
+	}
+	messages = append(messages, old...)
+	return append(messages, recent...)
+}
+
+func countMessageTokens(tk tokenizer.Tokenizer, content string) int {
+	if content == "" {
+		return 0
+	}
+	return tk.Count(content) + messageOverheadTokens
+}
+
+func countHistoryTokens(tk tokenizer.Tokenizer, messages []*Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += countMessageTokens(tk, msg.Content)
+	}
+	return total
+}
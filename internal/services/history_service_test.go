@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mind-weaver/config"
+	"mind-weaver/internal/db"
+	"mind-weaver/internal/llm"
+	"mind-weaver/pkg/logger"
+)
+
+func TestHistoryFit(t *testing.T) {
+	// 提示词从 ./pkg/prompts/content 读取，需要在仓库根目录下运行
+	wd, _ := os.Getwd()
+	if err := os.Chdir("../.."); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	dir := t.TempDir()
+	logger.Setup(config.Logger{Filename: filepath.Join(dir, "test.log")})
+	database, err := db.InitDB(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	projectID, err := database.CreateProject("demo", dir, "go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionID, err := database.CreateSession(projectID, "demo", "auto", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{}
+	cfg.LLM.Models = []config.ModelInfo{{Name: "small", MaxContext: 3000, MaxTokens: 500}}
+	cfg.History = config.History{SummaryModel: "cheap", KeepRecentMessages: 2, ToolResultTokens: 50}
+	aiService, err := NewAIService(database, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewHistoryService(database, cfg, aiService, NewUsageService(database, cfg))
+	var summaryPrompts []string
+	service.summarize = func(ctx context.Context, prompt, modelName string) (string, *llm.Usage, error) {
+		if modelName != "cheap" {
+			t.Errorf("Expected the summary model, got %s", modelName)
+		}
+		summaryPrompts = append(summaryPrompts, prompt)
+		return "The user is building a CLI.", nil, nil
+	}
+
+	history := []*Message{{Role: MsgTypeSystem, Content: "You are a coding assistant."}}
+	addTurn := func(request, reply string) {
+		for _, msg := range []*Message{{Role: MsgTypeUser, Content: request}, {Role: MsgTypeAssistant, Content: reply}} {
+			msg.Id, err = database.AddMessage(sessionID, msg.Role, msg.Content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			history = append(history, msg)
+		}
+	}
+
+	// 能放下时不做任何修改
+	addTurn("Build a CLI", "<read_file><path>main.go</path></read_file>")
+	if fitted := service.Fit(context.Background(), sessionID, "small", "", "next", history); len(fitted) != len(history) {
+		t.Fatalf("Expected history to be unchanged, got %d messages", len(fitted))
+	}
+
+	// 较早的工具结果被缩短后能放下时不需要总结
+	bigResult := strings.Repeat("line of file content\n", 600) + toolResultSuffix
+	addTurn(bigResult, "<read_file><path>util.go</path></read_file>")
+	addTurn("util.go content"+toolResultSuffix, "Done.")
+	fitted := service.Fit(context.Background(), sessionID, "small", "", "next", history)
+	if len(fitted) != len(history) || len(summaryPrompts) != 0 {
+		t.Fatalf("Expected only tool results to be shortened, got %d messages and %d summaries", len(fitted), len(summaryPrompts))
+	}
+	if !strings.HasSuffix(fitted[3].Content, shortenedToolResultNote) || history[3].Content != bigResult {
+		t.Errorf("Expected a shortened copy of the old tool result, got %q", fitted[3].Content)
+	}
+
+	// 仍然放不下时总结较早的消息，只保留系统消息、摘要和最近的消息
+	for i := 0; i < 8; i++ {
+		addTurn(strings.Repeat("please keep going with the next step ", 40), strings.Repeat("working on it ", 40))
+	}
+	fitted = service.Fit(context.Background(), sessionID, "small", "", "next", history)
+	if len(summaryPrompts) != 1 || !strings.Contains(summaryPrompts[0], "Build a CLI") {
+		t.Fatalf("Expected one summary of the old messages, got %d", len(summaryPrompts))
+	}
+	if len(fitted) != 4 || fitted[0] != history[0] || !strings.HasPrefix(fitted[1].Content, summaryMessagePrefix) {
+		t.Fatalf("Expected system, summary and 2 recent messages, got %d messages", len(fitted))
+	}
+	if fitted[2] != history[len(history)-2] || fitted[3] != history[len(history)-1] {
+		t.Errorf("Expected the recent messages to be kept")
+	}
+	stored, err := database.GetLatestHistorySummary(sessionID)
+	if err != nil || stored == nil || stored.LastMessageID != history[len(history)-3].Id {
+		t.Fatalf("Expected the summary to be stored up to the last old message, got %+v, %v", stored, err)
+	}
+
+	// 下一次请求复用保存的摘要，不再重新总结
+	fitted = service.Fit(context.Background(), sessionID, "small", "", "next", history)
+	if len(summaryPrompts) != 1 || len(fitted) != 4 {
+		t.Errorf("Expected the stored summary to be reused, got %d summaries and %d messages", len(summaryPrompts), len(fitted))
+	}
+}
+
+func TestHistorySummaryDeletedWithMessages(t *testing.T) {
+	dir := t.TempDir()
+	database, err := db.InitDB(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	projectID, err := database.CreateProject("demo", dir, "go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionID, err := database.CreateSession(projectID, "demo", "auto", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for i := 0; i < 4; i++ {
+		id, err := database.AddMessage(sessionID, MsgTypeUser, "message")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	addSummary := func() {
+		t.Helper()
+		if _, err := database.AddHistorySummary(&db.HistorySummary{SessionID: sessionID, LastMessageID: ids[1], Content: "summary"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	latestSummary := func() *db.HistorySummary {
+		t.Helper()
+		summary, err := database.GetLatestHistorySummary(sessionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return summary
+	}
+
+	// 删除摘要之后的消息(例如重试)不影响摘要
+	addSummary()
+	if err := database.DeleteMessage(ids[3]); err != nil {
+		t.Fatal(err)
+	}
+	if latestSummary() == nil {
+		t.Fatal("Expected the summary to be kept after deleting a newer message")
+	}
+
+	// 删除被摘要覆盖的消息后摘要失效
+	if err := database.DeleteMessage(ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	if summary := latestSummary(); summary != nil {
+		t.Fatalf("Expected the summary to be deleted with a covered message, got %+v", summary)
+	}
+
+	// 清空对话和删除会话时一起删除
+	addSummary()
+	if err := database.DeleteAllMessage(sessionID); err != nil {
+		t.Fatal(err)
+	}
+	if summary := latestSummary(); summary != nil {
+		t.Fatalf("Expected the summary to be deleted with all messages, got %+v", summary)
+	}
+	addSummary()
+	if err := database.DeleteSession(sessionID); err != nil {
+		t.Fatal(err)
+	}
+	if summary := latestSummary(); summary != nil {
+		t.Errorf("Expected the summary to be deleted with the session, got %+v", summary)
+	}
+}
@@ -0,0 +1,32 @@
+package services
+
+import (
+	"mind-weaver/config"
+	"mind-weaver/internal/third/mcp"
+	"mind-weaver/pkg/logger"
+)
+
+// NewMcpHub 根据配置创建 MCP 服务器集合，跳过停用和重名的服务器
+func NewMcpHub(cfg *config.Config) *mcp.Hub {
+	var servers []mcp.ServerConfig
+	seen := map[string]bool{}
+	for _, server := range cfg.MCP.Servers {
+		if server.Disabled {
+			continue
+		}
+		if server.Name == "" || seen[server.Name] {
+			logger.Errorf("Skipping MCP server with empty or duplicate name %q", server.Name)
+			continue
+		}
+		seen[server.Name] = true
+		servers = append(servers, mcp.ServerConfig{
+			Name:    server.Name,
+			Command: server.Command,
+			Args:    server.Args,
+			Env:     server.Env,
+			Dir:     server.Dir,
+			Timeout: server.GetTimeout(),
+		})
+	}
+	return mcp.NewHub(servers)
+}
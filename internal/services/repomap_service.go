@@ -0,0 +1,79 @@
+package services
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	"mind-weaver/config"
+	"mind-weaver/internal/repomap"
+	"mind-weaver/internal/tokenizer"
+	"mind-weaver/pkg/logger"
+)
+
+// 仓库地图两次刷新之间的最短间隔
+const repoMapRefreshInterval = 5 * time.Second
+
+// RepoMapService 缓存每个项目的仓库地图，只重新解析修改过的文件
+type RepoMapService struct {
+	cfg config.RepoMap
+
+	mu   sync.Mutex
+	maps map[string]*projectRepoMap // 项目绝对路径 -> 仓库地图
+}
+
+type projectRepoMap struct {
+	mu          sync.Mutex
+	repoMap     *repomap.Map
+	refreshedAt time.Time
+}
+
+func NewRepoMapService(cfg *config.Config) *RepoMapService {
+	return &RepoMapService{cfg: cfg.RepoMap, maps: map[string]*projectRepoMap{}}
+}
+
+// Enabled reports whether the repository map is added to the first turn.
+func (s *RepoMapService) Enabled() bool {
+	return s.cfg.Enabled
+}
+
+// Generate returns the repository map of a project within maxTokens tokens.
+// Files and identifiers mentioned in request are ranked higher. maxTokens <= 0
+// uses the configured budget.
+func (s *RepoMapService) Generate(projectPath string, tk tokenizer.Tokenizer, maxTokens int, request string) (string, error) {
+	m, err := s.get(projectPath)
+	if err != nil {
+		return "", err
+	}
+	if maxTokens <= 0 {
+		maxTokens = s.cfg.GetMaxTokens()
+	}
+	return m.Render(repomap.Options{MaxTokens: maxTokens, Tokenizer: tk, Request: request}), nil
+}
+
+// get 返回刷新过的仓库地图，同一个项目的解析不会同时进行
+func (s *RepoMapService) get(projectPath string) (*repomap.Map, error) {
+	abs, err := filepath.Abs(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	cached, ok := s.maps[abs]
+	if !ok {
+		cached = &projectRepoMap{repoMap: repomap.New(abs, s.cfg.GetMaxFiles())}
+		s.maps[abs] = cached
+	}
+	s.mu.Unlock()
+
+	cached.mu.Lock()
+	defer cached.mu.Unlock()
+	if time.Since(cached.refreshedAt) > repoMapRefreshInterval {
+		start := time.Now()
+		if err := cached.repoMap.Refresh(); err != nil {
+			return nil, err
+		}
+		cached.refreshedAt = time.Now()
+		logger.Infof("Refreshed repository map of %s in %v", abs, time.Since(start))
+	}
+	return cached.repoMap, nil
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -23,6 +24,7 @@ type SessionService struct {
 	fileService    *FileService
 	contextService *ContextService
 	aiService      *AIService
+	usageService   *UsageService
 }
 
 type SessionInfo struct {
@@ -42,6 +44,7 @@ type MessageInfo struct {
 	Role      string    `json:"role"`
 	Content   string    `json:"content"`
 	Timestamp time.Time `json:"timestamp"`
+	Status    string    `json:"status,omitempty"` // 被中断的回复为 interrupted，被停止的为 stopped
 }
 
 type ContextInfo struct {
@@ -56,12 +59,14 @@ func NewSessionService(
 	fileService *FileService,
 	contextService *ContextService,
 	aiService *AIService,
+	usageService *UsageService,
 ) *SessionService {
 	return &SessionService{
 		database:       database,
 		fileService:    fileService,
 		contextService: contextService,
 		aiService:      aiService,
+		usageService:   usageService,
 	}
 }
 
@@ -182,6 +187,7 @@ func (s *SessionService) GetSession(sessionID int64) (*SessionInfo, error) {
 			Role:      msg.Role,
 			Content:   content,
 			Timestamp: msg.Timestamp,
+			Status:    msg.Status,
 		}
 	}
 
@@ -263,10 +269,11 @@ func (s *SessionService) AddMessage(sessionID int64, content string, role string
 		Role:      addedMsg.Role,
 		Content:   addedMsg.Content,
 		Timestamp: addedMsg.Timestamp,
+		Status:    addedMsg.Status,
 	}, nil
 }
 
-func (s *SessionService) GenerateAIResponse(sessionID int64, projectPath string, contextFiles []string, userPrompt string) (*MessageInfo, error) {
+func (s *SessionService) GenerateAIResponse(ctx context.Context, sessionID int64, projectPath string, contextFiles []string, userPrompt string) (*MessageInfo, error) {
 	// Get project and session info
 	session, err := s.database.GetSession(sessionID)
 	if err != nil {
@@ -290,7 +297,7 @@ func (s *SessionService) GenerateAIResponse(sessionID int64, projectPath string,
 	}
 
 	// Generate AI response
-	aiResponse, err := s.aiService.GenerateCompletion(userPrompt, fileContexts)
+	aiResponse, usage, err := s.aiService.GenerateCompletion(ctx, userPrompt, fileContexts)
 	if err != nil {
 		return nil, err
 	}
@@ -300,6 +307,7 @@ func (s *SessionService) GenerateAIResponse(sessionID int64, projectPath string,
 	if err != nil {
 		return nil, err
 	}
+	s.usageService.RecordUsage(session.ID, msgID, s.aiService.model, usage)
 
 	// Return the message info
 	return &MessageInfo{
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"mind-weaver/config"
+	"mind-weaver/internal/db"
+	"mind-weaver/internal/llm"
+	"mind-weaver/pkg/logger"
+)
+
+// UsageService 负责记录和统计模型调用的token消耗与费用
+type UsageService struct {
+	database *db.Database
+	cfg      config.Config
+}
+
+// SessionUsage is the usage report of a single session.
+type SessionUsage struct {
+	SessionID int64              `json:"session_id"`
+	Total     *db.UsageSummary   `json:"total"`
+	ByModel   []*db.UsageSummary `json:"by_model"`
+	Messages  []*db.MessageUsage `json:"messages"`
+}
+
+// ProjectUsage is the usage rollup of all sessions in a project.
+type ProjectUsage struct {
+	ProjectID int64              `json:"project_id"`
+	Total     *db.UsageSummary   `json:"total"`
+	ByModel   []*db.UsageSummary `json:"by_model"`
+	BySession []*db.UsageSummary `json:"by_session"`
+}
+
+func NewUsageService(database *db.Database, cfg *config.Config) *UsageService {
+	return &UsageService{
+		database: database,
+		cfg:      *cfg,
+	}
+}
+
+// CalculateCost prices usage with the model's cost_per_token, unknown models cost 0.
+func (s *UsageService) CalculateCost(modelName string, usage *llm.Usage) float64 {
+	if usage == nil {
+		return 0
+	}
+	for _, model := range s.cfg.LLM.Models {
+		if model.Name == modelName {
+			return float64(usage.TotalTokens) * model.CostPerToken
+		}
+	}
+	return 0
+}
+
+// RecordUsage 保存一次模型调用的token消耗，messageID 为对应的assistant消息ID（没有保存消息时为0）
+func (s *UsageService) RecordUsage(sessionID int64, messageID int64, modelName string, usage *llm.Usage) {
+	if usage == nil || usage.TotalTokens == 0 {
+		return
+	}
+
+	_, err := s.database.AddMessageUsage(&db.MessageUsage{
+		SessionID:        sessionID,
+		MessageID:        messageID,
+		Model:            modelName,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+		Cost:             s.CalculateCost(modelName, usage),
+	})
+	if err != nil {
+		logger.Errorf("Failed to record usage for session %d: %v", sessionID, err)
+	}
+}
+
+func (s *UsageService) GetSessionUsage(sessionID int64) (*SessionUsage, error) {
+	total, err := s.sumOne("session_id", sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	byModel, err := s.database.SumUsages("session_id", sessionID, "model")
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := s.database.GetSessionUsages(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SessionUsage{
+		SessionID: sessionID,
+		Total:     total,
+		ByModel:   byModel,
+		Messages:  messages,
+	}, nil
+}
+
+func (s *UsageService) GetProjectUsage(projectID int64) (*ProjectUsage, error) {
+	total, err := s.sumOne("project_id", projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	byModel, err := s.database.SumUsages("project_id", projectID, "model")
+	if err != nil {
+		return nil, err
+	}
+
+	bySession, err := s.database.SumUsages("project_id", projectID, "session_id")
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProjectUsage{
+		ProjectID: projectID,
+		Total:     total,
+		ByModel:   byModel,
+		BySession: bySession,
+	}, nil
+}
+
+func (s *UsageService) sumOne(column string, id int64) (*db.UsageSummary, error) {
+	summaries, err := s.database.SumUsages(column, id, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(summaries) == 0 {
+		return &db.UsageSummary{}, nil
+	}
+	return summaries[0], nil
+}
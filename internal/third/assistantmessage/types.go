@@ -59,12 +59,18 @@ const (
 	ApplyDiff               ToolUseName = "apply_diff"
 	InsertContent           ToolUseName = "insert_content"
 	SearchAndReplace        ToolUseName = "search_and_replace"
+	BatchEdit               ToolUseName = "batch_edit"
+	MoveFile                ToolUseName = "move_file"
+	DeleteFile              ToolUseName = "delete_file"
+	CreateDirectory         ToolUseName = "create_directory"
 	SearchFiles             ToolUseName = "search_files"
 	ListFiles               ToolUseName = "list_files"
 	ListCodeDefinitionNames ToolUseName = "list_code_definition_names"
+	FetchURL                ToolUseName = "fetch_url"
 	BrowserAction           ToolUseName = "browser_action"
 	UseMcpTool              ToolUseName = "use_mcp_tool"
 	AccessMcpResource       ToolUseName = "access_mcp_resource"
+	CodebaseSearch          ToolUseName = "codebase_search"
 	AskFollowupQuestion     ToolUseName = "ask_followup_question"
 	AttemptCompletion       ToolUseName = "attempt_completion"
 	SwitchMode              ToolUseName = "switch_mode"
@@ -103,6 +109,9 @@ const (
 	FollowUp    ToolParamName = "follow_up"
 	Task        ToolParamName = "task"
 	Size        ToolParamName = "size"
+	DryRun      ToolParamName = "dry_run"
+	NewPath     ToolParamName = "new_path"
+	Query       ToolParamName = "query"
 )
 
 // AllToolUseNames returns all tool use names as a slice
@@ -114,12 +123,18 @@ func AllToolUseNames() []ToolUseName {
 		ApplyDiff,
 		InsertContent,
 		SearchAndReplace,
+		BatchEdit,
+		MoveFile,
+		DeleteFile,
+		CreateDirectory,
 		SearchFiles,
 		ListFiles,
 		ListCodeDefinitionNames,
+		FetchURL,
 		BrowserAction,
 		UseMcpTool,
 		AccessMcpResource,
+		CodebaseSearch,
 		AskFollowupQuestion,
 		AttemptCompletion,
 		SwitchMode,
@@ -160,5 +175,8 @@ func AllToolParamNames() []ToolParamName {
 		FollowUp,
 		Task,
 		Size,
+		DryRun,
+		NewPath,
+		Query,
 	}
 }
@@ -19,9 +19,9 @@ var diffBlockRegex = regexp.MustCompile(
 		`(:end_line:\s*(\d+)\s*\n)?` + // Optional end_line (group 4 = number)
 		`(-------\s*\n)?` + // Optional metadata separator (group 5)
 		`([\s\S]*?)(?:\n)?` + // Search content (group 6)
-		`(?:.*?\n=======\s*\n)` + // Separator
+		`(?:^=======\s*\n)` + // Separator, must start a line
 		`([\s\S]*?)(?:\n)?` + // Replace content (group 7)
-		`(?:.*?\n>>>>>>> REPLACE)(?:\n|$)`, // End marker
+		`(?:^>>>>>>> REPLACE)(?:\n|$)`, // End marker, must start a line
 )
 
 // var diffBlockRegex = regexp.MustCompile(
@@ -398,11 +398,25 @@ func (s *MultiSearchReplaceDiffStrategy) ApplyDiff(originalContent, diffContent
 			baseIndent = ""
 		}
 
+		// Keep the indentation of each replacement line relative to the first SEARCH line,
+		// re-based onto the indentation of the matched content
+		searchBaseIndent := ""
+		if len(searchLines) > 0 {
+			searchBaseIndent = GetIndent(searchLines[0])
+		} else if len(replaceLines) > 0 {
+			searchBaseIndent = GetIndent(replaceLines[0])
+		}
 		indentedReplaceLines := make([]string, len(replaceLines))
 		for i, line := range replaceLines {
-			// Basic indentation: apply the base indent to all replacement lines
-			// More complex logic needed to handle relative indents within the replace block itself
-			indentedReplaceLines[i] = baseIndent + strings.TrimLeft(line, " \t") // Apply base, remove original leading whitespace
+			currentIndent := GetIndent(line)
+			relativeLevel := len(currentIndent) - len(searchBaseIndent)
+			finalIndent := baseIndent
+			if relativeLevel < 0 {
+				finalIndent = baseIndent[:max(0, len(baseIndent)+relativeLevel)]
+			} else {
+				finalIndent = baseIndent + currentIndent[len(searchBaseIndent):]
+			}
+			indentedReplaceLines[i] = finalIndent + strings.TrimLeft(line, " \t")
 		}
 
 		// Reconstruct resultLines
@@ -0,0 +1,53 @@
+package multireplace
+
+import "testing"
+
+func TestApplyDiff(t *testing.T) {
+	const original = "package main\n\nfunc add(a, b int) int {\n\treturn a + b\n}\n"
+
+	tests := []struct {
+		name        string
+		diff        string
+		wantSuccess bool
+		want        string
+	}{
+		{
+			name:        "single line block",
+			diff:        "<<<<<<< SEARCH\n-------\n\treturn a + b\n=======\n\treturn b + a\n>>>>>>> REPLACE",
+			wantSuccess: true,
+			want:        "package main\n\nfunc add(a, b int) int {\n\treturn b + a\n}\n",
+		},
+		{
+			name:        "multi line block with line hints",
+			diff:        "<<<<<<< SEARCH\n:start_line:3\n:end_line:4\n-------\nfunc add(a, b int) int {\n\treturn a + b\n=======\nfunc sum(a, b int) int {\n\treturn a + b\n>>>>>>> REPLACE",
+			wantSuccess: true,
+			want:        "package main\n\nfunc sum(a, b int) int {\n\treturn a + b\n}\n",
+		},
+		{
+			name:        "block without separator line",
+			diff:        "<<<<<<< SEARCH\npackage main\n=======\npackage app\n>>>>>>> REPLACE\n",
+			wantSuccess: true,
+			want:        "package app\n\nfunc add(a, b int) int {\n\treturn a + b\n}\n",
+		},
+		{
+			name:        "search content not found",
+			diff:        "<<<<<<< SEARCH\n-------\n\treturn a * b\n=======\n\treturn b + a\n>>>>>>> REPLACE",
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewMultiSearchReplaceDiffStrategy(nil, nil).ApplyDiff(original, tt.diff, 0, 0)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if result.Success != tt.wantSuccess {
+				t.Fatalf("Expected success %v, got %v (%s)", tt.wantSuccess, result.Success, result.Error)
+			}
+			if tt.wantSuccess && result.Content != tt.want {
+				t.Errorf("Expected content %q, got %q", tt.want, result.Content)
+			}
+		})
+	}
+}
@@ -0,0 +1,341 @@
+package unified
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/adrg/strutil"
+	"github.com/adrg/strutil/metrics"
+
+	"mind-weaver/internal/third/diff/multireplace"
+)
+
+// hunkHeaderRegex matches "@@ -12,5 +12,7 @@", the counts are optional. Models
+// sometimes emit a bare "@@ @@", in which case the hunk has no line hint.
+var hunkHeaderRegex = regexp.MustCompile(`^@@\s*(?:-(\d+)(?:,(\d+))?\s+\+(\d+)(?:,(\d+))?)?\s*@@`)
+
+var whitespaceRegex = regexp.MustCompile(`\s+`)
+
+const defaultBufferLines = 40
+
+// UnifiedDiffStrategy applies standard unified diffs (git style patches). Hunks are
+// located by their context, tolerating line offsets and, depending on the fuzzy
+// threshold, small differences in the context lines.
+type UnifiedDiffStrategy struct {
+	fuzzyThreshold float64
+	bufferLines    int
+	levenshtein    *metrics.Levenshtein
+}
+
+func NewUnifiedDiffStrategy(fuzzyThreshold *float64, bufferLines *int) *UnifiedDiffStrategy {
+	threshold := 1.0
+	if fuzzyThreshold != nil {
+		threshold = *fuzzyThreshold
+	}
+	bufLines := defaultBufferLines
+	if bufferLines != nil {
+		bufLines = *bufferLines
+	}
+	return &UnifiedDiffStrategy{
+		fuzzyThreshold: threshold,
+		bufferLines:    bufLines,
+		levenshtein:    metrics.NewLevenshtein(),
+	}
+}
+
+func (s *UnifiedDiffStrategy) GetName() string {
+	return "Unified"
+}
+
+func (s *UnifiedDiffStrategy) GetToolDescription(args multireplace.ToolDescriptionArgs) string {
+	return fmt.Sprintf(`## apply_diff
+Description: Request to apply a unified diff (the format produced by `+"`diff -u`"+` or `+"`git diff`"+`) to an existing file.
+Each hunk starts with a header like `+"`@@ -start,count +start,count @@`"+`, followed by lines prefixed with a space (unchanged context), '-' (removed) or '+' (added).
+Hunks are located by their context lines, so the line numbers in the header only need to be approximately right, but the context and removed lines must match the file content including indentation.
+Include 2-3 lines of unchanged context around every change so the hunk can be located unambiguously.
+If you're not confident in the exact content of the file, use the read_file tool first.
+ALWAYS make as many changes in a single 'apply_diff' request as possible using multiple hunks.
+
+Parameters:
+- path: (required) The path of the file to modify (relative to the current workspace directory %s)
+- diff: (required) The unified diff. The '---'/'+++' file headers are optional.
+
+Example:
+
+Original file:
+`+"```"+`
+1 | def calculate_total(items):
+2 |     total = 0
+3 |     for item in items:
+4 |         total += item
+5 |     return total
+`+"```"+`
+
+Diff:
+`+"```"+`
+--- a/calc.py
++++ b/calc.py
+@@ -1,5 +1,5 @@
+-def calculate_total(items):
++def calculate_sum(items):
+     total = 0
+     for item in items:
+-        total += item
++        total += item * 1.1
+     return total
+`+"```"+`
+
+Usage:
+<apply_diff>
+<path>File path here</path>
+<diff>
+Your unified diff here
+</diff>
+</apply_diff>`, args.Cwd)
+}
+
+// hunk is one "@@ ... @@" section of a unified diff.
+type hunk struct {
+	Header   string
+	OldStart int      // 1-based line number from the header, 0 if not given
+	Lines    []string // hunk body, each line keeps its ' ', '-' or '+' prefix
+}
+
+// oldLines returns the lines the hunk expects in the original file (context and removed).
+func (h hunk) oldLines() []string {
+	lines := []string{}
+	for _, line := range h.Lines {
+		if line[0] == ' ' || line[0] == '-' {
+			lines = append(lines, line[1:])
+		}
+	}
+	return lines
+}
+
+// parseHunks extracts the hunks of a unified diff, skipping file headers and code fences.
+func parseHunks(diffContent string) []hunk {
+	lines := strings.Split(strings.ReplaceAll(diffContent, "\r\n", "\n"), "\n")
+	hunks := []hunk{}
+	var current *hunk
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		// 末尾的空行通常是多余的换行，不作为上下文
+		for len(current.Lines) > 0 && current.Lines[len(current.Lines)-1] == " " {
+			current.Lines = current.Lines[:len(current.Lines)-1]
+		}
+		if len(current.Lines) > 0 {
+			hunks = append(hunks, *current)
+		}
+		current = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if match := hunkHeaderRegex.FindStringSubmatch(line); match != nil {
+			flush()
+			oldStart, _ := strconv.Atoi(match[1])
+			current = &hunk{Header: strings.TrimSpace(line), OldStart: oldStart}
+			continue
+		}
+		// "--- a/x" 紧跟 "+++ b/x" 是文件头而不是删除行
+		if strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") {
+			flush()
+			i++
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		switch {
+		case line == "":
+			// 模型经常去掉空上下文行前面的空格
+			current.Lines = append(current.Lines, " ")
+		case line[0] == ' ' || line[0] == '-' || line[0] == '+':
+			current.Lines = append(current.Lines, line)
+		case strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file"
+		default:
+			// diff --git、index、``` 等其他内容结束当前 hunk
+			flush()
+		}
+	}
+	flush()
+
+	return hunks
+}
+
+func (s *UnifiedDiffStrategy) ApplyDiff(originalContent, diffContent string, paramStartLine, paramEndLine int) (*multireplace.DiffResult, error) {
+	hunks := parseHunks(diffContent)
+	if len(hunks) == 0 {
+		return &multireplace.DiffResult{Success: false, Error: "Invalid diff format - no valid unified diff hunks (starting with @@) found"}, nil
+	}
+
+	// hunk 按原文件的位置依次应用，没有行号的保持原顺序
+	sort.SliceStable(hunks, func(i, j int) bool {
+		if hunks[i].OldStart == 0 || hunks[j].OldStart == 0 {
+			return false
+		}
+		return hunks[i].OldStart < hunks[j].OldStart
+	})
+
+	lineEnding := "\n"
+	if strings.Contains(originalContent, "\r\n") {
+		lineEnding = "\r\n"
+	}
+	resultLines := strings.Split(originalContent, lineEnding)
+	if originalContent == "" {
+		resultLines = []string{}
+	}
+	delta := 0 // 之前的 hunk 造成的行号偏移
+	appliedCount := 0
+	var failedParts []*multireplace.DiffResult
+
+	for i, h := range hunks {
+		oldLines := h.oldLines()
+		expected := -1
+		if h.OldStart > 0 {
+			expected = h.OldStart - 1 + delta
+		}
+
+		var matchIndex int
+		if len(oldLines) == 0 {
+			// 纯插入："@@ -N,0 +M,k @@" 表示在第N行之后插入
+			if h.OldStart == 0 && len(resultLines) > 0 {
+				failedParts = append(failedParts, &multireplace.DiffResult{
+					Success: false,
+					Error:   fmt.Sprintf("Hunk #%d (%s) has no context lines and no line number, unable to locate it", i+1, h.Header),
+				})
+				continue
+			}
+			matchIndex = min(max(h.OldStart+delta, 0), len(resultLines))
+		} else {
+			index, score, bestMatch := s.findMatch(resultLines, oldLines, expected)
+			if index == -1 {
+				location := ""
+				if expected >= 0 {
+					location = fmt.Sprintf(" near line %d", expected+1)
+				}
+				failedParts = append(failedParts, &multireplace.DiffResult{
+					Success: false,
+					Error: fmt.Sprintf("Hunk #%d (%s): no sufficiently similar context found%s (%.0f%% similar, needs %.0f%%)",
+						i+1, h.Header, location, score*100, s.fuzzyThreshold*100),
+					Details: &multireplace.Details{
+						Similarity:    score,
+						Threshold:     s.fuzzyThreshold,
+						SearchContent: strings.Join(oldLines, "\n"),
+						BestMatch:     bestMatch,
+					},
+				})
+				continue
+			}
+			matchIndex = index
+		}
+
+		// 上下文行保留文件中的原样内容，只替换删除和新增的行
+		newLines := []string{}
+		fileIndex := matchIndex
+		for _, line := range h.Lines {
+			switch line[0] {
+			case ' ':
+				newLines = append(newLines, resultLines[fileIndex])
+				fileIndex++
+			case '-':
+				fileIndex++
+			case '+':
+				newLines = append(newLines, line[1:])
+			}
+		}
+
+		updated := make([]string, 0, len(resultLines)+len(newLines)-len(oldLines))
+		updated = append(updated, resultLines[:matchIndex]...)
+		updated = append(updated, newLines...)
+		updated = append(updated, resultLines[matchIndex+len(oldLines):]...)
+		resultLines = updated
+		delta += len(newLines) - len(oldLines)
+		appliedCount++
+	}
+
+	if appliedCount == 0 && len(failedParts) > 0 {
+		return &multireplace.DiffResult{Success: false, FailParts: failedParts, Error: "No diff hunks could be applied."}, nil
+	}
+
+	return &multireplace.DiffResult{Success: true, Content: strings.Join(resultLines, lineEnding), FailParts: failedParts}, nil
+}
+
+// findMatch locates oldLines in lines. An exact match (ignoring trailing whitespace)
+// anywhere in the file is preferred, the one closest to the expected index wins.
+// Otherwise the most similar window within bufferLines of the expected index (or the
+// whole file without a hint) is used if it reaches the fuzzy threshold.
+func (s *UnifiedDiffStrategy) findMatch(lines, oldLines []string, expected int) (int, float64, string) {
+	last := len(lines) - len(oldLines)
+	if last < 0 {
+		return -1, 0, ""
+	}
+
+	exact := -1
+	for i := 0; i <= last; i++ {
+		if !linesEqual(lines[i:i+len(oldLines)], oldLines) {
+			continue
+		}
+		if exact == -1 || expected >= 0 && abs(i-expected) < abs(exact-expected) {
+			exact = i
+		}
+	}
+	if exact != -1 {
+		return exact, 1.0, ""
+	}
+
+	start, end := 0, last
+	if expected >= 0 {
+		start = max(0, expected-s.bufferLines)
+		end = min(last, expected+s.bufferLines)
+	}
+
+	search := normalize(strings.Join(oldLines, "\n"))
+	bestIndex, bestScore := -1, 0.0
+	for i := start; i <= end; i++ {
+		score := 1.0
+		candidate := normalize(strings.Join(lines[i:i+len(oldLines)], "\n"))
+		if candidate != search {
+			score = strutil.Similarity(candidate, search, s.levenshtein)
+		}
+		if score > bestScore || score == bestScore && expected >= 0 && bestIndex >= 0 && abs(i-expected) < abs(bestIndex-expected) {
+			bestIndex, bestScore = i, score
+		}
+	}
+
+	if bestIndex == -1 {
+		return -1, 0, ""
+	}
+	if bestScore < s.fuzzyThreshold {
+		return -1, bestScore, strings.Join(lines[bestIndex:bestIndex+len(oldLines)], "\n")
+	}
+	return bestIndex, bestScore, ""
+}
+
+func linesEqual(a, b []string) bool {
+	for i := range a {
+		if strings.TrimRight(a[i], " \t\r") != strings.TrimRight(b[i], " \t\r") {
+			return false
+		}
+	}
+	return true
+}
+
+func normalize(str string) string {
+	return strings.TrimSpace(whitespaceRegex.ReplaceAllString(str, " "))
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,127 @@
+package unified
+
+import (
+	"strings"
+	"testing"
+)
+
+const original = `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println("hello")
+}
+
+func add(a, b int) int {
+	return a + b
+}
+`
+
+func TestApplyDiff(t *testing.T) {
+	threshold := 0.8
+	tests := []struct {
+		name        string
+		threshold   *float64
+		diff        string
+		wantSuccess bool
+		wantContent string
+		wantFails   int
+	}{
+		{
+			name: "exact hunk with file headers",
+			diff: `--- a/main.go
++++ b/main.go
+@@ -5,3 +5,3 @@
+ func main() {
+-	fmt.Println("hello")
++	fmt.Println("hello, world")
+ }`,
+			wantSuccess: true,
+			wantContent: strings.Replace(original, `"hello"`, `"hello, world"`, 1),
+		},
+		{
+			name: "wrong line numbers are tolerated",
+			diff: `@@ -1,3 +1,4 @@
+ func add(a, b int) int {
++	// add returns the sum
+ 	return a + b
+ }`,
+			wantSuccess: true,
+			wantContent: strings.Replace(original, "int {\n\treturn", "int {\n\t// add returns the sum\n\treturn", 1),
+		},
+		{
+			name: "multiple hunks apply with offset",
+			diff: "```diff\n" + `@@ -3,1 +3,4 @@
+-import "fmt"
++import (
++	"fmt"
++	"os"
++)
+@@ -10,1 +10,1 @@
+-	return a + b
++	return a - b
+` + "```",
+			wantSuccess: true,
+			wantContent: strings.Replace(strings.Replace(original, `import "fmt"`, "import (\n\t\"fmt\"\n\t\"os\"\n)", 1), "a + b", "a - b", 1),
+		},
+		{
+			name: "fuzzy context below threshold",
+			diff: `@@ -5,3 +5,3 @@
+ func main() {
+-	fmt.Println("helo")
++	fmt.Println("bye")
+ }`,
+			wantSuccess: false,
+			wantFails:   1,
+		},
+		{
+			name:      "fuzzy context above threshold",
+			threshold: &threshold,
+			diff: `@@ -5,3 +5,3 @@
+ func main() {
+-	fmt.Println("helo")
++	fmt.Println("bye")
+ }`,
+			wantSuccess: true,
+			wantContent: strings.Replace(original, `"hello"`, `"bye"`, 1),
+		},
+		{
+			name: "failed hunk is reported, others applied",
+			diff: `@@ -6,1 +6,1 @@
+-	fmt.Println("not there at all")
++	fmt.Println("x")
+@@ -10,1 +10,1 @@
+-	return a + b
++	return b + a
+`,
+			wantSuccess: true,
+			wantContent: strings.Replace(original, "a + b", "b + a", 1),
+			wantFails:   1,
+		},
+		{
+			name:        "no hunks",
+			diff:        "just some text",
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy := NewUnifiedDiffStrategy(tt.threshold, nil)
+			result, err := strategy.ApplyDiff(original, tt.diff, 0, 0)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if result.Success != tt.wantSuccess {
+				t.Fatalf("Expected success %v, got %v (%s)", tt.wantSuccess, result.Success, result.Error)
+			}
+			if tt.wantSuccess && result.Content != tt.wantContent {
+				t.Errorf("Expected content:\n%s\ngot:\n%s", tt.wantContent, result.Content)
+			}
+			if len(result.FailParts) != tt.wantFails {
+				t.Errorf("Expected %d failed hunks, got %d", tt.wantFails, len(result.FailParts))
+			}
+		})
+	}
+}
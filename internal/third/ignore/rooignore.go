@@ -55,22 +55,26 @@ func (c *RooIgnoreController) Initialize() error {
 // ValidateAccess checks if a given path (relative to CWD or absolute) is allowed.
 // Returns true if allowed, false if ignored.
 func (c *RooIgnoreController) ValidateAccess(filePath string) bool {
-	if !c.enabled || c.parser == nil {
+	// nil controller means no ignore rules, callers often pass it through interfaces
+	if c == nil || !c.enabled || c.parser == nil {
 		return true // Allowed if ignore is disabled or failed to init
 	}
 
-	// Library expects path relative to the ignore file's location (c.cwd)
-	relPath, err := filepath.Rel(c.cwd, filePath)
-	if err != nil {
-		// If it's not relative (e.g., different drive on Windows),
-		// treat it as allowed unless an absolute pattern matches.
-		// The library might handle absolute paths, check its docs.
-		relPath = filePath // Use the original path for matching absolute patterns
+	// Match 会按进程的工作目录解析相对路径，这里先转换为相对 c.cwd 的路径再匹配
+	absPath := filePath
+	if !filepath.IsAbs(absPath) {
+		absPath = filepath.Join(c.cwd, absPath)
+	}
+	relPath, err := filepath.Rel(c.cwd, absPath)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return true // 不在 .rooignore 所在目录下的路径不受其规则限制
 	}
+	// 文件不存在时(例如即将创建的文件)按文件匹配
+	info, statErr := os.Stat(absPath)
+	isDir := statErr == nil && info.IsDir()
 
-	// Check if the path matches any ignore pattern
-	match := c.parser.Match(relPath)
-	return match == nil // No match means it's allowed
+	match := c.parser.Relative(filepath.ToSlash(relPath), isDir)
+	return match == nil || !match.Ignore() // No match or a negated pattern means it's allowed
 }
 
 // ValidateCommand checks if a command attempts to access ignored files.
@@ -0,0 +1,369 @@
+// Package markdown converts HTML pages to readable markdown for the LLM.
+package markdown
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// 不包含正文内容的元素，转换时整体跳过
+var skipTags = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true, "template": true,
+	"svg": true, "canvas": true, "iframe": true, "object": true, "button": true,
+	"select": true, "input": true, "textarea": true, "nav": true,
+}
+
+// 块级元素，前后各自成段
+var blockTags = map[string]bool{
+	"html": true, "body": true, "main": true, "article": true, "section": true, "div": true,
+	"header": true, "footer": true, "aside": true, "address": true, "p": true, "blockquote": true,
+	"pre": true, "ul": true, "ol": true, "li": true, "dl": true, "dt": true, "dd": true,
+	"table": true, "figure": true, "figcaption": true, "form": true, "fieldset": true,
+	"details": true, "summary": true, "hr": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+var (
+	spacePattern     = regexp.MustCompile(`[ \t\r\n\f]+`)
+	blankLinePattern = regexp.MustCompile(`\n{3,}`)
+)
+
+// Document is a converted HTML page.
+type Document struct {
+	Title    string
+	Markdown string
+}
+
+// FromHTML converts an HTML document to markdown. Relative links and images are
+// resolved against base when it is not nil. Only <main> or <article> is converted
+// when the page has one, so navigation and footers don't drown the content.
+func FromHTML(r io.Reader, base *url.URL) (*Document, error) {
+	root, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &converter{base: base}
+	content := findElement(root, "main")
+	if content == nil {
+		content = findElement(root, "article")
+	}
+	if content == nil {
+		content = root
+	}
+
+	doc := &Document{Markdown: c.blocks(content)}
+	if title := findElement(root, "title"); title != nil {
+		doc.Title = strings.TrimSpace(spacePattern.ReplaceAllString(textContent(title), " "))
+	}
+	return doc, nil
+}
+
+type converter struct {
+	base *url.URL
+}
+
+// blocks renders the children of n as markdown blocks separated by blank lines.
+func (c *converter) blocks(n *html.Node) string {
+	var parts []string
+	var inline strings.Builder
+	flush := func() {
+		if text := cleanInline(inline.String()); text != "" {
+			parts = append(parts, text)
+		}
+		inline.Reset()
+	}
+
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && skipTags[child.Data] {
+			continue
+		}
+		if child.Type == html.ElementNode && blockTags[child.Data] {
+			flush()
+			if block := c.block(child); block != "" {
+				parts = append(parts, block)
+			}
+			continue
+		}
+		inline.WriteString(c.inline(child))
+	}
+	flush()
+
+	return strings.TrimSpace(blankLinePattern.ReplaceAllString(strings.Join(parts, "\n\n"), "\n\n"))
+}
+
+// block renders a block level element.
+func (c *converter) block(n *html.Node) string {
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		text := strings.ReplaceAll(cleanInline(c.inlineChildren(n)), "\n", " ")
+		if text == "" {
+			return ""
+		}
+		return strings.Repeat("#", int(n.Data[1]-'0')) + " " + text
+	case "pre":
+		return c.codeBlock(n)
+	case "ul", "ol":
+		return c.list(n, n.Data == "ol")
+	case "blockquote":
+		return prefixLines(c.blocks(n), "> ", "> ")
+	case "table":
+		return c.table(n)
+	case "hr":
+		return "---"
+	case "dt":
+		if text := cleanInline(c.inlineChildren(n)); text != "" {
+			return "**" + text + "**"
+		}
+		return ""
+	}
+	return c.blocks(n)
+}
+
+// inline renders an inline node.
+func (c *converter) inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return spacePattern.ReplaceAllString(n.Data, " ")
+	case html.ElementNode:
+	default:
+		return ""
+	}
+
+	switch n.Data {
+	case "br":
+		return "\n"
+	case "code", "kbd", "samp", "tt":
+		text := strings.TrimSpace(spacePattern.ReplaceAllString(textContent(n), " "))
+		if text == "" {
+			return ""
+		}
+		fence := "`"
+		if strings.Contains(text, "`") {
+			fence = "``"
+		}
+		return fence + text + fence
+	case "strong", "b":
+		return wrapInline(c.inlineChildren(n), "**")
+	case "em", "i":
+		return wrapInline(c.inlineChildren(n), "_")
+	case "del", "s", "strike":
+		return wrapInline(c.inlineChildren(n), "~~")
+	case "a":
+		text := c.inlineChildren(n)
+		href := c.resolve(getAttr(n, "href"))
+		if strings.TrimSpace(text) == "" || href == "" {
+			return text
+		}
+		return fmt.Sprintf("[%s](%s)", strings.TrimSpace(text), href)
+	case "img":
+		src := c.resolve(getAttr(n, "src"))
+		if src == "" {
+			return ""
+		}
+		return fmt.Sprintf("![%s](%s)", strings.TrimSpace(getAttr(n, "alt")), src)
+	}
+	if skipTags[n.Data] {
+		return ""
+	}
+	// 行内元素里嵌套了块级元素时，按空格分隔
+	if blockTags[n.Data] {
+		return " " + c.inlineChildren(n) + " "
+	}
+	return c.inlineChildren(n)
+}
+
+func (c *converter) inlineChildren(n *html.Node) string {
+	var sb strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		sb.WriteString(c.inline(child))
+	}
+	return sb.String()
+}
+
+// codeBlock renders <pre> as a fenced code block, keeping the text verbatim.
+func (c *converter) codeBlock(n *html.Node) string {
+	language := ""
+	if code := findElement(n, "code"); code != nil {
+		for _, class := range strings.Fields(getAttr(code, "class")) {
+			if lang, ok := strings.CutPrefix(class, "language-"); ok {
+				language = lang
+				break
+			}
+		}
+	}
+	text := strings.Trim(textContent(n), "\n")
+	if strings.TrimSpace(text) == "" {
+		return ""
+	}
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence + language + "\n" + text + "\n" + fence
+}
+
+// list renders ul/ol items, nested lists are indented under their item.
+func (c *converter) list(n *html.Node, ordered bool) string {
+	var items []string
+	index := 1
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode || child.Data != "li" {
+			continue
+		}
+		marker := "- "
+		if ordered {
+			marker = fmt.Sprintf("%d. ", index)
+			index++
+		}
+		// 列表项内部的段落紧凑排列
+		content := blankLinePattern.ReplaceAllString(c.blocks(child), "\n\n")
+		content = strings.ReplaceAll(content, "\n\n", "\n")
+		if content == "" {
+			continue
+		}
+		items = append(items, prefixLines(content, marker, strings.Repeat(" ", len(marker))))
+	}
+	return strings.Join(items, "\n")
+}
+
+// table renders a table as a markdown table, the first row is the header.
+func (c *converter) table(n *html.Node) string {
+	var rows [][]string
+	var collect func(*html.Node)
+	collect = func(node *html.Node) {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			switch child.Data {
+			case "thead", "tbody", "tfoot":
+				collect(child)
+			case "tr":
+				var cells []string
+				for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.Data == "td" || cell.Data == "th") {
+						text := strings.ReplaceAll(cleanInline(c.inlineChildren(cell)), "\n", " ")
+						cells = append(cells, strings.ReplaceAll(text, "|", `\|`))
+					}
+				}
+				if len(cells) > 0 {
+					rows = append(rows, cells)
+				}
+			}
+		}
+	}
+	collect(n)
+	if len(rows) == 0 {
+		return ""
+	}
+
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	var sb strings.Builder
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		sb.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			sb.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// resolve makes a link absolute, links that only work inside the page are dropped.
+func (c *converter) resolve(href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return ""
+	}
+	if c.base == nil {
+		return href
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return c.base.ResolveReference(ref).String()
+}
+
+// cleanInline trims the spaces around line breaks and collapses repeated spaces.
+func cleanInline(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(strings.Join(strings.Fields(line), " "))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func wrapInline(text, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	// 保留两侧的空格，避免和前后的文字粘在一起
+	leading := text[:len(text)-len(strings.TrimLeft(text, " "))]
+	trailing := text[len(strings.TrimRight(text, " ")):]
+	return leading + marker + trimmed + marker + trailing
+}
+
+func prefixLines(text, first, rest string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		prefix := rest
+		if i == 0 {
+			prefix = first
+		}
+		if line == "" {
+			lines[i] = strings.TrimRight(prefix, " ")
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func findElement(n *html.Node, tag string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == tag {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := findElement(child, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.Data == "br" {
+			sb.WriteString("\n")
+			continue
+		}
+		sb.WriteString(textContent(child))
+	}
+	return sb.String()
+}
+
+func getAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package markdown
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestFromHTML(t *testing.T) {
+	base, _ := url.Parse("https://docs.example.com/guide/intro.html")
+
+	tests := []struct {
+		name  string
+		html  string
+		want  string
+		title string
+	}{
+		{
+			name:  "headings paragraphs and inline",
+			html:  `<html><head><title> Intro  Guide </title><style>p{}</style></head><body><h1>Getting <em>started</em></h1><p>Run   the <code>init</code> command,<br>then <strong>build</strong>.</p><script>alert(1)</script></body></html>`,
+			want:  "# Getting _started_\n\nRun the `init` command,\nthen **build**.",
+			title: "Intro Guide",
+		},
+		{
+			name: "links and images are resolved",
+			html: `<p>See <a href="../api/index.html">the API</a>, <a href="#top">top</a> and <img src="/img/logo.png" alt="logo"></p>`,
+			want: "See [the API](https://docs.example.com/api/index.html), top and ![logo](https://docs.example.com/img/logo.png)",
+		},
+		{
+			name: "code block keeps formatting",
+			html: "<pre><code class=\"language-go\">func main() {\n\tfmt.Println(\"hi\")\n}\n</code></pre>",
+			want: "```go\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n```",
+		},
+		{
+			name: "nested lists",
+			html: `<ol><li>Install<ul><li>macOS</li><li>Linux</li></ul></li><li>Configure</li></ol>`,
+			want: "1. Install\n   - macOS\n   - Linux\n2. Configure",
+		},
+		{
+			name: "table",
+			html: `<table><thead><tr><th>Flag</th><th>Description</th></tr></thead><tbody><tr><td>-v</td><td>verbose | debug</td></tr><tr><td>-q</td></tr></tbody></table>`,
+			want: "| Flag | Description |\n| --- | --- |\n| -v | verbose \\| debug |\n| -q |  |",
+		},
+		{
+			name: "main content only",
+			html: `<body><nav><a href="/">Home</a></nav><header>Site</header><main><h2>Changelog</h2><blockquote><p>v1.2 released</p></blockquote></main><footer>© 2024</footer></body>`,
+			want: "## Changelog\n\n> v1.2 released",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := FromHTML(strings.NewReader(tt.html), base)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if doc.Markdown != tt.want {
+				t.Errorf("Expected markdown %q, got %q", tt.want, doc.Markdown)
+			}
+			if doc.Title != tt.title {
+				t.Errorf("Expected title %q, got %q", tt.title, doc.Title)
+			}
+		})
+	}
+}
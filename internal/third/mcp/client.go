@@ -0,0 +1,397 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 单条消息的最大长度，工具可能返回很大的结果
+const maxMessageSize = 16 << 20
+
+// stderr 只保留最后这么多字节，用于报告服务器启动失败的原因
+const stderrTailSize = 4 << 10
+
+// 关闭 stdin 后等待服务器退出的时间
+const closeGracePeriod = 2 * time.Second
+
+// ErrClosed is returned for calls on a client whose server has exited.
+var ErrClosed = errors.New("mcp server connection closed")
+
+// ServerConfig describes how to launch a stdio MCP server.
+type ServerConfig struct {
+	Name    string
+	Command string
+	Args    []string
+	Env     map[string]string // 追加到当前进程的环境变量
+	Dir     string            // 工作目录，为空时使用当前目录
+	Timeout time.Duration     // Hub 中单个请求的超时，为 0 时使用默认值
+}
+
+// Client is a connection to one stdio MCP server.
+type Client struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	writeMu sync.Mutex
+	stderr  *tailBuffer
+
+	nextID  atomic.Int64
+	mu      sync.Mutex
+	pending map[int64]chan *message
+	done    chan struct{}
+	err     error // 连接断开的原因
+
+	onNotification func(method string, params json.RawMessage)
+
+	initResult initializeResult
+}
+
+// Start launches the server process and performs the initialize handshake.
+// onNotification, if not nil, is called for notifications sent by the server,
+// such as notifications/tools/list_changed. It must not block.
+func Start(ctx context.Context, config ServerConfig, onNotification func(method string, params json.RawMessage)) (*Client, error) {
+	if config.Command == "" {
+		return nil, errors.New("missing command")
+	}
+	cmd := exec.Command(config.Command, config.Args...)
+	cmd.Dir = config.Dir
+	cmd.Env = os.Environ()
+	for key, value := range config.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		cmd:     cmd,
+		stdin:   stdin,
+		stderr:  &tailBuffer{limit: stderrTailSize},
+		pending: map[int64]chan *message{},
+		done:    make(chan struct{}),
+
+		onNotification: onNotification,
+	}
+	cmd.Stderr = c.stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting %s: %w", config.Command, err)
+	}
+	go c.readLoop(stdout)
+
+	if err := c.initialize(ctx); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) initialize(ctx context.Context) error {
+	params := initializeParams{
+		ProtocolVersion: ProtocolVersion,
+		Capabilities:    map[string]any{},
+		ClientInfo:      implementation{Name: "mind-weaver", Version: "1.0.0"},
+	}
+	if err := c.call(ctx, "initialize", params, &c.initResult); err != nil {
+		return fmt.Errorf("initialize: %w", err)
+	}
+	return c.notify("notifications/initialized", nil)
+}
+
+// ServerName returns the name the server reported during initialization.
+func (c *Client) ServerName() string {
+	return c.initResult.ServerInfo.Name
+}
+
+// Instructions returns the usage instructions the server sent, if any.
+func (c *Client) Instructions() string {
+	return c.initResult.Instructions
+}
+
+// HasTools reports whether the server offers tools.
+func (c *Client) HasTools() bool {
+	return c.initResult.Capabilities.Tools != nil
+}
+
+// HasResources reports whether the server offers resources.
+func (c *Client) HasResources() bool {
+	return c.initResult.Capabilities.Resources != nil
+}
+
+// Done is closed when the server process exits or the connection breaks.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// ListTools returns all tools of the server.
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	tools := []Tool{}
+	cursor := ""
+	for {
+		var result listToolsResult
+		if err := c.call(ctx, "tools/list", listParams{Cursor: cursor}, &result); err != nil {
+			return nil, err
+		}
+		tools = append(tools, result.Tools...)
+		if result.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// ListResources returns all resources of the server.
+func (c *Client) ListResources(ctx context.Context) ([]Resource, error) {
+	resources := []Resource{}
+	cursor := ""
+	for {
+		var result listResourcesResult
+		if err := c.call(ctx, "resources/list", listParams{Cursor: cursor}, &result); err != nil {
+			return nil, err
+		}
+		resources = append(resources, result.Resources...)
+		if result.NextCursor == "" {
+			return resources, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// ListResourceTemplates returns all resource templates of the server.
+func (c *Client) ListResourceTemplates(ctx context.Context) ([]ResourceTemplate, error) {
+	templates := []ResourceTemplate{}
+	cursor := ""
+	for {
+		var result listResourceTemplatesResult
+		if err := c.call(ctx, "resources/templates/list", listParams{Cursor: cursor}, &result); err != nil {
+			return nil, err
+		}
+		templates = append(templates, result.ResourceTemplates...)
+		if result.NextCursor == "" {
+			return templates, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// CallTool calls a tool. A tool that fails reports it through CallToolResult.IsError,
+// the error is only set when the call itself fails.
+func (c *Client) CallTool(ctx context.Context, name string, arguments map[string]any) (*CallToolResult, error) {
+	var result CallToolResult
+	if err := c.call(ctx, "tools/call", callToolParams{Name: name, Arguments: arguments}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ReadResource reads a resource by URI.
+func (c *Client) ReadResource(ctx context.Context, uri string) (*ReadResourceResult, error) {
+	var result ReadResourceResult
+	if err := c.call(ctx, "resources/read", readResourceParams{URI: uri}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Close stops the server process. The server gets a moment to exit after its
+// stdin is closed, as the stdio transport specifies, before it is killed.
+func (c *Client) Close() error {
+	c.stdin.Close()
+	select {
+	case <-c.done:
+	case <-time.After(closeGracePeriod):
+		if c.cmd.Process != nil {
+			c.cmd.Process.Kill()
+		}
+		<-c.done
+	}
+	return nil
+}
+
+// call sends a request and decodes the result into result.
+func (c *Client) call(ctx context.Context, method string, params any, result any) error {
+	id := c.nextID.Add(1)
+	rawID := json.RawMessage(strconv.FormatInt(id, 10))
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	ch := make(chan *message, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.write(&message{JSONRPC: "2.0", ID: &rawID, Method: method, Params: paramsJSON}); err != nil {
+		return err
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil {
+			return nil
+		}
+		return json.Unmarshal(resp.Result, result)
+	case <-c.done:
+		return c.closeErr()
+	case <-ctx.Done():
+		// 告诉服务器放弃这个请求
+		c.notify("notifications/cancelled", map[string]any{"requestId": id, "reason": ctx.Err().Error()})
+		return ctx.Err()
+	}
+}
+
+func (c *Client) notify(method string, params any) error {
+	msg := &message{JSONRPC: "2.0", Method: method}
+	if params != nil {
+		paramsJSON, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		msg.Params = paramsJSON
+	}
+	return c.write(msg)
+}
+
+func (c *Client) write(msg *message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := c.stdin.Write(append(data, '\n')); err != nil {
+		select {
+		case <-c.done:
+			return c.closeErr()
+		default:
+			return fmt.Errorf("writing to mcp server: %w", err)
+		}
+	}
+	return nil
+}
+
+// readLoop dispatches the messages the server writes to stdout, one JSON object per line.
+func (c *Client) readLoop(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64<<10), maxMessageSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var msg message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			// 有些服务器会把日志打印到 stdout，忽略非 JSON 的行
+			continue
+		}
+		c.dispatch(&msg)
+	}
+
+	err := scanner.Err()
+	waitErr := c.cmd.Wait()
+	if err == nil {
+		err = waitErr
+	}
+	stderr := c.stderr.String()
+	switch {
+	case err != nil && stderr != "":
+		err = fmt.Errorf("%w: %v: %s", ErrClosed, err, stderr)
+	case err != nil:
+		err = fmt.Errorf("%w: %v", ErrClosed, err)
+	case stderr != "":
+		err = fmt.Errorf("%w: %s", ErrClosed, stderr)
+	default:
+		err = ErrClosed
+	}
+
+	c.mu.Lock()
+	c.err = err
+	c.mu.Unlock()
+	close(c.done)
+}
+
+func (c *Client) dispatch(msg *message) {
+	switch {
+	case msg.Method == "" && msg.ID != nil:
+		id, err := strconv.ParseInt(string(*msg.ID), 10, 64)
+		if err != nil {
+			return
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[id]
+		c.mu.Unlock()
+		if ok {
+			ch <- msg
+		}
+	case msg.ID != nil:
+		// 服务器发来的请求，只支持 ping
+		resp := &message{JSONRPC: "2.0", ID: msg.ID}
+		if msg.Method == "ping" {
+			resp.Result = json.RawMessage("{}")
+		} else {
+			resp.Error = &RPCError{Code: codeMethodNotFound, Message: "method not supported by client: " + msg.Method}
+		}
+		go c.write(resp)
+	case c.onNotification != nil:
+		c.onNotification(msg.Method, msg.Params)
+	}
+}
+
+func (c *Client) closeErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	return ErrClosed
+}
+
+// tailBuffer keeps the last limit bytes written to it.
+type tailBuffer struct {
+	mu    sync.Mutex
+	limit int
+	buf   []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.limit {
+		b.buf = b.buf[len(b.buf)-b.limit:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(bytes.TrimSpace(b.buf))
+}
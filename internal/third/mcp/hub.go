@@ -0,0 +1,258 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 没有配置超时时单个请求的默认超时
+const defaultRequestTimeout = 60 * time.Second
+
+// 服务器状态
+const (
+	StatusConnected    = "connected"
+	StatusDisconnected = "disconnected"
+)
+
+// ServerInfo is a snapshot of a server managed by a Hub.
+type ServerInfo struct {
+	Name              string             `json:"name"`
+	Status            string             `json:"status"`
+	Error             string             `json:"error,omitempty"`
+	Instructions      string             `json:"instructions,omitempty"`
+	Tools             []Tool             `json:"tools"`
+	Resources         []Resource         `json:"resources"`
+	ResourceTemplates []ResourceTemplate `json:"resourceTemplates"`
+}
+
+// Hub manages the connections to a set of configured servers. A server that
+// exits is started again on its next call.
+type Hub struct {
+	servers []*server
+	closed  chan struct{}
+	once    sync.Once
+}
+
+type server struct {
+	config ServerConfig
+
+	mu        sync.Mutex
+	client    *Client
+	err       error // 最近一次连接失败的原因
+	tools     []Tool
+	resources []Resource
+	templates []ResourceTemplate
+}
+
+// NewHub creates a hub for the given servers. Servers are not started until
+// Start is called or they are first used.
+func NewHub(configs []ServerConfig) *Hub {
+	h := &Hub{closed: make(chan struct{})}
+	for _, config := range configs {
+		h.servers = append(h.servers, &server{
+			config:    config,
+			tools:     []Tool{},
+			resources: []Resource{},
+			templates: []ResourceTemplate{},
+		})
+	}
+	return h
+}
+
+// Start connects to all servers concurrently. A server that fails to start is
+// reported through Servers and retried on its next call.
+func (h *Hub) Start(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, s := range h.servers {
+		wg.Add(1)
+		go func(s *server) {
+			defer wg.Done()
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			h.connectLocked(ctx, s)
+		}(s)
+	}
+	wg.Wait()
+}
+
+// Servers returns the state of all servers in configuration order.
+func (h *Hub) Servers() []ServerInfo {
+	infos := make([]ServerInfo, 0, len(h.servers))
+	for _, s := range h.servers {
+		s.mu.Lock()
+		info := ServerInfo{
+			Name:              s.config.Name,
+			Status:            StatusDisconnected,
+			Tools:             s.tools,
+			Resources:         s.resources,
+			ResourceTemplates: s.templates,
+		}
+		if s.connectedLocked() {
+			info.Status = StatusConnected
+			info.Instructions = s.client.Instructions()
+		} else if s.err != nil {
+			info.Error = s.err.Error()
+		} else if s.client != nil {
+			info.Error = s.client.closeErr().Error()
+		}
+		s.mu.Unlock()
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// CallTool calls a tool on the named server.
+func (h *Hub) CallTool(ctx context.Context, serverName, toolName string, arguments map[string]any) (*CallToolResult, error) {
+	client, s, err := h.client(ctx, serverName)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.timeout())
+	defer cancel()
+	return client.CallTool(ctx, toolName, arguments)
+}
+
+// ReadResource reads a resource from the named server.
+func (h *Hub) ReadResource(ctx context.Context, serverName, uri string) (*ReadResourceResult, error) {
+	client, s, err := h.client(ctx, serverName)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.timeout())
+	defer cancel()
+	return client.ReadResource(ctx, uri)
+}
+
+// Close stops all servers. The hub cannot be used afterwards.
+func (h *Hub) Close() {
+	h.once.Do(func() { close(h.closed) })
+	for _, s := range h.servers {
+		s.mu.Lock()
+		if s.client != nil {
+			s.client.Close()
+			s.client = nil
+		}
+		s.mu.Unlock()
+	}
+}
+
+// client returns a live connection to the named server, reconnecting if the
+// server has exited.
+func (h *Hub) client(ctx context.Context, name string) (*Client, *server, error) {
+	var s *server
+	for _, candidate := range h.servers {
+		if candidate.config.Name == name {
+			s = candidate
+			break
+		}
+	}
+	if s == nil {
+		return nil, nil, fmt.Errorf("unknown MCP server %q", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.connectedLocked() {
+		if err := h.connectLocked(ctx, s); err != nil {
+			return nil, nil, fmt.Errorf("connecting to MCP server %q: %w", name, err)
+		}
+	}
+	return s.client, s, nil
+}
+
+// connectLocked starts the server and loads its tools and resources. s.mu must be held.
+func (h *Hub) connectLocked(ctx context.Context, s *server) error {
+	select {
+	case <-h.closed:
+		return errors.New("mcp hub closed")
+	default:
+	}
+	if s.client != nil {
+		s.client.Close()
+		s.client = nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout())
+	defer cancel()
+	client, err := Start(ctx, s.config, func(method string, params json.RawMessage) {
+		switch method {
+		case "notifications/tools/list_changed", "notifications/resources/list_changed":
+			go h.refresh(s)
+		}
+	})
+	if err != nil {
+		s.err = err
+		return err
+	}
+	s.client = client
+	if err := s.loadLocked(ctx); err != nil {
+		client.Close()
+		s.client = nil
+		s.err = err
+		return err
+	}
+	s.err = nil
+	return nil
+}
+
+// refresh reloads the tools and resources after the server reported a change.
+func (h *Hub) refresh(s *server) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.connectedLocked() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout())
+	defer cancel()
+	if err := s.loadLocked(ctx); err != nil {
+		s.err = err
+	}
+}
+
+func (s *server) loadLocked(ctx context.Context) error {
+	tools, resources, templates := []Tool{}, []Resource{}, []ResourceTemplate{}
+	var err error
+	if s.client.HasTools() {
+		if tools, err = s.client.ListTools(ctx); err != nil {
+			return fmt.Errorf("listing tools: %w", err)
+		}
+	}
+	if s.client.HasResources() {
+		if resources, err = s.client.ListResources(ctx); err != nil {
+			return fmt.Errorf("listing resources: %w", err)
+		}
+		// 模板是可选的，有些服务器没有实现这个方法
+		var rpcErr *RPCError
+		if templates, err = s.client.ListResourceTemplates(ctx); err != nil {
+			if !errors.As(err, &rpcErr) || rpcErr.Code != codeMethodNotFound {
+				return fmt.Errorf("listing resource templates: %w", err)
+			}
+			templates = []ResourceTemplate{}
+		}
+	}
+	s.tools, s.resources, s.templates = tools, resources, templates
+	return nil
+}
+
+func (s *server) connectedLocked() bool {
+	if s.client == nil {
+		return false
+	}
+	select {
+	case <-s.client.Done():
+		return false
+	default:
+		return true
+	}
+}
+
+func (s *server) timeout() time.Duration {
+	if s.config.Timeout > 0 {
+		return s.config.Timeout
+	}
+	return defaultRequestTimeout
+}
@@ -0,0 +1,185 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 设置该环境变量时，测试二进制作为假的 MCP 服务器运行
+const fakeServerEnv = "MCP_FAKE_SERVER"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeServerEnv) != "" {
+		runFakeServer()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runFakeServer serves a "notes" server with tools split over two pages and
+// one resource. The "crash" tool makes the process exit.
+func runFakeServer() {
+	fmt.Println("fake server starting") // 非 JSON 输出应被忽略
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var msg message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil || msg.ID == nil {
+			continue
+		}
+		var result any
+		var rpcErr *RPCError
+		switch msg.Method {
+		case "initialize":
+			result = map[string]any{
+				"protocolVersion": ProtocolVersion,
+				"capabilities":    map[string]any{"tools": map[string]any{}, "resources": map[string]any{}},
+				"serverInfo":      map[string]any{"name": "notes", "version": "0.1.0"},
+				"instructions":    "Use search before get.",
+			}
+		case "tools/list":
+			var params listParams
+			json.Unmarshal(msg.Params, &params)
+			if params.Cursor == "" {
+				result = map[string]any{
+					"tools":      []Tool{{Name: "echo", Description: "Echo the text", InputSchema: json.RawMessage(`{"type":"object","properties":{"text":{"type":"string"}},"required":["text"]}`)}},
+					"nextCursor": "page2",
+				}
+			} else {
+				result = map[string]any{"tools": []Tool{{Name: "crash", Description: "Exit the server"}}}
+			}
+		case "tools/call":
+			var params callToolParams
+			json.Unmarshal(msg.Params, &params)
+			switch params.Name {
+			case "echo":
+				text, _ := params.Arguments["text"].(string)
+				isError := text == ""
+				if isError {
+					text = "text is required"
+				}
+				result = CallToolResult{Content: []Content{{Type: "text", Text: text}}, IsError: isError}
+			case "crash":
+				fmt.Fprintln(os.Stderr, "crashing on purpose")
+				os.Exit(3)
+			default:
+				rpcErr = &RPCError{Code: -32602, Message: "unknown tool: " + params.Name}
+			}
+		case "resources/list":
+			result = map[string]any{"resources": []Resource{{URI: "notes://today", Name: "Today", MimeType: "text/plain"}}}
+		case "resources/read":
+			var params readResourceParams
+			json.Unmarshal(msg.Params, &params)
+			if params.URI != "notes://today" {
+				rpcErr = &RPCError{Code: -32002, Message: "resource not found"}
+				break
+			}
+			result = ReadResourceResult{Contents: []ResourceContents{{URI: params.URI, MimeType: "text/plain", Text: "buy milk"}}}
+		default:
+			rpcErr = &RPCError{Code: codeMethodNotFound, Message: "method not found"}
+		}
+
+		resp := map[string]any{"jsonrpc": "2.0", "id": msg.ID}
+		if rpcErr != nil {
+			resp["error"] = rpcErr
+		} else {
+			resp["result"] = result
+		}
+		data, _ := json.Marshal(resp)
+		fmt.Println(string(data))
+	}
+}
+
+func fakeServerConfig(t *testing.T, name string) ServerConfig {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ServerConfig{
+		Name:    name,
+		Command: exe,
+		Env:     map[string]string{fakeServerEnv: "1"},
+		Timeout: 10 * time.Second,
+	}
+}
+
+func TestHub(t *testing.T) {
+	hub := NewHub([]ServerConfig{
+		fakeServerConfig(t, "notes"),
+		{Name: "broken", Command: "/nonexistent/mcp-server"},
+	})
+	defer hub.Close()
+	hub.Start(context.Background())
+
+	servers := hub.Servers()
+	if len(servers) != 2 {
+		t.Fatalf("Expected 2 servers, got %d", len(servers))
+	}
+	notes, broken := servers[0], servers[1]
+	if notes.Status != StatusConnected || notes.Instructions != "Use search before get." {
+		t.Errorf("Expected notes to be connected with instructions, got %+v", notes)
+	}
+	if len(notes.Tools) != 2 || notes.Tools[0].Name != "echo" || notes.Tools[1].Name != "crash" {
+		t.Errorf("Expected tools from both pages, got %+v", notes.Tools)
+	}
+	if len(notes.Resources) != 1 || notes.Resources[0].URI != "notes://today" {
+		t.Errorf("Expected 1 resource, got %+v", notes.Resources)
+	}
+	if len(notes.ResourceTemplates) != 0 {
+		t.Errorf("Expected no resource templates, got %+v", notes.ResourceTemplates)
+	}
+	if broken.Status != StatusDisconnected || broken.Error == "" {
+		t.Errorf("Expected broken to be disconnected with an error, got %+v", broken)
+	}
+
+	ctx := context.Background()
+	result, err := hub.CallTool(ctx, "notes", "echo", map[string]any{"text": "hello"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.IsError || len(result.Content) != 1 || result.Content[0].Text != "hello" {
+		t.Errorf("Expected echo result, got %+v", result)
+	}
+
+	result, err = hub.CallTool(ctx, "notes", "echo", map[string]any{})
+	if err != nil || !result.IsError {
+		t.Errorf("Expected a tool error result, got %+v, %v", result, err)
+	}
+
+	var rpcErr *RPCError
+	if _, err := hub.CallTool(ctx, "notes", "missing", nil); !errors.As(err, &rpcErr) {
+		t.Errorf("Expected an RPC error for an unknown tool, got %v", err)
+	}
+
+	if _, err := hub.CallTool(ctx, "other", "echo", nil); err == nil || !strings.Contains(err.Error(), "unknown MCP server") {
+		t.Errorf("Expected unknown server error, got %v", err)
+	}
+
+	resource, err := hub.ReadResource(ctx, "notes", "notes://today")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(resource.Contents) != 1 || resource.Contents[0].Text != "buy milk" {
+		t.Errorf("Expected resource text, got %+v", resource)
+	}
+
+	// 服务器退出后，下一次调用会重新启动它
+	_, err = hub.CallTool(ctx, "notes", "crash", nil)
+	if !errors.Is(err, ErrClosed) || !strings.Contains(err.Error(), "crashing on purpose") {
+		t.Errorf("Expected closed error with stderr, got %v", err)
+	}
+	if status := hub.Servers()[0].Status; status != StatusDisconnected {
+		t.Errorf("Expected notes to be disconnected after crash, got %s", status)
+	}
+	result, err = hub.CallTool(ctx, "notes", "echo", map[string]any{"text": "again"})
+	if err != nil || result.Content[0].Text != "again" {
+		t.Errorf("Expected echo after reconnect, got %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,164 @@
+package prompts
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// patchContextLines 每个 hunk 前后保留的上下文行数
+	patchContextLines = 3
+	// maxLCSCells 超过该规模时不再逐行比较，直接把中间部分作为整体替换
+	maxLCSCells = 4_000_000
+)
+
+// patchLine is one line of a diff, Kind is ' ', '-' or '+'.
+type patchLine struct {
+	Kind byte
+	Text string
+}
+
+// CreatePrettyPatch generates a human-readable unified diff between two versions
+// of a file. Returns an empty string if nothing changed.
+func CreatePrettyPatch(filename, oldStr, newStr string) string {
+	if oldStr == newStr {
+		return ""
+	}
+
+	lines := diffLines(splitPatchLines(oldStr), splitPatchLines(newStr))
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "--- a/%s\n+++ b/%s\n", filename, filename)
+
+	oldNo, newNo := 0, 0 // 当前行之前已经经过的旧/新文件行数
+	for i := 0; i < len(lines); {
+		if lines[i].Kind == ' ' {
+			oldNo++
+			newNo++
+			i++
+			continue
+		}
+
+		// 向前扩展上下文，向后合并间隔不超过两倍上下文的修改
+		start := max(0, i-patchContextLines)
+		end := i
+		for j := i; j < len(lines); j++ {
+			if lines[j].Kind == ' ' {
+				continue
+			}
+			if j-end > 2*patchContextLines {
+				break
+			}
+			end = j
+		}
+		end = min(len(lines), end+patchContextLines+1)
+
+		hunkOldStart, hunkNewStart := oldNo-(i-start), newNo-(i-start)
+		oldCount, newCount := 0, 0
+		for _, line := range lines[start:end] {
+			if line.Kind != '+' {
+				oldCount++
+			}
+			if line.Kind != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&builder, "@@ -%s +%s @@\n", hunkRange(hunkOldStart, oldCount), hunkRange(hunkNewStart, newCount))
+		for _, line := range lines[start:end] {
+			builder.WriteByte(line.Kind)
+			builder.WriteString(line.Text)
+			builder.WriteByte('\n')
+		}
+
+		for _, line := range lines[i:end] {
+			if line.Kind != '+' {
+				oldNo++
+			}
+			if line.Kind != '-' {
+				newNo++
+			}
+		}
+		i = end
+	}
+
+	return builder.String()
+}
+
+// hunkRange formats the "start,count" part of a hunk header, start is 1-based.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func splitPatchLines(content string) []string {
+	if content == "" {
+		return []string{}
+	}
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// diffLines returns the line diff of a and b based on their longest common subsequence.
+func diffLines(a, b []string) []patchLine {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	result := make([]patchLine, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		result = append(result, patchLine{Kind: ' ', Text: line})
+	}
+
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(midA)*len(midB) > maxLCSCells {
+		for _, line := range midA {
+			result = append(result, patchLine{Kind: '-', Text: line})
+		}
+		for _, line := range midB {
+			result = append(result, patchLine{Kind: '+', Text: line})
+		}
+	} else {
+		// lcs[i][j] 是 midA[i:] 和 midB[j:] 的最长公共子序列长度
+		lcs := make([][]int, len(midA)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(midB)+1)
+		}
+		for i := len(midA) - 1; i >= 0; i-- {
+			for j := len(midB) - 1; j >= 0; j-- {
+				if midA[i] == midB[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+
+		i, j := 0, 0
+		for i < len(midA) || j < len(midB) {
+			switch {
+			case i < len(midA) && j < len(midB) && midA[i] == midB[j]:
+				result = append(result, patchLine{Kind: ' ', Text: midA[i]})
+				i++
+				j++
+			case j >= len(midB) || i < len(midA) && lcs[i+1][j] >= lcs[i][j+1]:
+				result = append(result, patchLine{Kind: '-', Text: midA[i]})
+				i++
+			default:
+				result = append(result, patchLine{Kind: '+', Text: midB[j]})
+				j++
+			}
+		}
+	}
+
+	for _, line := range a[len(a)-suffix:] {
+		result = append(result, patchLine{Kind: ' ', Text: line})
+	}
+	return result
+}
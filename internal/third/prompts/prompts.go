@@ -2,8 +2,9 @@ package prompts
 
 import (
 	"fmt"
-	"mind-weaver/internal/third/diff"             // Adjust
-	"mind-weaver/internal/third/ignore"           // Adjust
+	"mind-weaver/internal/third/diff"   // Adjust
+	"mind-weaver/internal/third/ignore" // Adjust
+	"mind-weaver/internal/third/mcp"
 	"mind-weaver/internal/third/prompts/sections" // Adjust
 	"mind-weaver/internal/third/prompts/tools"    // Adjust
 	"strings"
@@ -12,9 +13,12 @@ import (
 // EnvironmentContext holds info passed from the web frontend/API
 type EnvironmentContext struct {
 	Cwd                 string
-	SupportsComputerUse bool   // For browser actions etc.
-	BrowserViewportSize string // e.g., "1280x800"
-	Language            string // e.g., "en", "fr"
+	SupportsComputerUse bool             // For browser actions etc.
+	BrowserViewportSize string           // e.g., "1280x800"
+	Language            string           // e.g., "en", "fr"
+	McpServers          []mcp.ServerInfo // 可通过 use_mcp_tool、access_mcp_resource 使用的 MCP 服务器
+	RepoMap             string           // 仓库地图，为空时不加入
+	CodebaseSearch      bool             // 是否可以使用 codebase_search 语义搜索代码
 	// Potentially add OS, Shell info if needed by prompts
 	// Could also include Experiments map[string]bool
 }
@@ -71,6 +75,8 @@ func BuildSystemPrompt(args BuildSystemPromptArgs) (string, error) {
 		SupportsComputerUse: args.EnvCtx.SupportsComputerUse,
 		DiffStrategy:        args.DiffStrategy,
 		BrowserViewportSize: args.EnvCtx.BrowserViewportSize,
+		McpServers:          args.EnvCtx.McpServers,
+		CodebaseSearch:      args.EnvCtx.CodebaseSearch,
 		// Pass experiments if needed
 	}
 	builder.WriteString(tools.GetToolDescriptionsForMode(args.Mode, toolDescArgs, args.CustomModeConfigs)) // Needs implementation
@@ -96,6 +102,12 @@ func BuildSystemPrompt(args BuildSystemPromptArgs) (string, error) {
 	builder.WriteString(sections.GetSystemInfoSection(args.EnvCtx.Cwd, args.Mode, args.CustomModeConfigs)) // Needs implementation
 	builder.WriteString("\n\n")
 
+	// 9.1 Repository Map Section
+	if repoMapSection := sections.GetRepoMapSection(args.EnvCtx.Cwd, args.EnvCtx.RepoMap); repoMapSection != "" {
+		builder.WriteString(repoMapSection)
+		builder.WriteString("\n\n")
+	}
+
 	// 10. Objective Section
 	builder.WriteString(sections.GetObjectiveSection())
 	builder.WriteString("\n\n")
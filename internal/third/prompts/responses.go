@@ -9,7 +9,6 @@ import (
 	// Go's path package
 	"sort"
 	"strings"
-	// "github.com/sergi/go-diff/diffmatchpatch" // For diff generation if needed
 )
 
 const toolUseInstructionsReminder = `# Reminder: Instructions for Tool Use
@@ -154,32 +153,6 @@ func FormatTooManyMistakes(feedback string) string {
 	return fmt.Sprintf("You seem to be having trouble proceeding.%s", fbSection)
 }
 
-// CreatePrettyPatch generates a human-readable diff patch.
-// Requires a diff library. Returns empty string if no changes or on error.
-func CreatePrettyPatch(filename, oldStr, newStr string) string {
-	// --- Option 1: Using github.com/sergi/go-diff/diffmatchpatch ---
-	// dmp := diffmatchpatch.New()
-	// diffs := dmp.DiffMain(oldStr, newStr, true) // Check lines = true
-	// patches := dmp.PatchMake(oldStr, diffs)
-	// patchText := dmp.PatchToText(patches)
-	// // Basic cleanup to resemble the TS output (remove header)
-	// lines := strings.Split(patchText, "\n")
-	// if len(lines) > 4 {
-	//     // Heuristic: Remove the standard patch header lines
-	//     // Adjust indices if the library's output format differs
-	//     return strings.Join(lines[4:], "\n")
-	// }
-	// return patchText // Return raw patch if cleanup fails
-
-	// --- Option 2: Placeholder if no diff library is used server-side ---
-	fmt.Println("Warning: CreatePrettyPatch requires a diff library implementation.")
-	if oldStr == newStr {
-		return ""
-	}
-	// Simple indication of change if no library
-	return fmt.Sprintf("--- a/%s\n+++ b/%s\n@@ ... @@\n- [Content differs, diff generation not implemented server-side]\n+ [Content differs, diff generation not implemented server-side]\n", filename, filename)
-}
-
 // FormatToolDenied formats the message when the user denies an operation.
 func FormatToolDenied() string {
 	return "The user denied this operation."
@@ -46,7 +46,10 @@ var BuiltinModes = []ModeConfig{
 		Groups: []GroupEntry{
 			{Name: toolgroups.GroupRead},
 			{Name: toolgroups.GroupEdit},
+			{Name: toolgroups.GroupFiles},
 			{Name: toolgroups.GroupCommand},
+			{Name: toolgroups.GroupBrowser}, // 配置中未启用浏览器时不会出现在提示词中
+			{Name: toolgroups.GroupMcp},
 			// Add other default groups
 		},
 		CustomInstructions: "",
@@ -60,6 +63,7 @@ var BuiltinModes = []ModeConfig{
 			// Example restriction: only allow editing Markdown
 			{Name: toolgroups.GroupEdit, Restriction: &FileRestriction{FileRegex: `\.md$`, Description: "Markdown files only"}},
 			{Name: toolgroups.GroupCommand},
+			{Name: toolgroups.GroupMcp},
 		},
 		CustomInstructions: "Focus on high-level design, documentation...",
 	},
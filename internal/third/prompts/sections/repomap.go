@@ -0,0 +1,18 @@
+package sections
+
+import "strings"
+
+// GetRepoMapSection returns the repository map section, or an empty string
+// when there is no map.
+func GetRepoMapSection(cwd, repoMap string) string {
+	if strings.TrimSpace(repoMap) == "" {
+		return ""
+	}
+	return `====
+
+REPOSITORY MAP
+
+Below is an outline of the most important source code definitions in the current workspace directory ('` + ToPosix(cwd) + `'), ranked by how often they are referenced from other files. Each file starts with '# <relative path>', followed by one definition per line as '<start line>--<end line> | <first line of the definition>'. Use it to find the files and line ranges relevant to the task and read them with read_file directly, instead of exploring the project with list_files. The map only shows part of the project; use list_files, search_files or list_code_definition_names for anything not listed.
+
+` + strings.TrimRight(repoMap, "\n")
+}
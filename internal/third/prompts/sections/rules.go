@@ -77,7 +77,7 @@ RULES
 			false: "",
 		}[supportsComputerUse],
 		map[bool]string{
-			true:  " Then if you want to test your work, you might use browser_action to launch the site, wait for the user's response confirming the site was launched along with a screenshot, then perhaps e.g., click a button to test functionality if needed, wait for the user's response confirming the button was clicked along with a screenshot of the new state, before finally closing the browser.",
+			true:  " Then if you want to test your work, you might use browser_action to launch the site, check the returned page text and console logs, then perhaps e.g., click a button to test functionality if needed and check the new state of the page, before finally closing the browser.",
 			false: "",
 		}[supportsComputerUse],
 	)
@@ -1,10 +1,14 @@
 package tools
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
 
+	"mind-weaver/internal/third/diff/multireplace"
+	"mind-weaver/internal/third/mcp"
 	"mind-weaver/internal/third/prompts/sections"
 	"mind-weaver/internal/third/toolgroups"
 )
@@ -213,6 +217,27 @@ Example: Requesting to search for all .ts files in the current directory
 </search_files>`, args.Cwd)
 }
 
+func GetCodebaseSearchDescription(args ToolDescriptionGenArgs) string {
+	if !args.CodebaseSearch {
+		return ""
+	}
+	return fmt.Sprintf(`## codebase_search
+Description: Request to search the codebase by meaning rather than exact text. Use it first when you don't know where something is implemented or which names it uses, e.g. "where do we handle refund retries" or "how are API keys validated". Results are the functions, classes and other code chunks most similar to the query, best first, with file paths and line numbers. Use search_files instead when you know the exact text or identifier to look for, and read_file to see more of a result.
+Parameters:
+- query: (required) What you are looking for, described in natural language. Reuse the user's wording and add likely technical terms.
+- path: (optional) Limit the search to a directory or file (relative to the current workspace directory %s). Searches the whole workspace if not provided.
+Usage:
+<codebase_search>
+<query>Your natural language query here</query>
+<path>Directory path here (optional)</path>
+</codebase_search>
+
+Example: Requesting to find where refund retries are handled
+<codebase_search>
+<query>retry failed refund payments with backoff</query>
+</codebase_search>`, args.Cwd)
+}
+
 func GetListCodeDefinitionNamesDescription(args ToolDescriptionGenArgs) string {
 	return fmt.Sprintf(`## list_code_definition_names
 Description: Request to list definition names (classes, functions, methods, etc.) from source code. This tool can analyze either a single file or all files at the top level of a specified directory. It provides insights into the codebase structure and important constructs, encapsulating high-level concepts and relationships that are crucial for understanding the overall architecture.
@@ -270,8 +295,342 @@ Example: Insert a new function and its import statement
 </insert_content>`, args.Cwd)
 }
 
-// ... Add functions for apply_diff (using args.DiffStrategy.GetToolDescription),
-// ... insert_content, search_and_replace, browser_action (checking args.SupportsComputerUse)
+// GetApplyDiffDescription 由修改策略生成 apply_diff 的说明，没有启用策略时不提供该工具
+func GetApplyDiffDescription(args ToolDescriptionGenArgs) string {
+	if args.DiffStrategy == nil {
+		return ""
+	}
+	return args.DiffStrategy.GetToolDescription(multireplace.ToolDescriptionArgs{Cwd: args.Cwd})
+}
+
+func GetSearchAndReplaceDescription(args ToolDescriptionGenArgs) string {
+	return fmt.Sprintf(`## search_and_replace
+Description: Request to perform search and replace operations on a file. Each operation can specify a search pattern (literal string or regex) and replacement text, with optional line range restrictions and case-insensitive matching. Operations are applied in order and every match in the (scoped) content is replaced. This is the preferred tool for mechanical edits such as renaming an identifier across a file. Use dry_run to preview the resulting diff without modifying the file.
+Parameters:
+- path: (required) The path of the file to modify (relative to the current workspace directory %s)
+- operations: (required) A JSON array of search/replace operations. Each operation is an object with:
+    * search: (required) The text or pattern to search for
+    * replace: (required) The text to replace matches with. In regex mode, use $1, $2 or ${name} to reference capture groups. Use "\n" for line breaks in multi-line replacements
+    * start_line: (optional) Starting line number (1-based) to restrict the replacement to
+    * end_line: (optional) Ending line number (1-based, inclusive) to restrict the replacement to
+    * use_regex: (optional) Whether to treat search as a regular expression (RE2 syntax), default false
+    * ignore_case: (optional) Whether to ignore case when matching, default false
+    * regex_flags: (optional) Additional regex flags when use_regex is true: i (ignore case), s (. matches newline), U (ungreedy)
+- dry_run: (optional) Set to true to only return the diff preview without writing the file
+Usage:
+<search_and_replace>
+<path>File path here</path>
+<operations>[
+  {
+    "search": "text to find",
+    "replace": "replacement text",
+    "start_line": 1,
+    "end_line": 10
+  }
+]</operations>
+</search_and_replace>
+Example: Rename a function in example.go
+<search_and_replace>
+<path>example.go</path>
+<operations>[
+  {
+    "search": "oldFunc",
+    "replace": "newFunc"
+  }
+]</operations>
+</search_and_replace>
+Example: Preview a regex replacement limited to lines 10-40
+<search_and_replace>
+<path>example.ts</path>
+<operations>[
+  {
+    "search": "console\\.log\\((.*)\\)",
+    "replace": "logger.debug($1)",
+    "start_line": 10,
+    "end_line": 40,
+    "use_regex": true
+  }
+]</operations>
+<dry_run>true</dry_run>
+</search_and_replace>`, args.Cwd)
+}
+
+func GetBatchEditDescription(args ToolDescriptionGenArgs) string {
+	patchAction := ""
+	if args.DiffStrategy != nil {
+		patchAction = `
+    * "patch": modify an existing file. Requires "diff", written in exactly the same format as the diff parameter of the apply_diff tool`
+	}
+	return fmt.Sprintf(`## batch_edit
+Description: Request to apply several file operations in a single call, e.g. renaming a symbol across many files or moving a module and updating its imports. All operations are checked first and applied in order; the files are only written when every operation succeeds, otherwise nothing is changed and the error of the failing operation is returned. Prefer this tool over multiple single-file edits for cross-file changes.
+Parameters:
+- operations: (required) A JSON array of file operations. Each operation is an object with:
+    * action: (required) One of "create", "patch", "delete", "move"
+    * path: (required) The path of the file (relative to the current workspace directory %s)
+    * content: (required for create) The complete content of the file
+    * diff: (required for patch) The changes to apply
+    * new_path: (required for move) The destination path, which must not exist yet
+Actions:
+    * "create": create a new file or overwrite an existing one with "content"%s
+    * "delete": delete an existing file
+    * "move": move or rename a file to "new_path"
+Later operations see the result of earlier ones, so a file can be moved and then patched in the same batch.
+Usage:
+<batch_edit>
+<operations>[
+  {
+    "action": "create",
+    "path": "src/utils/format.ts",
+    "content": "export function format(value: number): string {\n  return value.toFixed(2);\n}\n"
+  },
+  {
+    "action": "move",
+    "path": "src/old_name.ts",
+    "new_path": "src/new_name.ts"
+  },
+  {
+    "action": "delete",
+    "path": "src/unused.ts"
+  }
+]</operations>
+</batch_edit>`, args.Cwd, patchAction)
+}
+
+func GetMoveFileDescription(args ToolDescriptionGenArgs) string {
+	return fmt.Sprintf(`## move_file
+Description: Request to move or rename a file. The destination must not exist yet; missing parent directories are created automatically. Use this instead of running mv with execute_command. Only files can be moved, not directories. Remember to update imports or references to the moved file afterwards.
+Parameters:
+- path: (required) The path of the file to move (relative to the current workspace directory %s)
+- new_path: (required) The destination path of the file (relative to the current workspace directory %s)
+Usage:
+<move_file>
+<path>File path here</path>
+<new_path>Destination path here</new_path>
+</move_file>
+
+Example: Requesting to rename utils.go to strings.go
+<move_file>
+<path>pkg/utils.go</path>
+<new_path>pkg/strings.go</new_path>
+</move_file>`, args.Cwd, args.Cwd)
+}
+
+func GetDeleteFileDescription(args ToolDescriptionGenArgs) string {
+	return fmt.Sprintf(`## delete_file
+Description: Request to delete a file that is no longer needed. Use this instead of running rm with execute_command. Only a single file can be deleted per call, directories are not supported.
+Parameters:
+- path: (required) The path of the file to delete (relative to the current workspace directory %s)
+Usage:
+<delete_file>
+<path>File path here</path>
+</delete_file>
+
+Example: Requesting to delete an obsolete test fixture
+<delete_file>
+<path>testdata/old_fixture.json</path>
+</delete_file>`, args.Cwd)
+}
+
+func GetCreateDirectoryDescription(args ToolDescriptionGenArgs) string {
+	return fmt.Sprintf(`## create_directory
+Description: Request to create a directory, including any missing parent directories. Succeeds if the directory already exists. You don't need this before write_to_file, which creates directories automatically; use it for directories that should exist without files yet.
+Parameters:
+- path: (required) The path of the directory to create (relative to the current workspace directory %s)
+Usage:
+<create_directory>
+<path>Directory path here</path>
+</create_directory>
+
+Example: Requesting to create a directory for generated assets
+<create_directory>
+<path>public/assets/generated</path>
+</create_directory>`, args.Cwd)
+}
+
+func GetFetchURLDescription(args ToolDescriptionGenArgs) string {
+	return `## fetch_url
+Description: Request to fetch a web page and read it as markdown, e.g. API documentation, a changelog, or an issue the user linked to. Scripts, styles and navigation are removed, and long pages are truncated. Plain text and JSON responses are returned as is. Only http and https URLs are supported, and some domains may not be allowed by the configuration.
+Parameters:
+- url: (required) The full URL of the page to fetch, including http:// or https://
+Usage:
+<fetch_url>
+<url>URL here</url>
+</fetch_url>
+
+Example: Requesting to read a changelog
+<fetch_url>
+<url>https://github.com/gin-gonic/gin/blob/master/CHANGELOG.md</url>
+</fetch_url>`
+}
+
+func GetBrowserActionDescription(args ToolDescriptionGenArgs) string {
+	if !args.SupportsComputerUse {
+		return ""
+	}
+	return fmt.Sprintf(`## browser_action
+Description: Request to interact with a headless Chrome browser. Every action except close returns the current URL, the page title, the visible text of the page and the console logs and uncaught exceptions produced since the previous action. Use it to check web pages you created, e.g. a generated html file or a local development server.
+- The sequence of actions **must always start with** launching the browser at a URL, and **must always end with** closing the browser. If you need to visit a new URL, use the navigate action instead of launching again.
+- While the browser is active, only the browser_action tool can be used. No other tools should be called. If you need to use other tools, close the browser first and launch it again afterwards.
+- The browser window has a resolution of **%s** pixels. When performing any click actions, ensure the coordinates are within this resolution range.
+- Before clicking on any elements such as icons, links, or buttons, work out the coordinates of the element from the page layout; clicks are performed at the given pixel position.
+Parameters:
+- action: (required) The action to perform. The available actions are:
+    * launch: Launch a new browser instance at the specified URL. This **must always be the first action**.
+        - Use with the `+"`url`"+` parameter to provide the URL.
+    * navigate: Open another URL in the running browser.
+        - Use with the `+"`url`"+` parameter to provide the URL.
+    * click: Click at a specific x,y coordinate.
+        - Use with the `+"`coordinate`"+` parameter to specify the location.
+    * type: Type a string of text into the focused element. You might use this after clicking on a text field to input text.
+        - Use with the `+"`text`"+` parameter to provide the string to type.
+    * scroll_down: Scroll down the page by one page height.
+    * scroll_up: Scroll up the page by one page height.
+    * screenshot: Save a screenshot of the current viewport to a file and return its path, so the user can look at it.
+    * console_logs: Return the console logs produced since the previous action.
+    * close: Close the browser instance. This **must always be the final browser action**.
+- url: (optional) Use this for the launch and navigate actions. Provide a full URL such as http://localhost:3000, or the path of an html file (relative to the current workspace directory %s)
+- coordinate: (optional) The X and Y coordinates for the click action, within the **%s** resolution.
+    * Example: <coordinate>450,300</coordinate>
+- text: (optional) Use this for the type action.
+Usage:
+<browser_action>
+<action>Action to perform (e.g., launch, click, type, close)</action>
+<url>URL to launch the browser at (optional)</url>
+<coordinate>x,y coordinates (optional)</coordinate>
+<text>Text to type (optional)</text>
+</browser_action>
+
+Example: Requesting to open a generated page
+<browser_action>
+<action>launch</action>
+<url>index.html</url>
+</browser_action>
+
+Example: Requesting to click on the element at coordinates 450,300
+<browser_action>
+<action>click</action>
+<coordinate>450,300</coordinate>
+</browser_action>`, args.BrowserViewportSize, args.Cwd, args.BrowserViewportSize)
+}
+
+func GetUseMcpToolDescription(args ToolDescriptionGenArgs) string {
+	servers := connectedMcpServers(args.McpServers, func(server mcp.ServerInfo) bool { return len(server.Tools) > 0 })
+	if len(servers) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(`## use_mcp_tool
+Description: Request to use a tool provided by a connected MCP server. Each MCP server can provide multiple tools with different capabilities. Tools have defined input schemas that specify required and optional parameters.
+Parameters:
+- server_name: (required) The name of the MCP server providing the tool
+- tool_name: (required) The name of the tool to execute
+- arguments: (required) A JSON object containing the tool's input parameters, following the tool's input schema
+Usage:
+<use_mcp_tool>
+<server_name>server name here</server_name>
+<tool_name>tool name here</tool_name>
+<arguments>
+{
+  "param1": "value1",
+  "param2": "value2"
+}
+</arguments>
+</use_mcp_tool>
+
+Example: Requesting to use an MCP tool
+<use_mcp_tool>
+<server_name>weather-server</server_name>
+<tool_name>get_forecast</tool_name>
+<arguments>
+{
+  "city": "San Francisco",
+  "days": 5
+}
+</arguments>
+</use_mcp_tool>
+
+Available tools:`)
+	for _, server := range servers {
+		fmt.Fprintf(&b, "\n\n### %s", server.Name)
+		if server.Instructions != "" {
+			fmt.Fprintf(&b, "\n%s", server.Instructions)
+		}
+		for _, tool := range server.Tools {
+			fmt.Fprintf(&b, "\n- %s: %s", tool.Name, tool.Description)
+			if schema := formatMcpSchema(tool.InputSchema); schema != "" {
+				fmt.Fprintf(&b, "\n    Input Schema:\n    %s", strings.ReplaceAll(schema, "\n", "\n    "))
+			}
+		}
+	}
+	return b.String()
+}
+
+func GetAccessMcpResourceDescription(args ToolDescriptionGenArgs) string {
+	servers := connectedMcpServers(args.McpServers, func(server mcp.ServerInfo) bool {
+		return len(server.Resources) > 0 || len(server.ResourceTemplates) > 0
+	})
+	if len(servers) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(`## access_mcp_resource
+Description: Request to access a resource provided by a connected MCP server. Resources represent data sources that can be used as context, such as files, API responses, or system information.
+Parameters:
+- server_name: (required) The name of the MCP server providing the resource
+- uri: (required) The URI identifying the specific resource to access
+Usage:
+<access_mcp_resource>
+<server_name>server name here</server_name>
+<uri>resource URI here</uri>
+</access_mcp_resource>
+
+Example: Requesting to access an MCP resource
+<access_mcp_resource>
+<server_name>weather-server</server_name>
+<uri>weather://san-francisco/current</uri>
+</access_mcp_resource>
+
+Available resources:`)
+	for _, server := range servers {
+		fmt.Fprintf(&b, "\n\n### %s", server.Name)
+		for _, resource := range server.Resources {
+			fmt.Fprintf(&b, "\n- %s (%s)", resource.URI, resource.Name)
+			if resource.Description != "" {
+				fmt.Fprintf(&b, ": %s", resource.Description)
+			}
+		}
+		for _, template := range server.ResourceTemplates {
+			fmt.Fprintf(&b, "\n- %s (%s)", template.URITemplate, template.Name)
+			if template.Description != "" {
+				fmt.Fprintf(&b, ": %s", template.Description)
+			}
+		}
+	}
+	return b.String()
+}
+
+// connectedMcpServers returns the connected servers that have something to offer.
+func connectedMcpServers(servers []mcp.ServerInfo, offers func(mcp.ServerInfo) bool) []mcp.ServerInfo {
+	var connected []mcp.ServerInfo
+	for _, server := range servers {
+		if server.Status == mcp.StatusConnected && offers(server) {
+			connected = append(connected, server)
+		}
+	}
+	return connected
+}
+
+// formatMcpSchema indents a tool input schema for the prompt, empty when the tool has no schema.
+func formatMcpSchema(schema json.RawMessage) string {
+	if len(schema) == 0 {
+		return ""
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, schema, "", "  "); err != nil {
+		return string(schema)
+	}
+	return out.String()
+}
 
 // Map tool names to their description functions
 var toolDescriptionMap = map[toolgroups.ToolName]func(ToolDescriptionGenArgs) string{
@@ -285,16 +644,18 @@ var toolDescriptionMap = map[toolgroups.ToolName]func(ToolDescriptionGenArgs) st
 	toolgroups.ToolAskFollowupQuestion:     GetAskFollowupQuestionDescription,
 	toolgroups.ToolAttemptCompletion:       GetAttemptCompletionDescription,
 	toolgroups.ToolInsertContent:           GetInsertContentDescription,
+	toolgroups.ToolApplyDiff:               GetApplyDiffDescription,
+	toolgroups.ToolSearchAndReplace:        GetSearchAndReplaceDescription,
+	toolgroups.ToolBatchEdit:               GetBatchEditDescription,
+	toolgroups.ToolMoveFile:                GetMoveFileDescription,
+	toolgroups.ToolDeleteFile:              GetDeleteFileDescription,
+	toolgroups.ToolCreateDirectory:         GetCreateDirectoryDescription,
+	toolgroups.ToolFetchURL:                GetFetchURLDescription,
+	toolgroups.ToolBrowserAction:           GetBrowserActionDescription,
+	toolgroups.ToolUseMcpTool:              GetUseMcpToolDescription,
+	toolgroups.ToolAccessMcpResource:       GetAccessMcpResourceDescription,
+	toolgroups.ToolCodebaseSearch:          GetCodebaseSearchDescription,
 	// Add other tools here...
-	// toolgroups.ToolApplyDiff: func(args ToolDescriptionGenArgs) string {
-	//     if args.DiffStrategy != nil {
-	//         // Assuming DiffStrategy.GetToolDescription takes diff.ToolDescriptionArgs
-	//         diffArgs := diff.ToolDescriptionArgs{Cwd: args.Cwd}
-	//         return args.DiffStrategy.GetToolDescription(diffArgs)
-	//     }
-	//     return ""
-	// },
-	// ... browser_action, insert_content, search_and_replace ...
 
 }
 
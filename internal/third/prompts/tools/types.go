@@ -2,6 +2,7 @@ package tools
 
 import (
 	"mind-weaver/internal/third/diff" // Adjust
+	"mind-weaver/internal/third/mcp"
 )
 
 // ToolDescriptionGenArgs holds arguments for generating tool descriptions.
@@ -10,6 +11,7 @@ type ToolDescriptionGenArgs struct {
 	SupportsComputerUse bool
 	DiffStrategy        diff.DiffStrategy // Can be nil
 	BrowserViewportSize string
-	// McpHub // Add back if needed
+	McpServers          []mcp.ServerInfo // 已配置的 MCP 服务器，只有已连接的会出现在提示词中
+	CodebaseSearch      bool             // 是否启用了代码索引，未启用时不加入 codebase_search
 	// ToolOptions map[string]string // Keep if specific options are needed per tool description
 }
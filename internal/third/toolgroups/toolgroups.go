@@ -11,13 +11,20 @@ const (
 	ToolApplyDiff               ToolName = "apply_diff"
 	ToolInsertContent           ToolName = "insert_content"
 	ToolSearchAndReplace        ToolName = "search_and_replace"
+	ToolBatchEdit               ToolName = "batch_edit"
+	ToolMoveFile                ToolName = "move_file"
+	ToolDeleteFile              ToolName = "delete_file"
+	ToolCreateDirectory         ToolName = "create_directory"
 	ToolSearchFiles             ToolName = "search_files"
 	ToolListFiles               ToolName = "list_files"
 	ToolListCodeDefinitionNames ToolName = "list_code_definition_names"
+	ToolFetchURL                ToolName = "fetch_url"
 	ToolBrowserAction           ToolName = "browser_action"
+	ToolUseMcpTool              ToolName = "use_mcp_tool"
+	ToolAccessMcpResource       ToolName = "access_mcp_resource"
+	ToolCodebaseSearch          ToolName = "codebase_search"
 	ToolAskFollowupQuestion     ToolName = "ask_followup_question"
 	ToolAttemptCompletion       ToolName = "attempt_completion"
-	// Add any other tools like use_mcp_tool if re-added
 )
 
 // ToolGroupName identifies a category of tools.
@@ -27,9 +34,10 @@ type ToolGroupName string
 const (
 	GroupRead    ToolGroupName = "read"
 	GroupEdit    ToolGroupName = "edit"
+	GroupFiles   ToolGroupName = "files" // 移动、删除文件和创建目录，可以单独允许或禁止
 	GroupCommand ToolGroupName = "command"
 	GroupBrowser ToolGroupName = "browser"
-	GroupMcp     ToolGroupName = "mcp" // Keep if MCP tools might be re-added
+	GroupMcp     ToolGroupName = "mcp" // 配置文件中的 MCP 服务器提供的工具和资源
 )
 
 // ToolGroup defines the tools belonging to a specific group.
@@ -47,6 +55,8 @@ var TOOL_GROUPS = map[ToolGroupName]ToolGroup{
 			ToolSearchFiles,
 			ToolListFiles,
 			ToolListCodeDefinitionNames,
+			ToolFetchURL,       // 只读取网页，可访问的域名由配置限制
+			ToolCodebaseSearch, // 没有启用代码索引时不会出现在提示词中
 			// Add fetch_instructions if re-added
 		},
 	},
@@ -57,6 +67,15 @@ var TOOL_GROUPS = map[ToolGroupName]ToolGroup{
 			ToolApplyDiff,        // Diff strategy availability checked elsewhere
 			ToolInsertContent,    // Experiment availability checked elsewhere
 			ToolSearchAndReplace, // Experiment availability checked elsewhere
+			ToolBatchEdit,
+		},
+	},
+	GroupFiles: {
+		Name: GroupFiles,
+		Tools: []ToolName{
+			ToolMoveFile,
+			ToolDeleteFile,
+			ToolCreateDirectory,
 		},
 	},
 	GroupCommand: {
@@ -67,7 +86,10 @@ var TOOL_GROUPS = map[ToolGroupName]ToolGroup{
 		Name:  GroupBrowser,
 		Tools: []ToolName{ToolBrowserAction}, // Browser support checked elsewhere
 	},
-	// Add GroupMcp if needed
+	GroupMcp: {
+		Name:  GroupMcp,
+		Tools: []ToolName{ToolUseMcpTool, ToolAccessMcpResource}, // 没有连接的服务器时不会出现在提示词中
+	},
 }
 
 // ALWAYS_AVAILABLE_TOOLS lists tools accessible regardless of mode group configuration.
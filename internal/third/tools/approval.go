@@ -0,0 +1,188 @@
+package tools
+
+import (
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"mind-weaver/internal/third/assistantmessage"
+)
+
+// ApprovalDecision is the outcome of checking a tool call against an ApprovalPolicy.
+type ApprovalDecision string
+
+const (
+	ApprovalAllow ApprovalDecision = "allow" // 直接执行
+	ApprovalAsk   ApprovalDecision = "ask"   // 需要用户确认
+	ApprovalDeny  ApprovalDecision = "deny"  // 总是拒绝
+)
+
+// editTools 会修改文件的工具，路径匹配 WriteGlobs 时自动允许
+var editTools = map[assistantmessage.ToolUseName]bool{
+	assistantmessage.WriteToFile:      true,
+	assistantmessage.ApplyDiff:        true,
+	assistantmessage.InsertContent:    true,
+	assistantmessage.SearchAndReplace: true,
+	assistantmessage.BatchEdit:        true,
+	assistantmessage.MoveFile:         true,
+	assistantmessage.DeleteFile:       true,
+	assistantmessage.CreateDirectory:  true,
+}
+
+// 命令中包含这些符号时可能串联了其他命令，不能只凭前缀自动允许
+var commandChainPattern = regexp.MustCompile("&&|\\|\\||[;|&`\\n]|\\$\\(|>|<")
+
+// ApprovalPolicy 工具的自动批准策略，可以按项目或会话配置
+type ApprovalPolicy struct {
+	AllowTools       []string `json:"allow_tools"`       // 总是允许的工具，"*" 表示全部
+	DenyTools        []string `json:"deny_tools"`        // 总是拒绝的工具，优先级最高
+	WriteGlobs       []string `json:"write_globs"`       // 编辑类工具的路径(相对项目根目录)匹配时自动允许，如 "src/**"
+	CommandAllowlist []string `json:"command_allowlist"` // execute_command 的命令匹配时自动允许，* 匹配任意字符，如 "go test *"
+}
+
+// Decide checks a tool call. Paths are resolved against cwd, the project root.
+func (p *ApprovalPolicy) Decide(toolUse assistantmessage.ToolUse, cwd string) ApprovalDecision {
+	name := string(toolUse.Name)
+	if containsTool(p.DenyTools, name) {
+		return ApprovalDeny
+	}
+	// 这两个工具只是向用户传递信息，不会产生副作用
+	if toolUse.Name == assistantmessage.AttemptCompletion || toolUse.Name == assistantmessage.AskFollowupQuestion {
+		return ApprovalAllow
+	}
+	if containsTool(p.AllowTools, name) {
+		return ApprovalAllow
+	}
+
+	// 编辑类工具涉及的所有路径都匹配 WriteGlobs 时才自动允许
+	if paths := EditPaths(toolUse); len(paths) > 0 && p.allowsWrites(cwd, paths) {
+		return ApprovalAllow
+	}
+
+	if toolUse.Name == assistantmessage.ExecuteCommand {
+		command := strings.TrimSpace(toolUse.Params[string(assistantmessage.Command)])
+		if command != "" && !commandChainPattern.MatchString(command) {
+			for _, pattern := range p.CommandAllowlist {
+				if matchCommand(pattern, command) {
+					return ApprovalAllow
+				}
+			}
+		}
+	}
+
+	return ApprovalAsk
+}
+
+// allowsWrites reports whether every path is inside the project and matches a write glob.
+func (p *ApprovalPolicy) allowsWrites(cwd string, paths []string) bool {
+	for _, filePath := range paths {
+		relPath, ok := projectRelPath(cwd, filePath)
+		if !ok || !matchAnyGlob(p.WriteGlobs, relPath) {
+			return false
+		}
+	}
+	return true
+}
+
+// EditPaths returns the files an edit tool call writes (as given, relative or absolute), nil for other tools.
+func EditPaths(toolUse assistantmessage.ToolUse) []string {
+	if !editTools[toolUse.Name] {
+		return nil
+	}
+	if toolUse.Name == assistantmessage.MoveFile {
+		return []string{toolUse.Params[string(assistantmessage.Path)], toolUse.Params[string(assistantmessage.NewPath)]}
+	}
+	if toolUse.Name != assistantmessage.BatchEdit {
+		return []string{toolUse.Params[string(assistantmessage.Path)]}
+	}
+
+	operations, err := ParseBatchOperations(toolUse.Params[string(assistantmessage.Operations)])
+	if err != nil || len(operations) == 0 {
+		return nil
+	}
+	paths := []string{}
+	for _, op := range operations {
+		paths = append(paths, op.Path)
+		if op.NewPath != "" {
+			paths = append(paths, op.NewPath)
+		}
+	}
+	return paths
+}
+
+func matchAnyGlob(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if MatchGlob(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsTool(list []string, name string) bool {
+	for _, item := range list {
+		if item == "*" || item == name {
+			return true
+		}
+	}
+	return false
+}
+
+// projectRelPath returns p relative to cwd with forward slashes, false when p is
+// empty or points outside the project.
+func projectRelPath(cwd, p string) (string, bool) {
+	if strings.TrimSpace(p) == "" {
+		return "", false
+	}
+	absolutePath := p
+	if !filepath.IsAbs(p) {
+		absolutePath = filepath.Join(cwd, p)
+	}
+	rel, err := filepath.Rel(filepath.Clean(cwd), filepath.Clean(absolutePath))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// MatchGlob matches a slash separated path against a glob pattern where "**"
+// matches any number of directories, e.g. "src/**" or "**/*_test.go".
+func MatchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// ** 可以匹配零个或多个目录
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// matchCommand matches a whole command line, "*" matches any characters.
+func matchCommand(pattern, command string) bool {
+	parts := strings.Split(strings.TrimSpace(pattern), "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	re, err := regexp.Compile("^" + strings.Join(parts, ".*") + "$")
+	if err != nil {
+		return false
+	}
+	return re.MatchString(command)
+}
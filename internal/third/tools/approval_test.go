@@ -0,0 +1,168 @@
+package tools
+
+import (
+	"testing"
+
+	"mind-weaver/internal/third/assistantmessage"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "src/**", name: "src/main.go", want: true},
+		{pattern: "src/**", name: "src/api/handlers/user.go", want: true},
+		{pattern: "src/**", name: "srcx/main.go", want: false},
+		{pattern: "src/*.go", name: "src/api/user.go", want: false},
+		{pattern: "**/*_test.go", name: "internal/db/db_test.go", want: true},
+		{pattern: "**/*_test.go", name: "db_test.go", want: true},
+		{pattern: "docs/*.md", name: "docs/README.md", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := MatchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("MatchGlob(%q, %q): Expected %v, got %v", tt.pattern, tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestApprovalPolicyDecide(t *testing.T) {
+	policy := &ApprovalPolicy{
+		AllowTools:       []string{"read_file", "list_files"},
+		DenyTools:        []string{"browser_action"},
+		WriteGlobs:       []string{"src/**"},
+		CommandAllowlist: []string{"go test *", "npm run lint"},
+	}
+	cwd := "/work/project"
+
+	tool := func(name assistantmessage.ToolUseName, params map[string]string) assistantmessage.ToolUse {
+		return assistantmessage.ToolUse{Type: "tool_use", Name: name, Params: params}
+	}
+
+	tests := []struct {
+		name    string
+		toolUse assistantmessage.ToolUse
+		want    ApprovalDecision
+	}{
+		{
+			name:    "read tool allowed",
+			toolUse: tool(assistantmessage.ReadFile, map[string]string{"path": "/etc/passwd"}),
+			want:    ApprovalAllow,
+		},
+		{
+			name:    "denied tool",
+			toolUse: tool(assistantmessage.BrowserAction, nil),
+			want:    ApprovalDeny,
+		},
+		{
+			name:    "write inside glob",
+			toolUse: tool(assistantmessage.WriteToFile, map[string]string{"path": "src/app/main.go"}),
+			want:    ApprovalAllow,
+		},
+		{
+			name:    "absolute write inside glob",
+			toolUse: tool(assistantmessage.ApplyDiff, map[string]string{"path": "/work/project/src/main.go"}),
+			want:    ApprovalAllow,
+		},
+		{
+			name:    "write outside glob",
+			toolUse: tool(assistantmessage.WriteToFile, map[string]string{"path": "config/app.yaml"}),
+			want:    ApprovalAsk,
+		},
+		{
+			name:    "write escaping the project",
+			toolUse: tool(assistantmessage.WriteToFile, map[string]string{"path": "src/../../other/src/main.go"}),
+			want:    ApprovalAsk,
+		},
+		{
+			name:    "batch edit inside glob",
+			toolUse: tool(assistantmessage.BatchEdit, map[string]string{"operations": `[{"action":"move","path":"src/a.go","new_path":"src/b.go"}]`}),
+			want:    ApprovalAllow,
+		},
+		{
+			name:    "batch edit partly outside glob",
+			toolUse: tool(assistantmessage.BatchEdit, map[string]string{"operations": `[{"action":"delete","path":"src/a.go"},{"action":"delete","path":"go.mod"}]`}),
+			want:    ApprovalAsk,
+		},
+		{
+			name:    "move file out of glob",
+			toolUse: tool(assistantmessage.MoveFile, map[string]string{"path": "src/a.go", "new_path": "a.go"}),
+			want:    ApprovalAsk,
+		},
+		{
+			name:    "delete file inside glob",
+			toolUse: tool(assistantmessage.DeleteFile, map[string]string{"path": "src/a.go"}),
+			want:    ApprovalAllow,
+		},
+		{
+			name:    "allowlisted command",
+			toolUse: tool(assistantmessage.ExecuteCommand, map[string]string{"command": "go test ./..."}),
+			want:    ApprovalAllow,
+		},
+		{
+			name:    "chained command",
+			toolUse: tool(assistantmessage.ExecuteCommand, map[string]string{"command": "go test ./... && rm -rf /"}),
+			want:    ApprovalAsk,
+		},
+		{
+			name:    "other command",
+			toolUse: tool(assistantmessage.ExecuteCommand, map[string]string{"command": "npm run lint --fix"}),
+			want:    ApprovalAsk,
+		},
+		{
+			name:    "completion is always allowed",
+			toolUse: tool(assistantmessage.AttemptCompletion, map[string]string{"result": "done"}),
+			want:    ApprovalAllow,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Decide(tt.toolUse, cwd); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExecuteToolChecksApproval(t *testing.T) {
+	input := ExecutorInput{
+		ToolUse: assistantmessage.ToolUse{
+			Type:   "tool_use",
+			Name:   assistantmessage.ExecuteCommand,
+			Params: map[string]string{"command": "echo hi"},
+		},
+		Cwd:      t.TempDir(),
+		Approval: &ApprovalPolicy{},
+	}
+
+	res, err := ExecuteTool(input)
+	if err != nil {
+		t.Fatalf("ExecuteTool failed: %v", err)
+	}
+	if !res.NeedsApproval || !res.IsError {
+		t.Errorf("Expected the unconfirmed command to need approval, got %+v", res)
+	}
+
+	// 没有策略时同样需要确认
+	input.Approval = nil
+	res, err = ExecuteTool(input)
+	if err != nil {
+		t.Fatalf("ExecuteTool failed: %v", err)
+	}
+	if !res.NeedsApproval || !res.IsError {
+		t.Errorf("Expected the unconfirmed command without a policy to need approval, got %+v", res)
+	}
+
+	input.Approval = &ApprovalPolicy{DenyTools: []string{"execute_command"}}
+	input.Confirmed = true
+	res, err = ExecuteTool(input)
+	if err != nil {
+		t.Fatalf("ExecuteTool failed: %v", err)
+	}
+	if res.NeedsApproval || !res.IsError {
+		t.Errorf("Expected the denied command to fail even when confirmed, got %+v", res)
+	}
+}
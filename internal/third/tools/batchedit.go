@@ -0,0 +1,349 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mind-weaver/internal/third/assistantmessage"
+	"mind-weaver/internal/third/ignore"
+	"mind-weaver/internal/third/prompts"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// batch_edit 支持的操作
+const (
+	BatchActionCreate = "create" // 创建或覆盖文件
+	BatchActionPatch  = "patch"  // 用 apply_diff 的格式修改文件
+	BatchActionDelete = "delete" // 删除文件
+	BatchActionMove   = "move"   // 移动/重命名文件
+)
+
+// BatchOperation is one file operation of a batch_edit call.
+type BatchOperation struct {
+	Action  string  `json:"action"`
+	Path    string  `json:"path"`
+	Content *string `json:"content,omitempty"`  // create
+	Diff    string  `json:"diff,omitempty"`     // patch
+	NewPath string  `json:"new_path,omitempty"` // move
+}
+
+// ParseBatchOperations parses the operations parameter of batch_edit.
+func ParseBatchOperations(operationsJSON string) ([]BatchOperation, error) {
+	var operations []BatchOperation
+	if err := json.Unmarshal([]byte(operationsJSON), &operations); err != nil {
+		return nil, err
+	}
+	return operations, nil
+}
+
+// stagedFile 是文件在批量修改过程中的状态，original* 记录修改前磁盘上的内容用于回滚
+type stagedFile struct {
+	exists          bool
+	content         string
+	mode            os.FileMode
+	originalExists  bool
+	originalContent string
+}
+
+// batchStage 在内存中依次应用所有操作，全部成功后才写入磁盘
+type batchStage struct {
+	files map[string]*stagedFile
+}
+
+// load returns the staged state of a file, reading it from disk the first time.
+func (s *batchStage) load(absolutePath string) (*stagedFile, error) {
+	if file, ok := s.files[absolutePath]; ok {
+		return file, nil
+	}
+
+	file := &stagedFile{mode: 0644}
+	info, err := os.Stat(absolutePath)
+	switch {
+	case err == nil && info.IsDir():
+		return nil, errors.New("path is a directory, not a file")
+	case err == nil:
+		data, readErr := os.ReadFile(absolutePath)
+		if readErr != nil {
+			return nil, readErr
+		}
+		file.exists, file.originalExists = true, true
+		file.content, file.originalContent = string(data), string(data)
+		file.mode = info.Mode()
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+	s.files[absolutePath] = file
+	return file, nil
+}
+
+// BatchEditTool applies several file operations in one call. All operations are
+// validated in memory first; files are only written when every operation applies,
+// and already written files are restored if writing fails halfway.
+func BatchEditTool(input ExecutorInput) (*ExecutorResult, error) {
+	operationsJSON, ok := input.ToolUse.Params[string(assistantmessage.Operations)]
+	if !ok || strings.TrimSpace(operationsJSON) == "" {
+		errText := prompts.FormatMissingParamError(string(input.ToolUse.Name), string(assistantmessage.Operations))
+		return &ExecutorResult{Result: errText, IsError: true}, nil
+	}
+
+	operations, err := ParseBatchOperations(operationsJSON)
+	if err != nil {
+		errText := fmt.Sprintf("Invalid operations JSON format: %v", err)
+		return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil
+	}
+	if len(operations) == 0 {
+		return &ExecutorResult{Result: "No batch operations provided."}, nil
+	}
+
+	// 所有操作的路径都要在项目内并经过 .rooignore 检查，控制器只读取一次
+	rooIgnore, err := rooIgnoreController(input)
+	if err != nil {
+		return &ExecutorResult{Result: prompts.FormatToolError(err.Error()), IsError: true}, nil
+	}
+	input.RooIgnoreController = rooIgnore
+
+	stage := &batchStage{files: map[string]*stagedFile{}}
+	summary := make([]string, 0, len(operations))
+	for i, op := range operations {
+		line, opErr := stage.apply(input, op)
+		if opErr != nil {
+			errText := fmt.Sprintf("Operation %d (%s %s) failed: %v\nNo files were changed, fix the operation and retry the whole batch.", i+1, op.Action, op.Path, opErr)
+			return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil
+		}
+		summary = append(summary, line)
+	}
+
+	if err := stage.commit(); err != nil {
+		return nil, fmt.Errorf("writing batch edit: %w", err)
+	}
+
+	resultText := fmt.Sprintf("Batch edit successfully applied %d operations:\n- %s", len(operations), strings.Join(summary, "\n- "))
+	return &ExecutorResult{Result: resultText}, nil
+}
+
+// apply validates one operation against the staged files and updates them.
+func (s *batchStage) apply(input ExecutorInput, op BatchOperation) (string, error) {
+	if strings.TrimSpace(op.Path) == "" {
+		return "", errors.New("missing path")
+	}
+	absolutePath, err := resolveToolPath(input, op.Path)
+	if err != nil {
+		return "", err
+	}
+	file, err := s.load(absolutePath)
+	if err != nil {
+		return "", err
+	}
+
+	switch op.Action {
+	case BatchActionCreate:
+		if op.Content == nil {
+			return "", errors.New("missing content")
+		}
+		file.exists, file.content = true, *op.Content
+		return fmt.Sprintf("wrote %s", op.Path), nil
+
+	case BatchActionPatch:
+		if input.DiffStrategy == nil {
+			return "", errors.New("patch is not available, apply_diff is not enabled")
+		}
+		if strings.TrimSpace(op.Diff) == "" {
+			return "", errors.New("missing diff")
+		}
+		if !file.exists {
+			return "", errors.New("file does not exist")
+		}
+		diffResult, err := input.DiffStrategy.ApplyDiff(file.content, op.Diff, 0, 0)
+		if err != nil {
+			return "", err
+		}
+		// 批量修改要求每个修改块都成功，部分成功也视为失败
+		failures := []string{}
+		if diffResult.Error != "" {
+			failures = append(failures, diffResult.Error)
+		}
+		for _, part := range diffResult.FailParts {
+			if !part.Success {
+				failures = append(failures, part.Error)
+			}
+		}
+		if !diffResult.Success || len(failures) > 0 {
+			return "", fmt.Errorf("unable to apply diff: %s", strings.Join(failures, "; "))
+		}
+		file.content = diffResult.Content
+		return fmt.Sprintf("patched %s", op.Path), nil
+
+	case BatchActionDelete:
+		if !file.exists {
+			return "", errors.New("file does not exist")
+		}
+		file.exists, file.content = false, ""
+		return fmt.Sprintf("deleted %s", op.Path), nil
+
+	case BatchActionMove:
+		if strings.TrimSpace(op.NewPath) == "" {
+			return "", errors.New("missing new_path")
+		}
+		if !file.exists {
+			return "", errors.New("file does not exist")
+		}
+		newAbsolutePath, err := resolveToolPath(input, op.NewPath)
+		if err != nil {
+			return "", err
+		}
+		target, err := s.load(newAbsolutePath)
+		if err != nil {
+			return "", fmt.Errorf("new_path: %w", err)
+		}
+		if target == file {
+			return "", errors.New("new_path is the same as path")
+		}
+		if target.exists {
+			return "", fmt.Errorf("destination %s already exists", op.NewPath)
+		}
+		target.exists, target.content, target.mode = true, file.content, file.mode
+		file.exists, file.content = false, ""
+		return fmt.Sprintf("moved %s -> %s", op.Path, op.NewPath), nil
+	}
+
+	return "", fmt.Errorf("unknown action %q, expected one of create, patch, delete, move", op.Action)
+}
+
+// commit writes the staged files. On failure the files written so far are restored
+// and directories created by the batch are removed again.
+func (s *batchStage) commit() error {
+	paths := make([]string, 0, len(s.files))
+	for absolutePath := range s.files {
+		paths = append(paths, absolutePath)
+	}
+	sort.Strings(paths)
+
+	written := []string{}
+	createdDirs := []string{}
+	rollback := func() {
+		for i := len(written) - 1; i >= 0; i-- {
+			file := s.files[written[i]]
+			if file.originalExists {
+				writeFileAtomic(written[i], []byte(file.originalContent), file.mode)
+			} else {
+				os.Remove(written[i])
+			}
+		}
+		for i := len(createdDirs) - 1; i >= 0; i-- {
+			os.Remove(createdDirs[i]) // 只会删除空目录
+		}
+	}
+
+	for _, absolutePath := range paths {
+		file := s.files[absolutePath]
+		if file.exists == file.originalExists && file.content == file.originalContent {
+			continue
+		}
+
+		var err error
+		if file.exists {
+			var dirs []string
+			dirs, err = missingDirs(filepath.Dir(absolutePath))
+			if err == nil {
+				err = os.MkdirAll(filepath.Dir(absolutePath), 0755)
+				createdDirs = append(createdDirs, dirs...)
+			}
+			if err == nil {
+				err = writeFileAtomic(absolutePath, []byte(file.content), file.mode)
+			}
+		} else {
+			err = os.Remove(absolutePath)
+		}
+		if err != nil {
+			rollback()
+			return err
+		}
+		written = append(written, absolutePath)
+	}
+	return nil
+}
+
+// renameFile 测试中替换为会失败的实现
+var renameFile = os.Rename
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// into place, so a failed write never leaves path truncated.
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, mode.Perm())
+	}
+	if err == nil {
+		err = renameFile(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+	}
+	return err
+}
+
+// missingDirs returns dir and its ancestors that do not exist yet, outermost first.
+func missingDirs(dir string) ([]string, error) {
+	dirs := []string{}
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+		dirs = append([]string{dir}, dirs...)
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return dirs, nil
+}
+
+// resolveToolPath resolves a path of an edit tool against the workspace. Paths
+// outside of the workspace and paths matched by .rooignore are rejected.
+func resolveToolPath(input ExecutorInput, relPath string) (string, error) {
+	absolutePath := filepath.Join(input.Cwd, relPath)
+	if !filepath.IsAbs(relPath) {
+		absolutePath = filepath.Clean(absolutePath)
+	} else {
+		absolutePath = filepath.Clean(relPath)
+	}
+	if _, ok := projectRelPath(input.Cwd, absolutePath); !ok {
+		return "", fmt.Errorf("path is outside of the workspace: %s", relPath)
+	}
+
+	// Check rooignore
+	rooIgnore, err := rooIgnoreController(input)
+	if err != nil {
+		return "", err
+	}
+	if !rooIgnore.ValidateAccess(absolutePath) {
+		return "", errors.New(prompts.FormatRooIgnoreError(relPath))
+	}
+	return absolutePath, nil
+}
+
+// rooIgnoreController 返回输入中的 .rooignore 控制器，调用方没有提供时读取项目的 .rooignore
+func rooIgnoreController(input ExecutorInput) (*ignore.RooIgnoreController, error) {
+	if input.RooIgnoreController != nil {
+		return input.RooIgnoreController, nil
+	}
+	rooIgnore := ignore.NewRooIgnoreController(input.Cwd)
+	if err := rooIgnore.Initialize(); err != nil {
+		return nil, fmt.Errorf("failed to load .rooignore: %w", err)
+	}
+	return rooIgnore, nil
+}
@@ -0,0 +1,185 @@
+package tools
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mind-weaver/internal/third/assistantmessage"
+	"mind-weaver/internal/third/diff/multireplace"
+)
+
+func TestBatchEditTool(t *testing.T) {
+	files := map[string]string{
+		"a.go":      "package main\n\nfunc oldName() {}\n",
+		"b.go":      "package main\n\nfunc main() {\n\toldName()\n}\n",
+		"unused.go": "package main\n",
+	}
+	patch := func(search, replace string) string {
+		return "<<<<<<< SEARCH\n-------\n" + search + "\n=======\n" + replace + "\n>>>>>>> REPLACE"
+	}
+
+	tests := []struct {
+		name       string
+		operations string
+		wantError  bool
+		want       map[string]string // 期望的文件内容，空字符串表示文件不存在
+	}{
+		{
+			name: "all operations applied",
+			operations: `[
+				{"action": "patch", "path": "a.go", "diff": ` + quote(patch("func oldName() {}", "func newName() {}")) + `},
+				{"action": "move", "path": "b.go", "new_path": "cmd/main.go"},
+				{"action": "patch", "path": "cmd/main.go", "diff": ` + quote(patch("\toldName()", "\tnewName()")) + `},
+				{"action": "create", "path": "README.md", "content": "# demo\n"},
+				{"action": "delete", "path": "unused.go"}
+			]`,
+			want: map[string]string{
+				"a.go":        "package main\n\nfunc newName() {}\n",
+				"b.go":        "",
+				"cmd/main.go": "package main\n\nfunc main() {\n\tnewName()\n}\n",
+				"README.md":   "# demo\n",
+				"unused.go":   "",
+			},
+		},
+		{
+			name: "failing patch changes nothing",
+			operations: `[
+				{"action": "create", "path": "README.md", "content": "# demo\n"},
+				{"action": "delete", "path": "unused.go"},
+				{"action": "patch", "path": "a.go", "diff": ` + quote(patch("func missing() {}", "func x() {}")) + `}
+			]`,
+			wantError: true,
+			want:      map[string]string{"README.md": "", "unused.go": files["unused.go"], "a.go": files["a.go"]},
+		},
+		{
+			name:       "move onto existing file",
+			operations: `[{"action": "move", "path": "a.go", "new_path": "b.go"}]`,
+			wantError:  true,
+			want:       map[string]string{"a.go": files["a.go"], "b.go": files["b.go"]},
+		},
+		{
+			name: "operation outside workspace rejects the batch",
+			operations: `[
+				{"action": "create", "path": "README.md", "content": "# demo\n"},
+				{"action": "delete", "path": "../outside.txt"}
+			]`,
+			wantError: true,
+			want:      map[string]string{"README.md": "", "../outside.txt": "outside\n"},
+		},
+		{
+			name: "move out of workspace rejects the batch",
+			operations: `[
+				{"action": "delete", "path": "unused.go"},
+				{"action": "move", "path": "a.go", "new_path": "../a.go"}
+			]`,
+			wantError: true,
+			want:      map[string]string{"unused.go": files["unused.go"], "a.go": files["a.go"], "../a.go": ""},
+		},
+		{
+			name: "ignored file rejects the batch",
+			operations: `[
+				{"action": "delete", "path": "unused.go"},
+				{"action": "create", "path": "secret.env", "content": "TOKEN=2\n"}
+			]`,
+			wantError: true,
+			want:      map[string]string{"unused.go": files["unused.go"], "secret.env": "TOKEN=1\n"},
+		},
+		{
+			name:       "unknown action",
+			operations: `[{"action": "chmod", "path": "a.go"}]`,
+			wantError:  true,
+			want:       map[string]string{"a.go": files["a.go"]},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			dir := filepath.Join(root, "project")
+			os.Mkdir(dir, 0755)
+			for name, content := range files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			os.WriteFile(filepath.Join(dir, ".rooignore"), []byte("secret.env\n"), 0644)
+			os.WriteFile(filepath.Join(dir, "secret.env"), []byte("TOKEN=1\n"), 0644)
+			os.WriteFile(filepath.Join(root, "outside.txt"), []byte("outside\n"), 0644)
+
+			res, err := BatchEditTool(ExecutorInput{
+				ToolUse:      assistantmessage.ToolUse{Name: assistantmessage.BatchEdit, Params: map[string]string{"operations": tt.operations}},
+				Cwd:          dir,
+				DiffStrategy: multireplace.NewMultiSearchReplaceDiffStrategy(nil, nil),
+			})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if res.IsError != tt.wantError {
+				t.Errorf("Expected is error %v, got %v: %s", tt.wantError, res.IsError, res.Result)
+			}
+
+			for name, want := range tt.want {
+				content, err := os.ReadFile(filepath.Join(dir, name))
+				if want == "" {
+					if err == nil {
+						t.Errorf("Expected %s to not exist", name)
+					}
+					continue
+				}
+				if string(content) != want {
+					t.Errorf("Expected %s to be %q, got %q", name, want, string(content))
+				}
+			}
+		})
+	}
+}
+
+func TestBatchEditWriteFailure(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{"a.go": "package a\n", "b.go": "package b\n"}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 第二个文件写入失败
+	renames := 0
+	renameFile = func(oldPath, newPath string) error {
+		renames++
+		if renames == 2 {
+			return errors.New("disk full")
+		}
+		return os.Rename(oldPath, newPath)
+	}
+	defer func() { renameFile = os.Rename }()
+
+	_, err := BatchEditTool(ExecutorInput{
+		ToolUse: assistantmessage.ToolUse{Name: assistantmessage.BatchEdit, Params: map[string]string{"operations": `[
+			{"action": "create", "path": "a.go", "content": "changed\n"},
+			{"action": "create", "path": "b.go", "content": "changed\n"}
+		]`}},
+		Cwd: dir,
+	})
+	if err == nil {
+		t.Fatalf("Expected the write failure to be returned")
+	}
+
+	for name, want := range files {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(content) != want {
+			t.Errorf("Expected %s to keep %q, got %q, %v", name, want, string(content), err)
+		}
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != len(files) {
+		t.Errorf("Expected no temporary files to be left, got %d entries", len(entries))
+	}
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`).Replace(s) + `"`
+}
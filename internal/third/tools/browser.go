@@ -0,0 +1,411 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"mind-weaver/internal/third/assistantmessage"
+	"mind-weaver/internal/third/prompts"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/launcher"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// browser_action 支持的操作
+const (
+	BrowserLaunch      = "launch"       // 启动浏览器并打开 url
+	BrowserNavigate    = "navigate"     // 在已启动的浏览器中打开 url
+	BrowserClick       = "click"        // 点击 coordinate 位置
+	BrowserType        = "type"         // 在当前焦点输入 text
+	BrowserScrollDown  = "scroll_down"  // 向下滚动一屏
+	BrowserScrollUp    = "scroll_up"    // 向上滚动一屏
+	BrowserScreenshot  = "screenshot"   // 截图保存为文件
+	BrowserConsoleLogs = "console_logs" // 获取控制台日志
+	BrowserClose       = "close"        // 关闭浏览器
+)
+
+const (
+	browserActionTimeout = 30 * time.Second
+	// 返回给模型的页面文字的token上限
+	browserPageTextTokens = 1500
+)
+
+// BrowserOptions browser_action 工具的设置
+type BrowserOptions struct {
+	Bin            string // Chrome/Chromium 可执行文件，为空时查找本机安装的浏览器，找不到则自动下载
+	ViewportWidth  int
+	ViewportHeight int
+	ScreenshotDir  string // 截图的保存目录
+}
+
+// BrowserSession is the headless browser of one chat session. It stays open
+// between tool calls from launch until close.
+type BrowserSession struct {
+	mu       sync.Mutex
+	options  BrowserOptions
+	launcher *launcher.Launcher
+	browser  *rod.Browser
+	page     *rod.Page
+
+	logsMu sync.Mutex
+	logs   []string // 上次返回之后新产生的控制台日志
+}
+
+// NewBrowserSession creates a session, the browser is started by the launch action.
+func NewBrowserSession(options BrowserOptions) *BrowserSession {
+	if options.ViewportWidth <= 0 || options.ViewportHeight <= 0 {
+		options.ViewportWidth, options.ViewportHeight = 900, 600
+	}
+	return &BrowserSession{options: options}
+}
+
+// Running reports whether the browser has been launched.
+func (s *BrowserSession) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.browser != nil
+}
+
+// Close shuts the browser down. Closing a session that is not running is a no-op.
+func (s *BrowserSession) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeLocked()
+}
+
+func (s *BrowserSession) closeLocked() error {
+	var err error
+	if s.browser != nil {
+		err = s.browser.Close()
+	}
+	if s.launcher != nil {
+		s.launcher.Kill()
+		s.launcher.Cleanup()
+	}
+	s.browser, s.page, s.launcher = nil, nil, nil
+	s.takeLogs()
+	return err
+}
+
+func (s *BrowserSession) launchLocked() error {
+	bin := s.options.Bin
+	if bin == "" {
+		bin, _ = launcher.LookPath()
+	}
+	l := launcher.New().Headless(true).Leakless(false)
+	if bin != "" {
+		l = l.Bin(bin)
+	}
+	// 以 root 运行时 Chrome 必须关闭沙箱
+	if os.Geteuid() == 0 {
+		l = l.NoSandbox(true)
+	}
+	controlURL, err := l.Launch()
+	if err != nil {
+		l.Cleanup()
+		return fmt.Errorf("launching browser: %w", err)
+	}
+
+	browser := rod.New().ControlURL(controlURL)
+	if err := browser.Connect(); err != nil {
+		l.Kill()
+		l.Cleanup()
+		return fmt.Errorf("connecting to browser: %w", err)
+	}
+	page, err := browser.Page(proto.TargetCreateTarget{URL: "about:blank"})
+	if err == nil {
+		err = page.SetViewport(&proto.EmulationSetDeviceMetricsOverride{
+			Width:             s.options.ViewportWidth,
+			Height:            s.options.ViewportHeight,
+			DeviceScaleFactor: 1,
+		})
+	}
+	if err != nil {
+		browser.Close()
+		l.Kill()
+		l.Cleanup()
+		return fmt.Errorf("opening page: %w", err)
+	}
+
+	s.launcher, s.browser, s.page = l, browser, page
+	go page.EachEvent(func(e *proto.RuntimeConsoleAPICalled) {
+		s.addLog(fmt.Sprintf("[%s] %s", e.Type, formatConsoleArgs(e.Args)))
+	}, func(e *proto.RuntimeExceptionThrown) {
+		s.addLog("[exception] " + formatException(e.ExceptionDetails))
+	}, func(e *proto.PageJavascriptDialogOpening) {
+		// 弹窗会阻塞页面，自动关闭并记录内容
+		s.addLog(fmt.Sprintf("[%s dialog] %s", e.Type, e.Message))
+		go proto.PageHandleJavaScriptDialog{Accept: true}.Call(page)
+	})()
+	return nil
+}
+
+func (s *BrowserSession) addLog(line string) {
+	s.logsMu.Lock()
+	defer s.logsMu.Unlock()
+	s.logs = append(s.logs, line)
+}
+
+func (s *BrowserSession) takeLogs() []string {
+	s.logsMu.Lock()
+	defer s.logsMu.Unlock()
+	logs := s.logs
+	s.logs = nil
+	return logs
+}
+
+// BrowserActionTool controls a headless Chrome: launch a page, interact with it
+// and read back the page text, console logs and screenshots.
+func BrowserActionTool(input ExecutorInput) (*ExecutorResult, error) {
+	action := strings.TrimSpace(input.ToolUse.Params[string(assistantmessage.Action)])
+	if action == "" {
+		errText := prompts.FormatMissingParamError(string(input.ToolUse.Name), string(assistantmessage.Action))
+		return &ExecutorResult{Result: errText, IsError: true}, nil
+	}
+	session := input.Browser
+	if session == nil {
+		return &ExecutorResult{Result: prompts.FormatToolError("The browser is not enabled on this server."), IsError: true}, nil
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if action == BrowserClose {
+		if err := session.closeLocked(); err != nil {
+			return nil, fmt.Errorf("closing browser: %w", err)
+		}
+		return &ExecutorResult{Result: "The browser has been closed."}, nil
+	}
+	if action == BrowserLaunch {
+		if session.browser != nil {
+			return &ExecutorResult{Result: prompts.FormatToolError("The browser is already running. Use the navigate action to open another page, or close it first."), IsError: true}, nil
+		}
+	} else if session.browser == nil {
+		return &ExecutorResult{Result: prompts.FormatToolError("The browser is not running. Use the launch action first."), IsError: true}, nil
+	}
+
+	// 参数在启动浏览器之前校验，避免白白启动
+	var target string
+	var point proto.Point
+	switch action {
+	case BrowserLaunch, BrowserNavigate:
+		var errText string
+		if target, errText = browserTargetURL(input); errText != "" {
+			return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil
+		}
+	case BrowserClick:
+		var err error
+		if point, err = parseCoordinate(input.ToolUse.Params[string(assistantmessage.Coordinate)]); err != nil {
+			return &ExecutorResult{Result: prompts.FormatToolError(err.Error()), IsError: true}, nil
+		}
+	case BrowserType:
+		if _, ok := input.ToolUse.Params[string(assistantmessage.Text)]; !ok {
+			errText := prompts.FormatMissingParamError(string(input.ToolUse.Name), string(assistantmessage.Text))
+			return &ExecutorResult{Result: errText, IsError: true}, nil
+		}
+	case BrowserScrollDown, BrowserScrollUp, BrowserScreenshot, BrowserConsoleLogs:
+	default:
+		errText := fmt.Sprintf("Unknown browser action %q. Expected one of launch, navigate, click, type, scroll_down, scroll_up, screenshot, console_logs, close.", action)
+		return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil
+	}
+
+	if action == BrowserLaunch {
+		if err := session.launchLocked(); err != nil {
+			return &ExecutorResult{Result: prompts.FormatToolError(err.Error()), IsError: true}, nil
+		}
+	}
+
+	ctx := input.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	page := session.page.Context(ctx).Timeout(browserActionTimeout)
+
+	var err error
+	switch action {
+	case BrowserLaunch, BrowserNavigate:
+		err = page.Navigate(target)
+		if err == nil {
+			err = page.WaitLoad()
+		}
+	case BrowserClick:
+		err = page.Mouse.MoveTo(point)
+		if err == nil {
+			err = page.Mouse.Click(proto.InputMouseButtonLeft, 1)
+		}
+	case BrowserType:
+		err = page.InsertText(input.ToolUse.Params[string(assistantmessage.Text)])
+	case BrowserScrollDown, BrowserScrollUp:
+		offset := session.options.ViewportHeight
+		if action == BrowserScrollUp {
+			offset = -offset
+		}
+		_, err = page.Eval(`(offset) => window.scrollBy(0, offset)`, offset)
+	case BrowserScreenshot:
+		return session.screenshot(page)
+	case BrowserConsoleLogs:
+		return &ExecutorResult{Result: formatConsoleLogs(session.takeLogs())}, nil
+	}
+	if err != nil {
+		errText := fmt.Sprintf("Browser action %s failed: %v", action, err)
+		return &ExecutorResult{Result: prompts.FormatToolError(errText) + "\n\n" + formatConsoleLogs(session.takeLogs()), IsError: true}, nil
+	}
+
+	// 等待页面对操作做出反应，超时不算错误
+	page.Timeout(3 * time.Second).WaitStable(300 * time.Millisecond)
+	return &ExecutorResult{Result: session.describePage(page, action)}, nil
+}
+
+// describePage returns the state of the page after an action.
+func (s *BrowserSession) describePage(page *rod.Page, action string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Browser action %s completed.\n", action))
+	if info, err := page.Info(); err == nil {
+		sb.WriteString(fmt.Sprintf("URL: %s\nTitle: %s\n", info.URL, info.Title))
+	}
+	if res, err := page.Eval(`() => [window.scrollY, document.documentElement.scrollHeight, window.innerHeight]`); err == nil {
+		arr := res.Value.Arr()
+		if len(arr) == 3 {
+			sb.WriteString(fmt.Sprintf("Scroll position: %d of %d pixels (viewport height %d)\n", arr[0].Int(), arr[1].Int(), arr[2].Int()))
+		}
+	}
+	if res, err := page.Eval(`() => document.body ? document.body.innerText : ""`); err == nil {
+		text, truncated := truncateToTokens(strings.TrimSpace(res.Value.Str()), browserPageTextTokens)
+		if truncated {
+			text += "\n[Page text truncated]"
+		}
+		if text == "" {
+			text = "(no visible text)"
+		}
+		sb.WriteString("\nVisible text:\n" + text + "\n")
+	}
+	sb.WriteString("\n" + formatConsoleLogs(s.takeLogs()))
+	return sb.String()
+}
+
+func (s *BrowserSession) screenshot(page *rod.Page) (*ExecutorResult, error) {
+	data, err := page.Screenshot(false, &proto.PageCaptureScreenshot{Format: proto.PageCaptureScreenshotFormatPng})
+	if err != nil {
+		errText := fmt.Sprintf("Browser action screenshot failed: %v", err)
+		return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil
+	}
+
+	dir := s.options.ScreenshotDir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating screenshot directory %s: %w", dir, err)
+	}
+	screenshotPath := filepath.Join(dir, fmt.Sprintf("screenshot-%s.png", time.Now().Format("20060102-150405.000")))
+	if err := os.WriteFile(screenshotPath, data, 0644); err != nil {
+		return nil, fmt.Errorf("writing screenshot %s: %w", screenshotPath, err)
+	}
+	return &ExecutorResult{Result: fmt.Sprintf("The screenshot of the current viewport was saved to %s.", screenshotPath)}, nil
+}
+
+// browserTargetURL returns the URL to open. Paths of local files, such as a
+// generated html page, and file:// URLs must point inside the workspace and are
+// checked against .rooignore.
+func browserTargetURL(input ExecutorInput) (string, string) {
+	rawURL := strings.TrimSpace(input.ToolUse.Params[string(assistantmessage.URL)])
+	if rawURL == "" {
+		return "", prompts.FormatMissingParamError(string(input.ToolUse.Name), string(assistantmessage.URL))
+	}
+
+	filePath := rawURL
+	if parsed, err := url.Parse(rawURL); err == nil && parsed.Scheme != "" && len(parsed.Scheme) > 1 {
+		switch parsed.Scheme {
+		case "http", "https":
+			return rawURL, ""
+		case "file":
+			// 只支持本机文件，file://host/path 指向其他机器
+			if parsed.Host != "" && parsed.Host != "localhost" {
+				return "", fmt.Sprintf("Unsupported file URL %q. Use a path in the workspace.", rawURL)
+			}
+			filePath = filepath.FromSlash(parsed.Path)
+		default:
+			return "", fmt.Sprintf("Unsupported URL scheme %q. Use http, https, file or a path in the workspace.", parsed.Scheme)
+		}
+	}
+
+	absolutePath := filePath
+	if !filepath.IsAbs(absolutePath) {
+		absolutePath = filepath.Join(input.Cwd, filePath)
+	}
+	absolutePath = filepath.Clean(absolutePath)
+	if _, ok := projectRelPath(input.Cwd, absolutePath); !ok {
+		return "", fmt.Sprintf("File is outside of the workspace: %s. Only files in the workspace can be opened.", rawURL)
+	}
+
+	rooIgnore, err := rooIgnoreController(input)
+	if err != nil {
+		return "", err.Error()
+	}
+	if !rooIgnore.ValidateAccess(absolutePath) {
+		return "", prompts.FormatRooIgnoreError(rawURL)
+	}
+	if _, err := os.Stat(absolutePath); err != nil {
+		return "", fmt.Sprintf("File does not exist at path: %s. Use a full http(s) URL for web pages.", rawURL)
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(absolutePath)}).String(), ""
+}
+
+// parseCoordinate parses "x,y" in pixels.
+func parseCoordinate(coordinate string) (proto.Point, error) {
+	parts := strings.Split(strings.TrimSpace(coordinate), ",")
+	if len(parts) != 2 {
+		return proto.Point{}, fmt.Errorf("Invalid coordinate %q, expected x,y such as 450,300", coordinate)
+	}
+	x, errX := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	y, errY := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if errX != nil || errY != nil || x < 0 || y < 0 {
+		return proto.Point{}, fmt.Errorf("Invalid coordinate %q, expected x,y such as 450,300", coordinate)
+	}
+	return proto.Point{X: x, Y: y}, nil
+}
+
+func formatConsoleLogs(logs []string) string {
+	if len(logs) == 0 {
+		return "Console logs: (none)"
+	}
+	return "Console logs:\n" + strings.Join(logs, "\n")
+}
+
+func formatConsoleArgs(args []*proto.RuntimeRemoteObject) string {
+	parts := make([]string, 0, len(args))
+	for _, arg := range args {
+		switch {
+		case arg.Type == proto.RuntimeRemoteObjectTypeString:
+			parts = append(parts, arg.Value.Str())
+		case arg.Description != "":
+			parts = append(parts, arg.Description)
+		case arg.UnserializableValue != "":
+			parts = append(parts, string(arg.UnserializableValue))
+		default:
+			parts = append(parts, arg.Value.JSON("", ""))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatException(details *proto.RuntimeExceptionDetails) string {
+	if details == nil {
+		return "unknown error"
+	}
+	message := details.Text
+	if details.Exception != nil && details.Exception.Description != "" {
+		message = details.Exception.Description
+	}
+	if details.URL != "" {
+		message += fmt.Sprintf(" (%s:%d:%d)", details.URL, details.LineNumber+1, details.ColumnNumber+1)
+	}
+	return message
+}
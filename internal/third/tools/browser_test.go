@@ -0,0 +1,178 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-rod/rod/lib/launcher"
+
+	"mind-weaver/internal/third/assistantmessage"
+)
+
+func TestParseCoordinate(t *testing.T) {
+	tests := []struct {
+		coordinate string
+		wantX      float64
+		wantY      float64
+		wantErr    bool
+	}{
+		{coordinate: "450,300", wantX: 450, wantY: 300},
+		{coordinate: " 12.5 , 8 ", wantX: 12.5, wantY: 8},
+		{coordinate: "450", wantErr: true},
+		{coordinate: "a,b", wantErr: true},
+		{coordinate: "-1,5", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		point, err := parseCoordinate(tt.coordinate)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCoordinate(%q): Expected error %v, got %v", tt.coordinate, tt.wantErr, err)
+			continue
+		}
+		if !tt.wantErr && (point.X != tt.wantX || point.Y != tt.wantY) {
+			t.Errorf("parseCoordinate(%q): Expected %v,%v, got %v,%v", tt.coordinate, tt.wantX, tt.wantY, point.X, point.Y)
+		}
+	}
+}
+
+func TestBrowserActionValidation(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, ".rooignore"), []byte("secret.html\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "secret.html"), []byte("<html></html>"), 0644)
+	session := NewBrowserSession(BrowserOptions{})
+
+	tests := []struct {
+		name       string
+		browser    *BrowserSession
+		params     map[string]string
+		wantResult string
+	}{
+		{
+			name:       "browser disabled",
+			params:     map[string]string{"action": "launch", "url": "https://example.com"},
+			wantResult: "not enabled",
+		},
+		{
+			name:       "action before launch",
+			browser:    session,
+			params:     map[string]string{"action": "click", "coordinate": "10,10"},
+			wantResult: "Use the launch action first",
+		},
+		{
+			name:       "missing file",
+			browser:    session,
+			params:     map[string]string{"action": "launch", "url": "missing.html"},
+			wantResult: "File does not exist",
+		},
+		{
+			name:       "file url outside workspace",
+			browser:    session,
+			params:     map[string]string{"action": "launch", "url": "file:///etc/passwd"},
+			wantResult: "outside of the workspace",
+		},
+		{
+			name:       "absolute path outside workspace",
+			browser:    session,
+			params:     map[string]string{"action": "launch", "url": filepath.Join(filepath.Dir(dir), "other", "index.html")},
+			wantResult: "outside of the workspace",
+		},
+		{
+			name:       "ignored file",
+			browser:    session,
+			params:     map[string]string{"action": "launch", "url": "file://" + filepath.ToSlash(filepath.Join(dir, "secret.html"))},
+			wantResult: "blocked by the .rooignore",
+		},
+		{
+			name:       "unsupported scheme",
+			browser:    session,
+			params:     map[string]string{"action": "launch", "url": "ftp://example.com"},
+			wantResult: "Unsupported URL scheme",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := BrowserActionTool(ExecutorInput{
+				ToolUse: assistantmessage.ToolUse{Name: assistantmessage.BrowserAction, Params: tt.params},
+				Cwd:     dir,
+				Browser: tt.browser,
+			})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !res.IsError || !strings.Contains(res.Result, tt.wantResult) {
+				t.Errorf("Expected error containing %q, got %q", tt.wantResult, res.Result)
+			}
+		})
+	}
+	if session.Running() {
+		t.Errorf("Expected the browser not to be launched by invalid actions")
+	}
+}
+
+// TestBrowserActionPage runs against a locally installed Chrome/Chromium.
+func TestBrowserActionPage(t *testing.T) {
+	bin, ok := launcher.LookPath()
+	if !ok {
+		t.Skip("no local Chrome or Chromium found")
+	}
+
+	dir := t.TempDir()
+	page := `<html><head><title>Counter</title></head><body>
+<button id="add" style="position:absolute;left:0;top:0;width:200px;height:100px">Add</button>
+<input id="name" style="position:absolute;left:0;top:200px">
+<p id="count">count: 0</p>
+<script>
+let count = 0;
+document.getElementById("add").onclick = () => { count++; document.getElementById("count").textContent = "count: " + count; console.log("clicked", count); };
+undefinedFunction();
+</script></body></html>`
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte(page), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	session := NewBrowserSession(BrowserOptions{Bin: bin, ScreenshotDir: filepath.Join(dir, "screenshots")})
+	defer session.Close()
+	run := func(params map[string]string) *ExecutorResult {
+		t.Helper()
+		res, err := BrowserActionTool(ExecutorInput{
+			ToolUse: assistantmessage.ToolUse{Name: assistantmessage.BrowserAction, Params: params},
+			Cwd:     dir,
+			Browser: session,
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if res.IsError {
+			t.Fatalf("Expected %s to succeed, got %s", params["action"], res.Result)
+		}
+		return res
+	}
+
+	res := run(map[string]string{"action": "launch", "url": "index.html"})
+	for _, want := range []string{"Title: Counter", "count: 0", "undefinedFunction is not defined"} {
+		if !strings.Contains(res.Result, want) {
+			t.Errorf("Expected launch result to contain %q, got %q", want, res.Result)
+		}
+	}
+
+	res = run(map[string]string{"action": "click", "coordinate": "100,50"})
+	if !strings.Contains(res.Result, "count: 1") || !strings.Contains(res.Result, "[log] clicked 1") {
+		t.Errorf("Expected click to update the page and log, got %q", res.Result)
+	}
+
+	run(map[string]string{"action": "click", "coordinate": "20,210"})
+	run(map[string]string{"action": "type", "text": "gopher"})
+
+	res = run(map[string]string{"action": "screenshot"})
+	if entries, _ := os.ReadDir(filepath.Join(dir, "screenshots")); len(entries) != 1 {
+		t.Errorf("Expected 1 screenshot, got %d: %s", len(entries), res.Result)
+	}
+
+	run(map[string]string{"action": "close"})
+	if session.Running() {
+		t.Errorf("Expected the browser to be closed")
+	}
+}
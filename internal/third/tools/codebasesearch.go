@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"mind-weaver/internal/third/assistantmessage"
+	"mind-weaver/internal/third/prompts"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// codebase_search 返回的结果数
+	codebaseSearchResults = 10
+	// 每个结果最多展示的行数，需要更多内容时模型可以用 read_file 读取
+	codebaseSearchResultLines = 40
+	// 搜索结果的token上限
+	maxCodebaseSearchTokens = 8000
+)
+
+// CodebaseSearchTool finds the code chunks of the project closest in meaning to
+// a natural language query.
+func CodebaseSearchTool(input ExecutorInput) (*ExecutorResult, error) {
+	query, ok := input.ToolUse.Params[string(assistantmessage.Query)]
+	if !ok || strings.TrimSpace(query) == "" {
+		errText := prompts.FormatMissingParamError(string(input.ToolUse.Name), string(assistantmessage.Query))
+		return &ExecutorResult{Result: errText, IsError: true}, nil
+	}
+	if input.CodeIndex == nil {
+		errText := "The code index is not enabled. Use search_files to search with a regex instead."
+		return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil
+	}
+
+	// path 可选，只搜索该目录下的文件
+	pathPrefix := ""
+	if relPath := strings.TrimSpace(input.ToolUse.Params[string(assistantmessage.Path)]); relPath != "" {
+		absolutePath := filepath.Clean(filepath.Join(input.Cwd, relPath))
+		if filepath.IsAbs(relPath) {
+			absolutePath = filepath.Clean(relPath)
+		}
+		rel, err := filepath.Rel(input.Cwd, absolutePath)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			errText := fmt.Sprintf("Search path is outside of the workspace: %s", relPath)
+			return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil
+		}
+		pathPrefix = filepath.ToSlash(rel)
+	}
+
+	ctx := input.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	results, err := input.CodeIndex.Search(ctx, input.Cwd, strings.TrimSpace(query), pathPrefix, codebaseSearchResults)
+	if err != nil {
+		errText := fmt.Sprintf("Failed to search the code index: %v", err)
+		return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil
+	}
+
+	if len(results) == 0 {
+		return &ExecutorResult{Result: "No matching code found."}, nil
+	}
+
+	// .rooignore 忽略的文件不会进入索引，这里不需要再过滤
+	var b strings.Builder
+	for _, result := range results {
+		fmt.Fprintf(&b, "# %s:%d-%d", result.Path, result.StartLine, result.EndLine)
+		if result.Symbol != "" {
+			fmt.Fprintf(&b, " (%s %s)", result.Kind, result.Symbol)
+		}
+		fmt.Fprintf(&b, " score %.2f\n", result.Score)
+		lines := strings.Split(result.Content, "\n")
+		for i, line := range lines {
+			if i == codebaseSearchResultLines {
+				fmt.Fprintf(&b, "... (%d more lines)\n", len(lines)-i)
+				break
+			}
+			fmt.Fprintf(&b, "%d | %s\n", result.StartLine+i, line)
+		}
+		b.WriteString("\n")
+	}
+	text, truncated := truncateToTokens(strings.TrimRight(b.String(), "\n"), maxCodebaseSearchTokens)
+	if truncated {
+		text += "\n\n[Results truncated to fit the token budget.]"
+	}
+	return &ExecutorResult{Result: text}, nil
+}
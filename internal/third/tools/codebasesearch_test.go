@@ -0,0 +1,55 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"mind-weaver/internal/codeindex"
+	"mind-weaver/internal/third/assistantmessage"
+)
+
+type fakeCodeSearcher struct {
+	pathPrefix string
+	results    []codeindex.Result
+}
+
+func (f *fakeCodeSearcher) Search(ctx context.Context, projectPath, query, pathPrefix string, limit int) ([]codeindex.Result, error) {
+	f.pathPrefix = pathPrefix
+	return f.results, nil
+}
+
+func TestCodebaseSearchTool(t *testing.T) {
+	searcher := &fakeCodeSearcher{results: []codeindex.Result{{
+		Chunk: codeindex.Chunk{Path: "billing/refund.go", StartLine: 3, EndLine: 4, Symbol: "RetryRefund", Kind: "function", Content: "func RetryRefund() {\n}"},
+		Score: 0.82,
+	}}}
+	run := func(params map[string]string, searcher CodeSearcher) *ExecutorResult {
+		t.Helper()
+		result, err := CodebaseSearchTool(ExecutorInput{
+			ToolUse:   assistantmessage.ToolUse{Name: assistantmessage.CodebaseSearch, Params: params},
+			Cwd:       "/project",
+			CodeIndex: searcher,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	result := run(map[string]string{"query": "refund retries", "path": "billing/"}, searcher)
+	want := "# billing/refund.go:3-4 (function RetryRefund) score 0.82\n3 | func RetryRefund() {\n4 | }"
+	if result.IsError || result.Result != want || searcher.pathPrefix != "billing" {
+		t.Errorf("Expected %q under billing, got %q (prefix %q)", want, result.Result, searcher.pathPrefix)
+	}
+
+	if result := run(map[string]string{"query": "refund", "path": "../other"}, searcher); !result.IsError || !strings.Contains(result.Result, "outside of the workspace") {
+		t.Errorf("Expected an error for a path outside of the workspace, got %q", result.Result)
+	}
+	if result := run(map[string]string{"query": "refund"}, nil); !result.IsError || !strings.Contains(result.Result, "not enabled") {
+		t.Errorf("Expected an error without code index, got %q", result.Result)
+	}
+	if result := run(map[string]string{"query": "nothing"}, &fakeCodeSearcher{}); result.IsError || result.Result != "No matching code found." {
+		t.Errorf("Expected no matches, got %q", result.Result)
+	}
+}
@@ -0,0 +1,188 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mind-weaver/internal/third/assistantmessage"
+	"mind-weaver/internal/third/markdown"
+	"mind-weaver/internal/third/prompts"
+	"mind-weaver/internal/tokenizer"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 网页最多读取的字节数，超过的部分直接丢弃
+const maxFetchBodySize = 5 << 20
+
+// FetchOptions fetch_url 工具的设置
+type FetchOptions struct {
+	AllowedDomains []string      // 允许访问的域名，同时允许子域名，为空时不限制
+	MaxTokens      int           // 返回内容的token上限，<=0 时默认8000
+	Timeout        time.Duration // 请求超时，<=0 时默认30秒
+	Client         *http.Client  // 为空时使用默认的客户端
+}
+
+// AllowsHost reports whether host (without port) is in the domain allowlist.
+func (o *FetchOptions) AllowsHost(host string) bool {
+	if o == nil || len(o.AllowedDomains) == 0 {
+		return true
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, domain := range o.AllowedDomains {
+		domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "*."))
+		if domain == "*" || host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// FetchURLTool downloads a web page and returns it as markdown, truncated to the
+// token budget. Non-HTML text such as JSON or plain text is returned as is.
+func FetchURLTool(input ExecutorInput) (*ExecutorResult, error) {
+	rawURL, ok := input.ToolUse.Params[string(assistantmessage.URL)]
+	if !ok || strings.TrimSpace(rawURL) == "" {
+		errText := prompts.FormatMissingParamError(string(input.ToolUse.Name), string(assistantmessage.URL))
+		return &ExecutorResult{Result: errText, IsError: true}, nil
+	}
+	rawURL = strings.TrimSpace(rawURL)
+
+	options := input.Fetch
+	if options == nil {
+		options = &FetchOptions{}
+	}
+
+	target, err := url.Parse(rawURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		errText := fmt.Sprintf("Invalid URL: %s. Only http and https URLs are supported.", rawURL)
+		return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil
+	}
+	if !options.AllowsHost(target.Hostname()) {
+		return &ExecutorResult{Result: prompts.FormatToolError(domainNotAllowedError(target.Hostname(), options)), IsError: true}, nil
+	}
+
+	ctx := input.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request for %s: %w", rawURL, err)
+	}
+	req.Header.Set("User-Agent", "mind-weaver/1.0 (fetch_url)")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/markdown,text/plain,application/json;q=0.9,*/*;q=0.5")
+
+	client := http.Client{}
+	if options.Client != nil {
+		client = *options.Client
+	}
+	// 重定向后的域名同样需要在允许列表中
+	client.CheckRedirect = func(redirect *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		if !options.AllowsHost(redirect.URL.Hostname()) {
+			return errors.New(domainNotAllowedError(redirect.URL.Hostname(), options))
+		}
+		return nil
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		errText := fmt.Sprintf("Failed to fetch %s: %v", rawURL, unwrapURLError(err))
+		return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		errText := fmt.Sprintf("Failed to fetch %s: HTTP %s", rawURL, resp.Status)
+		return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchBodySize))
+	if err != nil {
+		errText := fmt.Sprintf("Failed to read the response of %s: %v", rawURL, err)
+		return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "" {
+		mediaType = http.DetectContentType(body)
+		mediaType, _, _ = mime.ParseMediaType(mediaType)
+	}
+
+	title := ""
+	content := ""
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		doc, err := markdown.FromHTML(strings.NewReader(string(body)), resp.Request.URL)
+		if err != nil {
+			errText := fmt.Sprintf("Failed to parse the HTML of %s: %v", rawURL, err)
+			return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil
+		}
+		title, content = doc.Title, doc.Markdown
+	case strings.HasPrefix(mediaType, "text/") || mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") ||
+		mediaType == "application/xml" || strings.HasSuffix(mediaType, "+xml") || mediaType == "application/javascript":
+		content = strings.TrimSpace(string(body))
+	default:
+		errText := fmt.Sprintf("Unsupported content type %q at %s, only web pages and text can be fetched.", mediaType, rawURL)
+		return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil
+	}
+
+	content, truncated := truncateToTokens(content, options.MaxTokens)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Fetched %s", resp.Request.URL.String()))
+	if title != "" {
+		sb.WriteString(fmt.Sprintf(" (%s)", title))
+	}
+	sb.WriteString(":\n\n")
+	if content == "" {
+		sb.WriteString("(The page has no readable content.)")
+	} else {
+		sb.WriteString(content)
+	}
+	if truncated {
+		sb.WriteString("\n\n[Content truncated to fit the token budget. Fetch a more specific page if the information you need is missing.]")
+	}
+	return &ExecutorResult{Result: sb.String()}, nil
+}
+
+// truncateToTokens cuts content to maxTokens tokens of the default tokenizer,
+// at a line break when possible.
+func truncateToTokens(content string, maxTokens int) (string, bool) {
+	if maxTokens <= 0 {
+		maxTokens = 8000
+	}
+	return tokenizer.Truncate(tokenizer.Default(), content, maxTokens)
+}
+
+func domainNotAllowedError(host string, options *FetchOptions) string {
+	return fmt.Sprintf("Domain %s is not in the fetch_url allowlist (%s).", host, strings.Join(options.AllowedDomains, ", "))
+}
+
+// unwrapURLError drops the "Get <url>:" prefix the http client adds.
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return errors.New("request timed out")
+	}
+	return err
+}
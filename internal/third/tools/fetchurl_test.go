@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mind-weaver/internal/third/assistantmessage"
+)
+
+func TestFetchURLTool(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/docs", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><title>API Docs</title><script>track()</script></head><body><h1>Client</h1><p>Call <code>New()</code> first. See <a href="/changelog">changes</a>.</p></body></html>`))
+	})
+	mux.HandleFunc("/changelog", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(strings.Repeat("- fixed a bug\n", 100)))
+	})
+	mux.HandleFunc("/data.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"version": "1.2.0"}`))
+	})
+	mux.HandleFunc("/logo.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte{0x89, 'P', 'N', 'G'})
+	})
+	mux.HandleFunc("/away", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://localhost.invalid/", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		url        string
+		options    *FetchOptions
+		wantResult []string
+		notResult  []string
+		wantError  bool
+	}{
+		{
+			name:       "html converted to markdown",
+			url:        server.URL + "/docs",
+			wantResult: []string{"(API Docs)", "# Client", "Call `New()` first. See [changes](" + server.URL + "/changelog)."},
+			notResult:  []string{"track()"},
+		},
+		{
+			name:       "plain text truncated",
+			url:        server.URL + "/changelog",
+			options:    &FetchOptions{MaxTokens: 20},
+			wantResult: []string{"- fixed a bug", "[Content truncated"},
+		},
+		{
+			name:       "json returned as is",
+			url:        server.URL + "/data.json",
+			wantResult: []string{`{"version": "1.2.0"}`},
+		},
+		{
+			name:       "domain allowed",
+			url:        server.URL + "/data.json",
+			options:    &FetchOptions{AllowedDomains: []string{"127.0.0.1"}},
+			wantResult: []string{"1.2.0"},
+		},
+		{
+			name:       "domain not allowed",
+			url:        server.URL + "/data.json",
+			options:    &FetchOptions{AllowedDomains: []string{"golang.org"}},
+			wantResult: []string{"not in the fetch_url allowlist"},
+			wantError:  true,
+		},
+		{
+			name:       "redirect to domain not allowed",
+			url:        server.URL + "/away",
+			options:    &FetchOptions{AllowedDomains: []string{"127.0.0.1"}},
+			wantResult: []string{"localhost.invalid is not in the fetch_url allowlist"},
+			wantError:  true,
+		},
+		{
+			name:       "not found",
+			url:        server.URL + "/missing",
+			wantResult: []string{"HTTP 404"},
+			wantError:  true,
+		},
+		{
+			name:       "binary content",
+			url:        server.URL + "/logo.png",
+			wantResult: []string{"Unsupported content type"},
+			wantError:  true,
+		},
+		{
+			name:       "unsupported scheme",
+			url:        "file:///etc/passwd",
+			wantResult: []string{"Invalid URL"},
+			wantError:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := FetchURLTool(ExecutorInput{
+				ToolUse: assistantmessage.ToolUse{Name: assistantmessage.FetchURL, Params: map[string]string{"url": tt.url}},
+				Fetch:   tt.options,
+			})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if res.IsError != tt.wantError {
+				t.Errorf("Expected is error %v, got %v: %s", tt.wantError, res.IsError, res.Result)
+			}
+			for _, want := range tt.wantResult {
+				if !strings.Contains(res.Result, want) {
+					t.Errorf("Expected result to contain %q, got %q", want, res.Result)
+				}
+			}
+			for _, notWant := range tt.notResult {
+				if strings.Contains(res.Result, notWant) {
+					t.Errorf("Expected result not to contain %q, got %q", notWant, res.Result)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchOptionsAllowsHost(t *testing.T) {
+	options := &FetchOptions{AllowedDomains: []string{"golang.org", "*.github.com"}}
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{host: "golang.org", want: true},
+		{host: "pkg.golang.org", want: true},
+		{host: "notgolang.org", want: false},
+		{host: "raw.github.com", want: true},
+		{host: "github.com", want: true},
+		{host: "example.com", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := options.AllowsHost(tt.host); got != tt.want {
+			t.Errorf("AllowsHost(%q): Expected %v, got %v", tt.host, tt.want, got)
+		}
+	}
+}
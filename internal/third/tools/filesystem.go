@@ -0,0 +1,129 @@
+package tools
+
+import (
+	"fmt"
+	"mind-weaver/internal/third/assistantmessage"
+	"mind-weaver/internal/third/prompts"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MoveFileTool moves or renames a file. The destination must not exist, missing
+// parent directories of the destination are created.
+func MoveFileTool(input ExecutorInput) (*ExecutorResult, error) {
+	relPath, ok := input.ToolUse.Params[string(assistantmessage.Path)]
+	if !ok || strings.TrimSpace(relPath) == "" {
+		errText := prompts.FormatMissingParamError(string(input.ToolUse.Name), string(assistantmessage.Path))
+		return &ExecutorResult{Result: errText, IsError: true}, nil
+	}
+	newRelPath, ok := input.ToolUse.Params[string(assistantmessage.NewPath)]
+	if !ok || strings.TrimSpace(newRelPath) == "" {
+		errText := prompts.FormatMissingParamError(string(input.ToolUse.Name), string(assistantmessage.NewPath))
+		return &ExecutorResult{Result: errText, IsError: true}, nil
+	}
+
+	absolutePath, err := resolveToolPath(input, relPath)
+	if err != nil {
+		return &ExecutorResult{Result: prompts.FormatToolError(err.Error()), IsError: true}, nil
+	}
+	newAbsolutePath, err := resolveToolPath(input, newRelPath)
+	if err != nil {
+		return &ExecutorResult{Result: prompts.FormatToolError(err.Error()), IsError: true}, nil
+	}
+	if absolutePath == newAbsolutePath {
+		return &ExecutorResult{Result: prompts.FormatToolError("new_path is the same as path"), IsError: true}, nil
+	}
+
+	// 只移动文件，目录的移动无法保存检查点
+	if errText := checkRegularFile(absolutePath, relPath); errText != "" {
+		return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil
+	}
+	if _, err := os.Lstat(newAbsolutePath); err == nil {
+		errText := fmt.Sprintf("Destination already exists: %s. Delete it first or choose another path.", newRelPath)
+		return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("stating file %s: %w", newAbsolutePath, err)
+	}
+
+	dir := filepath.Dir(newAbsolutePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating directory %s: %w", dir, err)
+	}
+	if err := os.Rename(absolutePath, newAbsolutePath); err != nil {
+		return nil, fmt.Errorf("moving file %s to %s: %w", absolutePath, newAbsolutePath, err)
+	}
+
+	return &ExecutorResult{Result: fmt.Sprintf("The file was successfully moved from %s to %s.", relPath, newRelPath)}, nil
+}
+
+// DeleteFileTool deletes a single file. Directories are rejected.
+func DeleteFileTool(input ExecutorInput) (*ExecutorResult, error) {
+	relPath, ok := input.ToolUse.Params[string(assistantmessage.Path)]
+	if !ok || strings.TrimSpace(relPath) == "" {
+		errText := prompts.FormatMissingParamError(string(input.ToolUse.Name), string(assistantmessage.Path))
+		return &ExecutorResult{Result: errText, IsError: true}, nil
+	}
+
+	absolutePath, err := resolveToolPath(input, relPath)
+	if err != nil {
+		return &ExecutorResult{Result: prompts.FormatToolError(err.Error()), IsError: true}, nil
+	}
+	if errText := checkRegularFile(absolutePath, relPath); errText != "" {
+		return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil
+	}
+
+	if err := os.Remove(absolutePath); err != nil {
+		return nil, fmt.Errorf("deleting file %s: %w", absolutePath, err)
+	}
+
+	return &ExecutorResult{Result: fmt.Sprintf("The file %s was successfully deleted.", relPath)}, nil
+}
+
+// CreateDirectoryTool creates a directory and any missing parents.
+func CreateDirectoryTool(input ExecutorInput) (*ExecutorResult, error) {
+	relPath, ok := input.ToolUse.Params[string(assistantmessage.Path)]
+	if !ok || strings.TrimSpace(relPath) == "" {
+		errText := prompts.FormatMissingParamError(string(input.ToolUse.Name), string(assistantmessage.Path))
+		return &ExecutorResult{Result: errText, IsError: true}, nil
+	}
+
+	absolutePath, err := resolveToolPath(input, relPath)
+	if err != nil {
+		return &ExecutorResult{Result: prompts.FormatToolError(err.Error()), IsError: true}, nil
+	}
+
+	info, err := os.Stat(absolutePath)
+	switch {
+	case err == nil && info.IsDir():
+		return &ExecutorResult{Result: fmt.Sprintf("The directory %s already exists.", relPath)}, nil
+	case err == nil:
+		errText := fmt.Sprintf("A file already exists at path: %s", relPath)
+		return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil
+	case !os.IsNotExist(err):
+		// 例如某一级父路径是文件
+		errText := fmt.Sprintf("Cannot create directory %s: %v", relPath, err)
+		return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil
+	}
+
+	if err := os.MkdirAll(absolutePath, 0755); err != nil {
+		return nil, fmt.Errorf("creating directory %s: %w", absolutePath, err)
+	}
+
+	return &ExecutorResult{Result: fmt.Sprintf("The directory %s was successfully created.", relPath)}, nil
+}
+
+// checkRegularFile returns an error text for the LLM when the path is missing or not a file.
+func checkRegularFile(absolutePath, relPath string) string {
+	info, err := os.Lstat(absolutePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Sprintf("File does not exist at path: %s", relPath)
+		}
+		return fmt.Sprintf("Unable to access %s: %v", relPath, err)
+	}
+	if info.IsDir() {
+		return fmt.Sprintf("Path is a directory, not a file: %s", relPath)
+	}
+	return ""
+}
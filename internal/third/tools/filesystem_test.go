@@ -0,0 +1,159 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mind-weaver/internal/third/assistantmessage"
+)
+
+func TestFileSystemTools(t *testing.T) {
+	tests := []struct {
+		name      string
+		tool      assistantmessage.ToolUseName
+		params    map[string]string
+		wantError bool
+		exists    []string
+		missing   []string
+	}{
+		{
+			name:    "move file into new directory",
+			tool:    assistantmessage.MoveFile,
+			params:  map[string]string{"path": "a.go", "new_path": "pkg/b.go"},
+			exists:  []string{"pkg/b.go"},
+			missing: []string{"a.go"},
+		},
+		{
+			name:      "move onto existing file",
+			tool:      assistantmessage.MoveFile,
+			params:    map[string]string{"path": "a.go", "new_path": "docs/README.md"},
+			wantError: true,
+			exists:    []string{"a.go", "docs/README.md"},
+		},
+		{
+			name:      "move missing file",
+			tool:      assistantmessage.MoveFile,
+			params:    map[string]string{"path": "missing.go", "new_path": "b.go"},
+			wantError: true,
+			missing:   []string{"b.go"},
+		},
+		{
+			name:      "move directory",
+			tool:      assistantmessage.MoveFile,
+			params:    map[string]string{"path": "docs", "new_path": "doc"},
+			wantError: true,
+			exists:    []string{"docs/README.md"},
+		},
+		{
+			name:    "delete file",
+			tool:    assistantmessage.DeleteFile,
+			params:  map[string]string{"path": "a.go"},
+			missing: []string{"a.go"},
+		},
+		{
+			name:      "delete directory",
+			tool:      assistantmessage.DeleteFile,
+			params:    map[string]string{"path": "docs"},
+			wantError: true,
+			exists:    []string{"docs/README.md"},
+		},
+		{
+			name:      "delete ignored file",
+			tool:      assistantmessage.DeleteFile,
+			params:    map[string]string{"path": "secret.env"},
+			wantError: true,
+			exists:    []string{"secret.env"},
+		},
+		{
+			name:      "move ignored file",
+			tool:      assistantmessage.MoveFile,
+			params:    map[string]string{"path": "secret.env", "new_path": "public.env"},
+			wantError: true,
+			exists:    []string{"secret.env"},
+			missing:   []string{"public.env"},
+		},
+		{
+			name:      "delete file outside workspace",
+			tool:      assistantmessage.DeleteFile,
+			params:    map[string]string{"path": "../outside.txt"},
+			wantError: true,
+			exists:    []string{"../outside.txt"},
+		},
+		{
+			name:      "move file out of workspace",
+			tool:      assistantmessage.MoveFile,
+			params:    map[string]string{"path": "a.go", "new_path": "../a.go"},
+			wantError: true,
+			exists:    []string{"a.go"},
+			missing:   []string{"../a.go"},
+		},
+		{
+			name:      "move absolute path outside workspace",
+			tool:      assistantmessage.MoveFile,
+			params:    map[string]string{"path": "{outside}", "new_path": "outside.txt"},
+			wantError: true,
+			exists:    []string{"../outside.txt"},
+			missing:   []string{"outside.txt"},
+		},
+		{
+			name:   "create nested directory",
+			tool:   assistantmessage.CreateDirectory,
+			params: map[string]string{"path": "internal/new/pkg"},
+			exists: []string{"internal/new/pkg"},
+		},
+		{
+			name:   "create existing directory",
+			tool:   assistantmessage.CreateDirectory,
+			params: map[string]string{"path": "docs"},
+			exists: []string{"docs/README.md"},
+		},
+		{
+			name:      "create directory over file",
+			tool:      assistantmessage.CreateDirectory,
+			params:    map[string]string{"path": "a.go/sub"},
+			wantError: true,
+			exists:    []string{"a.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			dir := filepath.Join(root, "project")
+			os.MkdirAll(filepath.Join(dir, "docs"), 0755)
+			os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n"), 0644)
+			os.WriteFile(filepath.Join(dir, "docs", "README.md"), []byte("# docs\n"), 0644)
+			os.WriteFile(filepath.Join(dir, ".rooignore"), []byte("secret.env\n"), 0644)
+			os.WriteFile(filepath.Join(dir, "secret.env"), []byte("TOKEN=1\n"), 0644)
+			os.WriteFile(filepath.Join(root, "outside.txt"), []byte("outside\n"), 0644)
+
+			params := map[string]string{}
+			for k, v := range tt.params {
+				params[k] = strings.ReplaceAll(v, "{outside}", filepath.Join(root, "outside.txt"))
+			}
+			res, err := ExecuteTool(ExecutorInput{
+				ToolUse:   assistantmessage.ToolUse{Name: tt.tool, Params: params},
+				Cwd:       dir,
+				Confirmed: true,
+			})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if res.IsError != tt.wantError {
+				t.Errorf("Expected is error %v, got %v: %s", tt.wantError, res.IsError, res.Result)
+			}
+			for _, p := range tt.exists {
+				if _, err := os.Stat(filepath.Join(dir, p)); err != nil {
+					t.Errorf("Expected %s to exist, got %v", p, err)
+				}
+			}
+			for _, p := range tt.missing {
+				if _, err := os.Stat(filepath.Join(dir, p)); !os.IsNotExist(err) {
+					t.Errorf("Expected %s to be missing, got %v", p, err)
+				}
+			}
+		})
+	}
+}
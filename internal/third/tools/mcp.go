@@ -0,0 +1,129 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mind-weaver/internal/third/assistantmessage"
+	"mind-weaver/internal/third/mcp"
+	"mind-weaver/internal/third/prompts"
+	"strings"
+)
+
+// MCP 工具结果的token上限，超出部分截断
+const maxMcpResultTokens = 8000
+
+// UseMcpToolTool calls a tool of a connected MCP server.
+func UseMcpToolTool(input ExecutorInput) (*ExecutorResult, error) {
+	serverName, toolName := input.ToolUse.Params[string(assistantmessage.ServerName)], input.ToolUse.Params[string(assistantmessage.ToolName)]
+	if res := checkMcpParams(input, map[assistantmessage.ToolParamName]string{
+		assistantmessage.ServerName: serverName,
+		assistantmessage.ToolName:   toolName,
+	}); res != nil {
+		return res, nil
+	}
+
+	var arguments map[string]any
+	if rawArgs := strings.TrimSpace(input.ToolUse.Params[string(assistantmessage.Arguments)]); rawArgs != "" {
+		if err := json.Unmarshal([]byte(rawArgs), &arguments); err != nil {
+			errText := fmt.Sprintf("Invalid JSON in arguments: %v. The arguments must be a JSON object matching the tool's input schema.", err)
+			return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil
+		}
+	}
+
+	result, err := input.Mcp.CallTool(mcpContext(input), strings.TrimSpace(serverName), strings.TrimSpace(toolName), arguments)
+	if err != nil {
+		errText := fmt.Sprintf("Failed to call tool '%s' on MCP server '%s': %v", toolName, serverName, err)
+		return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil
+	}
+
+	text := formatMcpContent(result.Content)
+	if text == "" {
+		text = "(No response)"
+	}
+	text, truncated := truncateToTokens(text, maxMcpResultTokens)
+	if truncated {
+		text += "\n\n[Result truncated to fit the token budget.]"
+	}
+	if result.IsError {
+		return &ExecutorResult{Result: prompts.FormatToolError(text), IsError: true}, nil
+	}
+	return &ExecutorResult{Result: text}, nil
+}
+
+// AccessMcpResourceTool reads a resource of a connected MCP server.
+func AccessMcpResourceTool(input ExecutorInput) (*ExecutorResult, error) {
+	serverName, uri := input.ToolUse.Params[string(assistantmessage.ServerName)], input.ToolUse.Params[string(assistantmessage.URI)]
+	if res := checkMcpParams(input, map[assistantmessage.ToolParamName]string{
+		assistantmessage.ServerName: serverName,
+		assistantmessage.URI:        uri,
+	}); res != nil {
+		return res, nil
+	}
+
+	result, err := input.Mcp.ReadResource(mcpContext(input), strings.TrimSpace(serverName), strings.TrimSpace(uri))
+	if err != nil {
+		errText := fmt.Sprintf("Failed to read resource '%s' from MCP server '%s': %v", uri, serverName, err)
+		return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil
+	}
+
+	parts := make([]string, 0, len(result.Contents))
+	for _, contents := range result.Contents {
+		parts = append(parts, formatMcpResource(contents))
+	}
+	text := strings.Join(parts, "\n\n")
+	if text == "" {
+		text = "(Empty response)"
+	}
+	text, truncated := truncateToTokens(text, maxMcpResultTokens)
+	if truncated {
+		text += "\n\n[Resource truncated to fit the token budget.]"
+	}
+	return &ExecutorResult{Result: text}, nil
+}
+
+// checkMcpParams returns an error result when MCP is not configured or a required parameter is missing.
+func checkMcpParams(input ExecutorInput, params map[assistantmessage.ToolParamName]string) *ExecutorResult {
+	for _, name := range []assistantmessage.ToolParamName{assistantmessage.ServerName, assistantmessage.ToolName, assistantmessage.URI} {
+		if value, ok := params[name]; ok && strings.TrimSpace(value) == "" {
+			return &ExecutorResult{Result: prompts.FormatMissingParamError(string(input.ToolUse.Name), string(name)), IsError: true}
+		}
+	}
+	if input.Mcp == nil || len(input.Mcp.Servers()) == 0 {
+		return &ExecutorResult{Result: prompts.FormatToolError("No MCP servers are configured."), IsError: true}
+	}
+	return nil
+}
+
+func mcpContext(input ExecutorInput) context.Context {
+	if input.Ctx == nil {
+		return context.Background()
+	}
+	return input.Ctx
+}
+
+// formatMcpContent turns tool result content into text. Images and audio are
+// replaced by a placeholder since the model only receives text.
+func formatMcpContent(content []mcp.Content) string {
+	parts := make([]string, 0, len(content))
+	for _, item := range content {
+		switch item.Type {
+		case "text":
+			parts = append(parts, item.Text)
+		case "resource":
+			if item.Resource != nil {
+				parts = append(parts, formatMcpResource(*item.Resource))
+			}
+		default:
+			parts = append(parts, fmt.Sprintf("[%s: %s]", item.Type, item.MimeType))
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func formatMcpResource(contents mcp.ResourceContents) string {
+	if contents.Blob != "" && contents.Text == "" {
+		return fmt.Sprintf("[binary resource %s: %s]", contents.URI, contents.MimeType)
+	}
+	return contents.Text
+}
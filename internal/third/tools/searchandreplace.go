@@ -11,6 +11,17 @@ import (
 	"strings"
 )
 
+// searchReplaceOperation is one entry of the search_and_replace operations array.
+type searchReplaceOperation struct {
+	Search     string  `json:"search"`
+	Replace    string  `json:"replace"`
+	StartLine  *int    `json:"start_line,omitempty"` // Use pointers for optional ints (1-based)
+	EndLine    *int    `json:"end_line,omitempty"`   // Use pointers for optional ints (1-based)
+	UseRegex   bool    `json:"use_regex,omitempty"`
+	IgnoreCase bool    `json:"ignore_case,omitempty"`
+	RegexFlags *string `json:"regex_flags,omitempty"` // Use pointer for optional string
+}
+
 // SearchAndReplaceTool performs find and replace operations on a file.
 func SearchAndReplaceTool(input ExecutorInput) (*ExecutorResult, error) {
 	relPath, ok := input.ToolUse.Params[string(assistantmessage.Path)]
@@ -23,6 +34,7 @@ func SearchAndReplaceTool(input ExecutorInput) (*ExecutorResult, error) {
 		errText := prompts.FormatMissingParamError(string(input.ToolUse.Name), string(assistantmessage.Operations))
 		return &ExecutorResult{Result: errText, IsError: true}, nil
 	}
+	dryRun := strings.TrimSpace(strings.ToLower(input.ToolUse.Params[string(assistantmessage.DryRun)])) == "true"
 
 	absolutePath := filepath.Join(input.Cwd, relPath)
 	if !filepath.IsAbs(relPath) {
@@ -50,15 +62,7 @@ func SearchAndReplaceTool(input ExecutorInput) (*ExecutorResult, error) {
 	}
 
 	// Parse operations JSON
-	var parsedOperations []struct {
-		Search     string  `json:"search"`
-		Replace    string  `json:"replace"`
-		StartLine  *int    `json:"start_line,omitempty"` // Use pointers for optional ints (1-based)
-		EndLine    *int    `json:"end_line,omitempty"`   // Use pointers for optional ints (1-based)
-		UseRegex   bool    `json:"use_regex,omitempty"`
-		IgnoreCase bool    `json:"ignore_case,omitempty"`
-		RegexFlags *string `json:"regex_flags,omitempty"` // Use pointer for optional string
-	}
+	var parsedOperations []searchReplaceOperation
 	err = json.Unmarshal([]byte(operationsJSON), &parsedOperations)
 	if err != nil {
 		errText := fmt.Sprintf("Invalid operations JSON format: %v", err)
@@ -74,56 +78,33 @@ func SearchAndReplaceTool(input ExecutorInput) (*ExecutorResult, error) {
 	if err != nil {
 		return nil, fmt.Errorf("reading file for search/replace %s: %w", absolutePath, err)
 	}
-	currentContent := string(originalContentBytes)
-	originalContentForDiff := currentContent // Keep original for final diff message if needed
+	originalContent := string(originalContentBytes)
+	currentContent := originalContent
 
 	// Apply operations sequentially
+	warnings := []string{}
 	for i, op := range parsedOperations {
-		var searchRe *regexp.Regexp
-		var compileErr error
-
-		if op.UseRegex {
-			// Build regex flags string
-			flags := ""
-			if op.RegexFlags != nil {
-				flags = *op.RegexFlags // Use provided flags
-			} else if op.IgnoreCase {
-				flags = "i" // Default to ignore case if specified
-			}
-			// Ensure multiline (?m) and global (implicit via ReplaceAllString)
-			// Go regex doesn't have a direct 'g' flag, ReplaceAll handles it.
-			// Add (?m) for ^$ matching lines, (?i) for case-insensitivity.
-			pattern := op.Search
-			finalFlags := ""
-			if !strings.Contains(flags, "m") {
-				finalFlags += "m"
-			}
-			if !strings.Contains(flags, "i") && op.IgnoreCase {
-				finalFlags += "i"
-			}
-			// Add other flags if needed, filtering duplicates
-			// ... logic to parse and add flags from op.RegexFlags or flags string ...
-
-			if finalFlags != "" {
-				pattern = fmt.Sprintf("(?%s)%s", finalFlags, pattern)
-			}
-
-			searchRe, compileErr = regexp.Compile(pattern)
-		} else {
-			// Literal search - escape regex metacharacters
-			pattern := regexp.QuoteMeta(op.Search)
-			if op.IgnoreCase {
-				pattern = "(?i)" + pattern // Add case-insensitive flag
-			}
-			searchRe, compileErr = regexp.Compile(pattern)
+		if op.Search == "" {
+			errText := fmt.Sprintf("Empty search pattern (operation %d)", i+1)
+			return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil
 		}
 
+		searchRe, compileErr := compileSearchPattern(op)
 		if compileErr != nil {
 			errText := fmt.Sprintf("Invalid search pattern (operation %d): %v", i+1, compileErr)
 			return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil
 		}
 
+		// 字面量模式下替换内容中的 $ 不做展开
+		replace := func(content string) string {
+			if op.UseRegex {
+				return searchRe.ReplaceAllString(content, op.Replace)
+			}
+			return searchRe.ReplaceAllLiteralString(content, op.Replace)
+		}
+
 		// Handle line ranges if specified
+		var matches int
 		if op.StartLine != nil || op.EndLine != nil {
 			lines := strings.Split(currentContent, "\n")
 			startIdx := 0 // 0-based
@@ -136,48 +117,85 @@ func SearchAndReplaceTool(input ExecutorInput) (*ExecutorResult, error) {
 			}
 
 			if startIdx > endIdx || startIdx >= len(lines) {
-				// Invalid range or starts after file ends, skip operation? Or error?
-				fmt.Printf("Warning: Invalid or out-of-bounds line range (%d-%d) for operation %d on file %s. Skipping.\n", startIdx+1, endIdx+1, i+1, relPath)
-				continue
+				errText := fmt.Sprintf("Invalid line range %d-%d (operation %d), the file has %d lines", startIdx+1, endIdx+1, i+1, len(lines))
+				return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil
 			}
 
-			targetLines := lines[startIdx : endIdx+1]
-			targetContent := strings.Join(targetLines, "\n")
-			modifiedSection := searchRe.ReplaceAllString(targetContent, op.Replace)
+			targetContent := strings.Join(lines[startIdx:endIdx+1], "\n")
+			matches = len(searchRe.FindAllStringIndex(targetContent, -1))
+			modifiedSection := strings.Split(replace(targetContent), "\n")
 
 			// Reconstruct the content
-			var builder strings.Builder
-			if startIdx > 0 {
-				builder.WriteString(strings.Join(lines[:startIdx], "\n"))
-				builder.WriteString("\n") // Add newline removed by split if needed
-			}
-			builder.WriteString(modifiedSection)
-			if endIdx+1 < len(lines) {
-				builder.WriteString("\n") // Add newline removed by split if needed
-				builder.WriteString(strings.Join(lines[endIdx+1:], "\n"))
-			}
-			currentContent = builder.String()
-
+			newLines := make([]string, 0, len(lines)-(endIdx+1-startIdx)+len(modifiedSection))
+			newLines = append(newLines, lines[:startIdx]...)
+			newLines = append(newLines, modifiedSection...)
+			newLines = append(newLines, lines[endIdx+1:]...)
+			currentContent = strings.Join(newLines, "\n")
 		} else {
 			// Global replace on the whole content
-			currentContent = searchRe.ReplaceAllString(currentContent, op.Replace)
+			matches = len(searchRe.FindAllStringIndex(currentContent, -1))
+			currentContent = replace(currentContent)
+		}
+
+		if matches == 0 {
+			warnings = append(warnings, fmt.Sprintf("operation %d: no matches found for %q", i+1, op.Search))
 		}
 	} // End loop through operations
 
-	// --- Write the modified content ---
-	if currentContent == originalContentForDiff {
-		return &ExecutorResult{Result: fmt.Sprintf("No changes needed for '%s' after search/replace.", relPath)}, nil
+	warningText := ""
+	if len(warnings) > 0 {
+		warningText = "\nWarning: " + strings.Join(warnings, "; ")
 	}
 
+	if currentContent == originalContent {
+		return &ExecutorResult{Result: fmt.Sprintf("No changes needed for '%s' after search/replace.%s", relPath, warningText)}, nil
+	}
+
+	diffSummary := prompts.CreatePrettyPatch(filepath.ToSlash(relPath), originalContent, currentContent)
+
+	// 预览模式只返回修改后的差异，不写入文件
+	if dryRun {
+		return &ExecutorResult{Result: fmt.Sprintf("Dry run, %s was not modified. Changes that would be applied:\n%s%s", relPath, diffSummary, warningText)}, nil
+	}
+
+	// --- Write the modified content ---
 	err = os.WriteFile(absolutePath, []byte(currentContent), info.Mode()) // Preserve original permissions
 	if err != nil {
 		return nil, fmt.Errorf("writing search/replaced content to file %s: %w", absolutePath, err)
 	}
 
-	// Success message (maybe include a diff summary?)
-	// diffSummary := prompts.CreatePrettyPatch(relPath, originalContentForDiff, currentContent)
-	resultText := fmt.Sprintf("Search and replace operations successfully applied to %s.", relPath)
-	// if diffSummary != "" { resultText += "\nChanges Applied:\n" + diffSummary }
-
+	resultText := fmt.Sprintf("Search and replace operations successfully applied to %s.\nChanges Applied:\n%s%s", relPath, diffSummary, warningText)
 	return &ExecutorResult{Result: resultText}, nil
 }
+
+// compileSearchPattern builds the regexp for an operation. Literal searches are
+// quoted, regex searches get (?m) so ^ and $ match at line boundaries.
+func compileSearchPattern(op searchReplaceOperation) (*regexp.Regexp, error) {
+	if !op.UseRegex {
+		pattern := regexp.QuoteMeta(op.Search)
+		if op.IgnoreCase {
+			pattern = "(?i)" + pattern
+		}
+		return regexp.Compile(pattern)
+	}
+
+	flags := "m"
+	if op.IgnoreCase {
+		flags += "i"
+	}
+	if op.RegexFlags != nil {
+		for _, flag := range *op.RegexFlags {
+			switch flag {
+			case 'i', 'm', 's', 'U':
+				if !strings.ContainsRune(flags, flag) {
+					flags += string(flag)
+				}
+			case 'g':
+				// Go 没有 g 标志，ReplaceAll 本身就是全局替换
+			default:
+				return nil, fmt.Errorf("unsupported regex flag %q", flag)
+			}
+		}
+	}
+	return regexp.Compile(fmt.Sprintf("(?%s)%s", flags, op.Search))
+}
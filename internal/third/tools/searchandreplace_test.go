@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mind-weaver/internal/third/assistantmessage"
+)
+
+func TestSearchAndReplaceTool(t *testing.T) {
+	const original = "func oldName() {}\n\nfunc caller() {\n\toldName()\n\tOLDNAME()\n}\n\nvar price = \"$5\"\n"
+
+	tests := []struct {
+		name       string
+		operations string
+		dryRun     bool
+		want       string
+		wantResult string
+		wantError  bool
+	}{
+		{
+			name:       "literal rename",
+			operations: `[{"search": "oldName", "replace": "newName"}]`,
+			want:       strings.ReplaceAll(original, "oldName", "newName"),
+			wantResult: "+\tnewName()",
+		},
+		{
+			name:       "literal replace keeps dollar signs",
+			operations: `[{"search": "\"$5\"", "replace": "\"$10\""}]`,
+			want:       strings.Replace(original, `"$5"`, `"$10"`, 1),
+		},
+		{
+			name:       "ignore case within line range",
+			operations: `[{"search": "oldname", "replace": "newName", "ignore_case": true, "start_line": 4, "end_line": 5}]`,
+			want:       strings.Replace(strings.Replace(original, "\toldName()", "\tnewName()", 1), "OLDNAME", "newName", 1),
+		},
+		{
+			name:       "regex with capture group",
+			operations: `[{"search": "^func (\\w+)\\(\\)", "replace": "func ${1}V2()", "use_regex": true}]`,
+			want:       strings.Replace(strings.Replace(original, "func oldName()", "func oldNameV2()", 1), "func caller()", "func callerV2()", 1),
+		},
+		{
+			name:       "dry run does not write",
+			operations: `[{"search": "caller", "replace": "invoker"}]`,
+			dryRun:     true,
+			want:       original,
+			wantResult: "+func invoker() {",
+		},
+		{
+			name:       "invalid regex",
+			operations: `[{"search": "(", "replace": "x", "use_regex": true}]`,
+			want:       original,
+			wantError:  true,
+		},
+		{
+			name:       "invalid line range",
+			operations: `[{"search": "x", "replace": "y", "start_line": 50}]`,
+			want:       original,
+			wantError:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "main.go")
+			if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			params := map[string]string{"path": "main.go", "operations": tt.operations}
+			if tt.dryRun {
+				params["dry_run"] = "true"
+			}
+			res, err := SearchAndReplaceTool(ExecutorInput{
+				ToolUse: assistantmessage.ToolUse{Name: assistantmessage.SearchAndReplace, Params: params},
+				Cwd:     dir,
+			})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if res.IsError != tt.wantError {
+				t.Errorf("Expected is error %v, got %v: %s", tt.wantError, res.IsError, res.Result)
+			}
+			if !strings.Contains(res.Result, tt.wantResult) {
+				t.Errorf("Expected result to contain %q, got %q", tt.wantResult, res.Result)
+			}
+
+			content, _ := os.ReadFile(path)
+			if string(content) != tt.want {
+				t.Errorf("Expected content %q, got %q", tt.want, string(content))
+			}
+		})
+	}
+}
@@ -8,18 +8,26 @@ import (
 
 // ToolExecutorMap maps tool names to their execution functions.
 var ToolExecutorMap = map[assistantmessage.ToolUseName]ToolExecutor{
-	assistantmessage.ExecuteCommand:      ExecuteCommandTool,
-	assistantmessage.ReadFile:            ReadFileTool,
-	assistantmessage.WriteToFile:         WriteToFileTool,
-	assistantmessage.ApplyDiff:           ApplyDiffTool,
-	assistantmessage.ListFiles:           ListFilesTool,
-	assistantmessage.SearchFiles:         SearchFilesTool,
-	assistantmessage.AskFollowupQuestion: AskFollowupQuestionTool,
-	assistantmessage.AttemptCompletion:   AttemptCompletionTool,
-	// assistantmessage.ListCodeDefinitionNames: ListCodeDefinitionNamesTool,
-	assistantmessage.InsertContent: InsertContentTool, // Added
-	// assistantmessage.ToolSearchAndReplace:        SearchAndReplaceTool, // Added
-	// Add BrowserActionTool if implemented
+	assistantmessage.ExecuteCommand:          ExecuteCommandTool,
+	assistantmessage.ReadFile:                ReadFileTool,
+	assistantmessage.WriteToFile:             WriteToFileTool,
+	assistantmessage.ApplyDiff:               ApplyDiffTool,
+	assistantmessage.ListFiles:               ListFilesTool,
+	assistantmessage.SearchFiles:             SearchFilesTool,
+	assistantmessage.AskFollowupQuestion:     AskFollowupQuestionTool,
+	assistantmessage.AttemptCompletion:       AttemptCompletionTool,
+	assistantmessage.ListCodeDefinitionNames: ListCodeDefinitionNamesTool,
+	assistantmessage.InsertContent:           InsertContentTool, // Added
+	assistantmessage.SearchAndReplace:        SearchAndReplaceTool,
+	assistantmessage.BatchEdit:               BatchEditTool,
+	assistantmessage.MoveFile:                MoveFileTool,
+	assistantmessage.DeleteFile:              DeleteFileTool,
+	assistantmessage.CreateDirectory:         CreateDirectoryTool,
+	assistantmessage.FetchURL:                FetchURLTool,
+	assistantmessage.BrowserAction:           BrowserActionTool,
+	assistantmessage.UseMcpTool:              UseMcpToolTool,
+	assistantmessage.AccessMcpResource:       AccessMcpResourceTool,
+	assistantmessage.CodebaseSearch:          CodebaseSearchTool,
 }
 
 // ExecuteTool selects and runs the appropriate tool executor.
@@ -30,6 +38,23 @@ func ExecuteTool(input ExecutorInput) (*ExecutorResult, error) {
 		return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil // Format as tool error for LLM
 	}
 
+	// 执行前检查批准策略：拒绝的工具总是返回错误，需要确认的工具必须由用户确认过。
+	// 没有策略时使用空策略，除了不产生副作用的工具都需要确认
+	policy := input.Approval
+	if policy == nil {
+		policy = &ApprovalPolicy{}
+	}
+	switch policy.Decide(input.ToolUse, input.Cwd) {
+	case ApprovalDeny:
+		errText := fmt.Sprintf("Tool '%s' is denied by the approval policy.", input.ToolUse.Name)
+		return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true}, nil
+	case ApprovalAsk:
+		if !input.Confirmed {
+			errText := fmt.Sprintf("Tool '%s' not approved by user.", input.ToolUse.Name)
+			return &ExecutorResult{Result: prompts.FormatToolError(errText), IsError: true, NeedsApproval: true}, nil
+		}
+	}
+
 	// Check if the tool is enabled via experiments if that logic is needed here
 	// if !isToolEnabled(input.ToolUse.Name, input.Experiments) {
 	// 	errText := fmt.Sprintf("Tool '%s' is experimental and not enabled.", input.ToolUse.Name)
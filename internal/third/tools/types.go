@@ -2,9 +2,11 @@ package tools
 
 import (
 	"context"
+	"mind-weaver/internal/codeindex"
 	"mind-weaver/internal/third/assistantmessage"
 	"mind-weaver/internal/third/diff"
 	"mind-weaver/internal/third/ignore"
+	"mind-weaver/internal/third/mcp"
 )
 
 // ExecutorInput holds all necessary context for executing a tool.
@@ -15,9 +17,19 @@ type ExecutorInput struct {
 	RooIgnoreController *ignore.RooIgnoreController // Can be nil
 	DiffStrategy        diff.DiffStrategy           // Can be nil
 	Confirmed           bool
+	Approval            *ApprovalPolicy // 工具批准策略，nil 表示只看 Confirmed 由调用方负责确认
+	Fetch               *FetchOptions   // fetch_url 的设置，nil 时使用默认值且不限制域名
+	Browser             *BrowserSession // browser_action 使用的浏览器，nil 表示未启用
+	Mcp                 *mcp.Hub        // use_mcp_tool、access_mcp_resource 使用的 MCP 服务器，nil 表示未配置
+	CodeIndex           CodeSearcher    // codebase_search 使用的代码索引，nil 表示未启用
 	// Add any other required context (e.g., UserID, SessionID)
 }
 
+// CodeSearcher searches the code index of a project by meaning.
+type CodeSearcher interface {
+	Search(ctx context.Context, projectPath, query, pathPrefix string, limit int) ([]codeindex.Result, error)
+}
+
 // ExecutorResult represents the outcome of a tool execution.
 // This is the data that will be formatted (using prompts/responses) and sent back to the LLM.
 type ExecutorResult struct {
@@ -25,6 +37,8 @@ type ExecutorResult struct {
 	Error  error  // Any error that occurred during execution
 	// Add fields for specific tool outputs if needed (e.g., file list, search results)
 	IsError bool // Indicates if 'Result' is an error message for the LLM
+	// NeedsApproval 表示工具没有执行，策略要求用户确认
+	NeedsApproval bool
 }
 
 // ToolExecutor defines the interface for a tool execution function.
@@ -0,0 +1,149 @@
+// Package tokenizer counts and truncates text in model tokens. The BPE
+// vocabularies (cl100k_base, o200k_base) are embedded in the binary, so no
+// network access is needed at runtime.
+package tokenizer
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// 支持的编码
+const (
+	Cl100kBase = "cl100k_base"
+	O200kBase  = "o200k_base"
+)
+
+// DefaultEncoding is used for models without a known encoding. Models of other
+// vendors (deepseek, claude, qwen...) use their own vocabularies, cl100k is
+// close enough for budgeting.
+const DefaultEncoding = Cl100kBase
+
+// 使用 o200k 的模型名前缀
+var o200kPrefixes = []string{"gpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "o1", "o3", "o4", "chatgpt-4o"}
+
+// Tokenizer splits text into model tokens.
+type Tokenizer interface {
+	// Encoding returns the name of the vocabulary, e.g. "cl100k_base".
+	Encoding() string
+	Encode(text string) []int
+	Decode(tokens []int) string
+	Count(text string) int
+}
+
+var (
+	mu         sync.Mutex
+	tokenizers = map[string]Tokenizer{}
+)
+
+func init() {
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+}
+
+// Get returns the tokenizer of an encoding. Vocabularies are loaded on first
+// use and shared afterwards.
+func Get(encoding string) (Tokenizer, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if tk, ok := tokenizers[encoding]; ok {
+		return tk, nil
+	}
+	switch encoding {
+	case Cl100kBase, O200kBase:
+	default:
+		return nil, fmt.Errorf("unsupported tokenizer encoding %q", encoding)
+	}
+	enc, err := tiktoken.GetEncoding(encoding)
+	if err != nil {
+		return nil, fmt.Errorf("loading %s: %w", encoding, err)
+	}
+	tk := &bpeTokenizer{encoding: encoding, enc: enc}
+	tokenizers[encoding] = tk
+	return tk, nil
+}
+
+// ForModel returns the tokenizer of a model. encoding, when set, overrides the
+// encoding guessed from the model name.
+func ForModel(model, encoding string) Tokenizer {
+	if encoding == "" {
+		encoding = EncodingForModel(model)
+	}
+	tk, err := Get(encoding)
+	if err != nil {
+		// 配置了不支持的编码时退回默认编码，内置词表不会加载失败
+		tk, _ = Get(DefaultEncoding)
+	}
+	return tk
+}
+
+// Default returns the tokenizer used when the model is unknown.
+func Default() Tokenizer {
+	return ForModel("", DefaultEncoding)
+}
+
+// EncodingForModel guesses the encoding from the model name.
+func EncodingForModel(model string) string {
+	name := strings.ToLower(model)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:] // openai/gpt-4o 这类带提供方前缀的名称
+	}
+	for _, prefix := range o200kPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return O200kBase
+		}
+	}
+	return DefaultEncoding
+}
+
+// Truncate cuts text to at most maxTokens tokens. The cut never splits a
+// character and is moved back to the last line break when that keeps at least
+// half of the text.
+func Truncate(tk Tokenizer, text string, maxTokens int) (string, bool) {
+	if maxTokens <= 0 {
+		return "", text != ""
+	}
+	tokens := tk.Encode(text)
+	if len(tokens) <= maxTokens {
+		return text, false
+	}
+
+	// 解码后的字节是原文的前缀，末尾可能是被拆开的多字节字符
+	cut := min(len(tk.Decode(tokens[:maxTokens])), len(text))
+	for cut > 0 && cut < len(text) && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	if lineEnd := strings.LastIndex(text[:cut], "\n"); lineEnd > cut/2 {
+		cut = lineEnd
+	}
+	return strings.TrimRight(text[:cut], "\n "), true
+}
+
+type bpeTokenizer struct {
+	encoding string
+	enc      *tiktoken.Tiktoken
+}
+
+func (t *bpeTokenizer) Encoding() string {
+	return t.encoding
+}
+
+// Encode treats special tokens such as <|endoftext|> as plain text.
+func (t *bpeTokenizer) Encode(text string) []int {
+	return t.enc.EncodeOrdinary(text)
+}
+
+func (t *bpeTokenizer) Decode(tokens []int) string {
+	return t.enc.Decode(tokens)
+}
+
+func (t *bpeTokenizer) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+	return len(t.Encode(text))
+}
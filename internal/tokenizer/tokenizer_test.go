@@ -0,0 +1,88 @@
+package tokenizer
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestEncodingForModel(t *testing.T) {
+	tests := map[string]string{
+		"gpt-4o-mini":                O200kBase,
+		"openai/gpt-4.1":             O200kBase,
+		"o3-mini":                    O200kBase,
+		"gpt-4-turbo":                Cl100kBase,
+		"deepseek-chat":              Cl100kBase,
+		"claude-3-7-sonnet-20250219": Cl100kBase,
+		"":                           Cl100kBase,
+	}
+	for model, want := range tests {
+		if got := EncodingForModel(model); got != want {
+			t.Errorf("EncodingForModel(%q) = %s, want %s", model, got, want)
+		}
+	}
+
+	if tk := ForModel("deepseek-chat", O200kBase); tk.Encoding() != O200kBase {
+		t.Errorf("Expected configured encoding to win, got %s", tk.Encoding())
+	}
+	if tk := ForModel("gpt-4o", "unknown"); tk.Encoding() != DefaultEncoding {
+		t.Errorf("Expected fallback to default encoding, got %s", tk.Encoding())
+	}
+}
+
+func TestCount(t *testing.T) {
+	for _, encoding := range []string{Cl100kBase, O200kBase} {
+		tk, err := Get(encoding)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tk.Count("hello world"); got != 2 {
+			t.Errorf("%s: expected 2 tokens for \"hello world\", got %d", encoding, got)
+		}
+		if got := tk.Count(""); got != 0 {
+			t.Errorf("%s: expected 0 tokens for empty text, got %d", encoding, got)
+		}
+		// cl100k 中文每个字符通常至少一个token，按字节/4估算会严重偏少
+		chinese := strings.Repeat("这是一个用于测试的中文注释。", 20)
+		if got := tk.Count(chinese); encoding == Cl100kBase && got <= len(chinese)/4 {
+			t.Errorf("%s: expected more than %d tokens for chinese text, got %d", encoding, len(chinese)/4, got)
+		}
+		if got := tk.Decode(tk.Encode(chinese)); got != chinese {
+			t.Errorf("%s: expected decode to round trip", encoding)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tk := Default()
+
+	text, truncated := Truncate(tk, "short text", 100)
+	if truncated || text != "short text" {
+		t.Errorf("Expected text to be kept, got %q, %v", text, truncated)
+	}
+
+	lines := make([]string, 50)
+	for i := range lines {
+		lines[i] = "第" + strings.Repeat("行内容", 5) + "结束"
+	}
+	content := strings.Join(lines, "\n")
+	text, truncated = Truncate(tk, content, 101)
+	if !truncated {
+		t.Fatal("Expected content to be truncated")
+	}
+	if !utf8.ValidString(text) {
+		t.Errorf("Expected valid UTF-8, got %q", text)
+	}
+	if !strings.HasPrefix(content, text) || !strings.HasSuffix(text, "结束") {
+		t.Errorf("Expected truncation at a line end, got %q", text[max(0, len(text)-30):])
+	}
+	if got := tk.Count(text); got > 101 {
+		t.Errorf("Expected at most 101 tokens, got %d", got)
+	}
+
+	// 单行长文本不能按行截断时，也不能截断在字符中间
+	text, truncated = Truncate(tk, strings.Repeat("汉字", 200), 33)
+	if !truncated || !utf8.ValidString(text) || tk.Count(text) > 33 {
+		t.Errorf("Expected valid text within budget, got %q (%d tokens)", text, tk.Count(text))
+	}
+}
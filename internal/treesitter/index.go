@@ -0,0 +1,259 @@
+package treesitter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	sitter "github.com/smacker/go-tree-sitter"
+
+	"mind-weaver/internal/third/glob"
+	"mind-weaver/internal/third/ignore"
+	"mind-weaver/pkg/logger"
+)
+
+const (
+	// 定义至少要有这么多行才会输出，过滤掉单行的变量、简单的getter等噪音
+	minComponentLines = 4
+	// 目录模式下最多列出的文件数和最多解析的文件数
+	maxListFiles  = 200
+	maxParseFiles = 50
+)
+
+// tree-sitter 的 parser 不是并发安全的，缓存的 parser 在解析时需要加锁
+var parseMutex sync.Mutex
+
+// ParseSourceCodeDefinitionsForFile returns the definitions of a single file, or an
+// empty string when the file type is not supported or nothing was found.
+func ParseSourceCodeDefinitionsForFile(filePath string, ignoreCtrl *ignore.RooIgnoreController) (string, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return "", fmt.Errorf("file not found: %w", err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory", filePath)
+	}
+
+	ext := strings.ToLower(filepath.Ext(filePath))
+	if !isExtensionSupported(ext) {
+		return "", nil
+	}
+	if ignoreCtrl != nil && !ignoreCtrl.ValidateAccess(filePath) {
+		return "", nil
+	}
+
+	var definitions string
+	if isMarkdown(ext) {
+		definitions, err = parseMarkdownFile(filePath)
+	} else {
+		parsers, loadErr := LoadRequiredLanguageParsers(context.Background(), []string{filePath})
+		if loadErr != nil {
+			return "", loadErr
+		}
+		definitions, err = parseFile(filePath, parsers)
+	}
+	if err != nil || definitions == "" {
+		return "", err
+	}
+
+	return fmt.Sprintf("# %s\n%s", filepath.Base(filePath), definitions), nil
+}
+
+// ParseSourceCodeForDefinitionsTopLevel returns the definitions of the supported
+// files directly inside dirPath (not recursive).
+func ParseSourceCodeForDefinitionsTopLevel(dirPath string, ignoreCtrl *ignore.RooIgnoreController) (string, error) {
+	info, err := os.Stat(dirPath)
+	if err != nil || !info.IsDir() {
+		return "This directory does not exist or you do not have permission to access it.", nil
+	}
+
+	allFiles, _, err := glob.ListFiles(dirPath, false, maxListFiles, ignoreCtrl)
+	if err != nil {
+		return "", err
+	}
+
+	filesToParse := []string{}
+	for _, file := range allFiles {
+		// 目录以分隔符结尾，这里只保留支持的源码文件
+		if strings.HasSuffix(file, "/") || strings.HasSuffix(file, string(filepath.Separator)) {
+			continue
+		}
+		if isExtensionSupported(strings.ToLower(filepath.Ext(file))) {
+			filesToParse = append(filesToParse, file)
+		}
+		if len(filesToParse) >= maxParseFiles {
+			break
+		}
+	}
+	sort.Strings(filesToParse)
+
+	parsers, err := LoadRequiredLanguageParsers(context.Background(), filesToParse)
+	if err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	for _, file := range filesToParse {
+		if ignoreCtrl != nil && !ignoreCtrl.ValidateAccess(file) {
+			continue
+		}
+
+		var definitions string
+		if ext := strings.ToLower(filepath.Ext(file)); isMarkdown(ext) {
+			definitions, err = parseMarkdownFile(file)
+		} else {
+			definitions, err = parseFile(file, parsers)
+		}
+		if err != nil {
+			// 单个文件解析失败不影响其他文件
+			logger.Errorf("Failed to parse %s for code definitions: %v", file, err)
+			continue
+		}
+		if definitions == "" {
+			continue
+		}
+
+		relPath, relErr := filepath.Rel(dirPath, file)
+		if relErr != nil {
+			relPath = file
+		}
+		fmt.Fprintf(&result, "# %s\n%s\n", filepath.ToSlash(relPath), definitions)
+	}
+
+	if result.Len() == 0 {
+		return "No source code definitions found.", nil
+	}
+	return result.String(), nil
+}
+
+func isMarkdown(ext string) bool {
+	return ext == ".md" || ext == ".markdown"
+}
+
+// parseFile runs the language query over a file and formats the captured definitions.
+func parseFile(filePath string, parsers LanguageParsers) (string, error) {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(filePath), "."))
+	info, ok := parsers[ext]
+	if !ok {
+		return "", nil
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	parseMutex.Lock()
+	tree, err := info.Parser.ParseCtx(context.Background(), nil, content)
+	parseMutex.Unlock()
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", filePath, err)
+	}
+	defer tree.Close()
+
+	cursor := sitter.NewQueryCursor()
+	defer cursor.Close()
+	cursor.Exec(info.Query, tree.RootNode())
+
+	captures := []sitter.QueryCapture{}
+	for {
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+		// 处理 #eq?、#match? 等条件
+		match = cursor.FilterPredicates(match, content)
+		captures = append(captures, match.Captures...)
+	}
+
+	lines := strings.Split(string(content), "\n")
+	return processCaptures(captures, info.Query, lines), nil
+}
+
+// processCaptures formats captures as "start--end | first line", one definition per line.
+func processCaptures(captures []sitter.QueryCapture, query *sitter.Query, lines []string) string {
+	infos := []CaptureInfo{}
+	for _, capture := range captures {
+		name := query.CaptureNameForId(capture.Index)
+		if !strings.Contains(name, "definition") && !strings.Contains(name, "name") {
+			continue
+		}
+
+		// name 捕获的是标识符，需要取父节点得到整个定义的范围
+		node := capture.Node
+		if strings.Contains(name, "name") {
+			node = node.Parent()
+		}
+		if node == nil {
+			continue
+		}
+
+		startLine := node.StartPoint().Row
+		endLine := node.EndPoint().Row
+		if int(startLine) >= len(lines) {
+			continue
+		}
+		infos = append(infos, CaptureInfo{
+			StartLine:      startLine,
+			EndLine:        endLine,
+			DefinitionLine: lines[startLine],
+			CaptureName:    name,
+		})
+	}
+
+	return formatCaptureInfos(infos)
+}
+
+// parseMarkdownFile formats the header sections of a markdown file.
+func parseMarkdownFile(filePath string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(string(content), "\n")
+	infos := []CaptureInfo{}
+	for _, capture := range ParseMarkdown(string(content)) {
+		if !strings.HasPrefix(capture.Name, "definition") {
+			continue
+		}
+		startLine := capture.Node.StartPosition.Row
+		if int(startLine) >= len(lines) {
+			continue
+		}
+		infos = append(infos, CaptureInfo{
+			StartLine:      startLine,
+			EndLine:        capture.Node.EndPosition.Row,
+			DefinitionLine: lines[startLine],
+			CaptureName:    capture.Name,
+		})
+	}
+
+	return formatCaptureInfos(infos), nil
+}
+
+func formatCaptureInfos(infos []CaptureInfo) string {
+	sort.SliceStable(infos, func(i, j int) bool {
+		return infos[i].StartLine < infos[j].StartLine
+	})
+
+	var output strings.Builder
+	processed := make(map[string]bool)
+	for _, info := range infos {
+		if info.EndLine-info.StartLine+1 < minComponentLines {
+			continue
+		}
+		// 同一个定义可能同时被 name 和 definition 捕获，只输出一次
+		key := fmt.Sprintf("%d-%d", info.StartLine, info.EndLine)
+		if processed[key] {
+			continue
+		}
+		processed[key] = true
+		fmt.Fprintf(&output, "%d--%d | %s\n", info.StartLine+1, info.EndLine+1, strings.TrimRight(info.DefinitionLine, "\r"))
+	}
+	return output.String()
+}
@@ -0,0 +1,95 @@
+package treesitter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseSourceCodeDefinitions(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go": `package main
+
+type Server struct {
+	Addr string
+	Port int
+}
+
+func (s *Server) Start() error {
+	println(s.Addr)
+	return nil
+}
+
+var x = 1
+`,
+		"lib.rs": `pub struct Point {
+    x: i32,
+    y: i32,
+}
+
+impl Point {
+    fn new() -> Self {
+        let x = 0;
+        Point { x, y: 0 }
+    }
+}
+`,
+		"notes.txt": "not source code\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want []string
+	}{
+		{
+			name: "go file",
+			path: "main.go",
+			want: []string{"# main.go", "3--6 | type Server struct {", "8--11 | func (s *Server) Start() error {"},
+		},
+		{
+			name: "rust file",
+			path: "lib.rs",
+			want: []string{"# lib.rs", "1--4 | pub struct Point {", "6--11 | impl Point {", "7--10 |     fn new() -> Self {"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSourceCodeDefinitionsForFile(filepath.Join(dir, tt.path), nil)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("Expected output to contain %q, got %q", want, got)
+				}
+			}
+		})
+	}
+
+	got, err := ParseSourceCodeDefinitionsForFile(filepath.Join(dir, "notes.txt"), nil)
+	if err != nil || got != "" {
+		t.Errorf("Expected empty result for unsupported file, got %q, %v", got, err)
+	}
+
+	got, err = ParseSourceCodeForDefinitionsTopLevel(dir, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, want := range []string{"# lib.rs", "# main.go"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected directory output to contain %q, got %q", want, got)
+		}
+	}
+	if strings.Contains(got, "notes.txt") {
+		t.Errorf("Expected unsupported files to be skipped, got %q", got)
+	}
+}
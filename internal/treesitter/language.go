@@ -54,13 +54,13 @@ var (
 	}
 
 	queryMap = map[string]string{
-		"js":   javascriptQuery,
-		"jsx":  javascriptQuery, // Reusing JS query
-		"json": javascriptQuery, // Reusing JS query
-		"ts":   typescriptQuery,
-		"tsx":  tsxQuery,
-		"py":   pythonQuery,
-		// "rs":    rustQuery,
+		"js":    javascriptQuery,
+		"jsx":   javascriptQuery, // Reusing JS query
+		"json":  javascriptQuery, // Reusing JS query
+		"ts":    typescriptQuery,
+		"tsx":   tsxQuery,
+		"py":    pythonQuery,
+		"rs":    rustQuery,
 		"go":    goQuery,
 		"cpp":   cppQuery,
 		"hpp":   cppQuery,
@@ -860,5 +860,60 @@ const (
 (type_alias
   (type_identifier) @name.definition.type
 ) @definition.type
+`
+
+	rustQuery = `
+; Function definitions, including methods inside impl blocks
+(function_item
+  name: (identifier) @name.definition.function) @definition.function
+
+; Struct definitions (standard, tuple and unit structs)
+(struct_item
+  name: (type_identifier) @name.definition.struct) @definition.struct
+
+; Enum definitions
+(enum_item
+  name: (type_identifier) @name.definition.enum) @definition.enum
+
+; Union definitions
+(union_item
+  name: (type_identifier) @name.definition.union) @definition.union
+
+; Trait definitions
+(trait_item
+  name: (type_identifier) @name.definition.trait) @definition.trait
+
+; Inherent impl blocks
+(impl_item
+  type: (type_identifier) @name.definition.impl) @definition.impl
+
+; Trait implementations
+(impl_item
+  trait: (type_identifier) @name.definition.impl_trait
+  type: (type_identifier) @name.definition.impl_for) @definition.impl_trait
+
+; Generic impl blocks, e.g. impl<T> Stack<T>
+(impl_item
+  type: (generic_type
+    type: (type_identifier) @name.definition.impl)) @definition.impl
+
+; Module definitions
+(mod_item
+  name: (identifier) @name.definition.module) @definition.module
+
+; Macro definitions
+(macro_definition
+  name: (identifier) @name.definition.macro) @definition.macro
+
+; Type aliases
+(type_item
+  name: (type_identifier) @name.definition.type_alias) @definition.type_alias
+
+; Constants and statics
+(const_item
+  name: (identifier) @name.definition.constant) @definition.constant
+
+(static_item
+  name: (identifier) @name.definition.static) @definition.static
 `
 )
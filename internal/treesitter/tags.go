@@ -0,0 +1,147 @@
+package treesitter
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// 仓库地图中保留的定义类型，变量、参数、lambda 等噪音较多的捕获不参与排名
+var tagDefinitionKinds = map[string]bool{
+	"function": true, "method": true, "class": true, "interface": true, "type": true,
+	"struct": true, "enum": true, "trait": true, "module": true, "namespace": true,
+	"type_alias": true, "union": true, "macro": true, "object": true,
+	"async_function": true, "async_method": true, "generator_function": true,
+	"decorated_function": true, "decorated_class": true, "dataclass": true,
+	"class_method": true, "static_method": true, "method_direct": true,
+	"component": true, "jsx_component": true, "hook": true, "custom_hook": true,
+	"template_class": true, "template_function": true, "inner_class": true,
+}
+
+// Tag is a definition of a symbol in a file.
+type Tag struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`       // 定义类型，例如 function、class
+	StartLine uint32 `json:"start_line"` // 名称所在行，从0开始
+	EndLine   uint32 `json:"end_line"`   // 定义结束行，从0开始
+	Line      string `json:"line"`       // 名称所在行的源码
+}
+
+// FileTags holds the definitions of a file and how often each identifier is
+// referenced in it.
+type FileTags struct {
+	Definitions []Tag
+	References  map[string]int
+}
+
+// SupportsTags reports whether ParseTags can extract tags from the file.
+func SupportsTags(filePath string) bool {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(filePath), "."))
+	_, langOk := languageMap[ext]
+	_, queryOk := queryMap[ext]
+	return langOk && queryOk
+}
+
+// ParseTags extracts the definitions and identifier references of a source
+// file. It returns nil when the language is not supported.
+func ParseTags(filePath string, content []byte) (*FileTags, error) {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(filePath), "."))
+	parsers, err := LoadRequiredLanguageParsers(context.Background(), []string{filePath})
+	if err != nil {
+		return nil, err
+	}
+	info, ok := parsers[ext]
+	if !ok {
+		return nil, nil
+	}
+
+	parseMutex.Lock()
+	tree, err := info.Parser.ParseCtx(context.Background(), nil, content)
+	parseMutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	defer tree.Close()
+
+	lines := strings.Split(string(content), "\n")
+	tags := &FileTags{References: map[string]int{}}
+	nameNodes := map[uint32]bool{} // 定义名称的起始字节，不算作引用
+	seen := map[string]bool{}
+
+	cursor := sitter.NewQueryCursor()
+	defer cursor.Close()
+	cursor.Exec(info.Query, tree.RootNode())
+	for {
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+		match = cursor.FilterPredicates(match, content)
+
+		// 一次匹配中同时有定义节点和名称节点，名称可能是 @name 或 @name.definition.xxx
+		var kind string
+		var defNode, nameNode *sitter.Node
+		for _, capture := range match.Captures {
+			name := info.Query.CaptureNameForId(capture.Index)
+			switch {
+			case strings.HasPrefix(name, "definition."):
+				kind, defNode = strings.TrimPrefix(name, "definition."), capture.Node
+			case name == "name" || strings.HasPrefix(name, "name.definition."):
+				nameNode = capture.Node
+			}
+		}
+		if defNode == nil || nameNode == nil || !tagDefinitionKinds[kind] {
+			continue
+		}
+		row := nameNode.StartPoint().Row
+		if int(row) >= len(lines) {
+			continue
+		}
+		nameNodes[nameNode.StartByte()] = true
+		name := nameNode.Content(content)
+		// 同一个定义会被多个查询捕获，例如 type 和 struct
+		key := fmt.Sprintf("%s:%d", name, row)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		tags.Definitions = append(tags.Definitions, Tag{
+			Name:      name,
+			Kind:      kind,
+			StartLine: row,
+			EndLine:   defNode.EndPoint().Row,
+			Line:      strings.TrimRight(lines[row], "\r"),
+		})
+	}
+
+	collectReferences(tree.RootNode(), content, nameNodes, tags.References)
+	return tags, nil
+}
+
+// collectReferences 统计语法树中标识符出现的次数，跳过定义名称本身
+func collectReferences(root *sitter.Node, content []byte, skip map[uint32]bool, refs map[string]int) {
+	cursor := sitter.NewTreeCursor(root)
+	defer cursor.Close()
+	for {
+		node := cursor.CurrentNode()
+		if node.ChildCount() == 0 && isIdentifierNode(node.Type()) && !skip[node.StartByte()] {
+			refs[node.Content(content)]++
+		}
+		if cursor.GoToFirstChild() {
+			continue
+		}
+		for !cursor.GoToNextSibling() {
+			if !cursor.GoToParent() {
+				return
+			}
+		}
+	}
+}
+
+// isIdentifierNode 各语言的标识符节点类型，例如 identifier、type_identifier、field_identifier
+func isIdentifierNode(nodeType string) bool {
+	return (strings.HasSuffix(nodeType, "identifier") && nodeType != "package_identifier") || nodeType == "constant" || nodeType == "name"
+}
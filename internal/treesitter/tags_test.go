@@ -0,0 +1,52 @@
+package treesitter
+
+import (
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	src := `package main
+
+// Server 服务
+type Server struct {
+	Addr string
+}
+
+func NewServer(addr string) *Server {
+	return &Server{Addr: addr}
+}
+
+func (s *Server) Start() error {
+	srv := NewServer(s.Addr)
+	_ = srv
+	return nil
+}
+
+var x = 1
+`
+	tags, err := ParseTags("main.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs := map[string]Tag{}
+	for _, tag := range tags.Definitions {
+		defs[tag.Name] = tag
+	}
+	if len(defs) != 3 {
+		t.Errorf("Expected Server, NewServer and Start, got %+v", tags.Definitions)
+	}
+	if tag := defs["Start"]; tag.Kind != "method" || tag.StartLine != 11 || tag.EndLine != 15 || tag.Line != "func (s *Server) Start() error {" {
+		t.Errorf("Expected method Start on line 12, got %+v", tag)
+	}
+	if _, ok := defs["x"]; ok {
+		t.Errorf("Expected variables to be skipped")
+	}
+	// 定义名称本身不算引用
+	if tags.References["Server"] != 3 || tags.References["NewServer"] != 1 {
+		t.Errorf("Expected references Server=3 NewServer=1, got %v", tags.References)
+	}
+
+	if tags, err := ParseTags("notes.txt", []byte("hello")); err != nil || tags != nil {
+		t.Errorf("Expected nil for unsupported files, got %+v, %v", tags, err)
+	}
+}
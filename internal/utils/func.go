@@ -2,6 +2,7 @@ package utils
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/base64"
 	"encoding/json"
 	"io/ioutil"
@@ -102,6 +103,45 @@ func GetFilesInDirectory(dirPath string, filter FileFilterFunc, maxDepth int) ([
 	return files, err
 }
 
+// 遍历项目时跳过的依赖和构建产物目录
+var skippedProjectDirs = map[string]bool{
+	"node_modules":     true,
+	"vendor":           true,
+	"__pycache__":      true,
+	"venv":             true,
+	"env":              true,
+	"dist":             true,
+	"build":            true,
+	"out":              true,
+	"target":           true,
+	"bower_components": true,
+}
+
+// IsSkippedProjectDir 判断遍历项目源码时是否跳过该目录：依赖、构建产物和隐藏目录
+func IsSkippedProjectDir(name string) bool {
+	return skippedProjectDirs[name] || (strings.HasPrefix(name, ".") && name != "." && name != "..")
+}
+
+// 平均每行或者某一行超过该长度的文件视为压缩后的代码
+const (
+	minifiedAverageLineLength = 200
+	minifiedLineLength        = 1000
+)
+
+// IsMinified 判断文件是否是压缩或打包后的代码，这类文件不参与解析和索引
+func IsMinified(content []byte) bool {
+	lines := bytes.Split(content, []byte("\n"))
+	if len(content)/len(lines) > minifiedAverageLineLength {
+		return true
+	}
+	for _, line := range lines {
+		if len(line) > minifiedLineLength {
+			return true
+		}
+	}
+	return false
+}
+
 func GetAllowedExtensions() map[string]bool {
 	return map[string]bool{
 		".go":    true,
@@ -5,6 +5,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"mind-weaver/internal/tokenizer"
 )
 
 // PromptBuilder 用于构建发送给AI的提示词
@@ -15,6 +17,7 @@ type PromptBuilder struct {
 	cursorPosition string
 	language       string
 	maxTokens      int
+	tokenizer      tokenizer.Tokenizer
 }
 
 // ContextFile 表示上下文中的文件
@@ -31,7 +34,16 @@ func NewPromptBuilder(maxTokens int) *PromptBuilder {
 		systemPrompt: "You are MindWeaver AI, an intelligent coding assistant. Help the user write clean, efficient, and correct code.",
 		contextFiles: []ContextFile{},
 		maxTokens:    maxTokens,
+		tokenizer:    tokenizer.Default(),
+	}
+}
+
+// SetTokenizer 设置计算token使用的分词器，应与所用模型一致
+func (b *PromptBuilder) SetTokenizer(tk tokenizer.Tokenizer) *PromptBuilder {
+	if tk != nil {
+		b.tokenizer = tk
 	}
+	return b
 }
 
 // SetSystemPrompt 设置系统提示词
@@ -200,13 +212,18 @@ func (b *PromptBuilder) BuildCompletionPrompt(userPrompt string, addSysPrompt bo
 	return systemPrompt, enhancedUserPrompt
 }
 
-// EstimateTokenCount 估计提示词的token数量
-// 这是一个简化的估算，实际token数量需要用tokenizer计算
+// EstimateTokenCount 计算提示词的token数量
+// 使用模型对应的BPE词表，其他厂商的模型使用相近的词表，结果可能略有偏差
 func (b *PromptBuilder) EstimateTokenCount(text string) int {
-	// 粗略估计：英文平均每4个字符约为1个token
-	return len(text) / 4
+	return b.tokenizer.Count(text)
 }
 
+// 文件内容被截断时追加的说明
+const (
+	truncatedNote       = "\n// ... content truncated to fit token limit"
+	truncatedNoteTokens = 20
+)
+
 // TrimContextToFit 裁剪上下文以适应token限制
 func (b *PromptBuilder) TrimContextToFit() {
 	// 如果没有上下文文件，直接返回
@@ -214,12 +231,11 @@ func (b *PromptBuilder) TrimContextToFit() {
 		return
 	}
 
-	// 计算系统提示的基本token数（不包括文件内容）
-	basePrompt := b.systemPrompt + "\n\n"
-	if b.language != "" {
-		basePrompt += fmt.Sprintf("You are helping with %s code. ", b.language)
-	}
-	basePrompt += "Follow these guidelines:\n1. Write clean, efficient, and well-documented code\n2. Follow best practices and design patterns\n3. Include error handling where appropriate\n4. Be concise but thorough in your explanations\n\nCONTEXT FILES:\n"
+	// 计算系统提示的基本token数（不包括文件内容），按包含系统提示词的最长形式计算
+	files := b.contextFiles
+	b.contextFiles = nil
+	basePrompt := b.BuildSystemPrompt(true) + "CONTEXT FILES:\n"
+	b.contextFiles = files
 
 	baseTokens := b.EstimateTokenCount(basePrompt)
 
@@ -276,10 +292,9 @@ func (b *PromptBuilder) TrimContextToFit() {
 				// 计算可用于内容的token
 				contentTokens := availableTokens - headerFooterTokens
 				if contentTokens > 0 {
-					// 按字符数粗略裁剪（实际应该按token裁剪）
-					maxChars := contentTokens * 4
-					if len(file.Content) > maxChars {
-						file.Content = file.Content[:maxChars] + "\n// ... content truncated to fit token limit"
+					// 按token裁剪，截断位置在完整的字符和行上
+					if content, truncated := tokenizer.Truncate(b.tokenizer, file.Content, contentTokens-truncatedNoteTokens); truncated {
+						file.Content = content + truncatedNote
 					}
 
 					b.contextFiles = []ContextFile{file}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTrimContextToFit(t *testing.T) {
+	line := "// 这一行是中文注释，用来测试按token裁剪上下文\n"
+	main := strings.Repeat(line, 100)
+	other := strings.Repeat(line, 10)
+
+	// 字节/4 估算会认为主文件能放下
+	b := NewPromptBuilder(3000)
+	if estimate := len(main) / 4; estimate > 3000-1000 {
+		t.Fatalf("Test content too large for the byte estimate: %d", estimate)
+	}
+	b.AddContextFile("main.go", main, "go", true).AddContextFile("other.go", other, "go", false)
+	b.TrimContextToFit()
+
+	if len(b.contextFiles) != 1 {
+		t.Fatalf("Expected only the main file to be kept, got %d files", len(b.contextFiles))
+	}
+	content := b.contextFiles[0].Content
+	if !strings.HasSuffix(content, truncatedNote) {
+		t.Fatalf("Expected main file to be truncated, got %d bytes", len(content))
+	}
+	kept := strings.TrimSuffix(content, truncatedNote)
+	if !utf8.ValidString(kept) || !strings.HasPrefix(main, kept) || !strings.HasSuffix(kept, "上下文") {
+		t.Errorf("Expected truncation at a line end, got %q", kept[max(0, len(kept)-40):])
+	}
+	if tokens := b.EstimateTokenCount(b.BuildSystemPrompt(true)); tokens > 3000-1000 {
+		t.Errorf("Expected prompt within budget, got %d tokens", tokens)
+	}
+}
+
+func TestTrimContextToFitKeepsFilesThatFit(t *testing.T) {
+	b := NewPromptBuilder(8000)
+	b.AddContextFile("main.go", "package main\n", "go", true).AddContextFile("util.go", "package main\n\nfunc f() {}\n", "go", false)
+	b.TrimContextToFit()
+	if len(b.contextFiles) != 2 || b.contextFiles[0].Content != "package main\n" {
+		t.Errorf("Expected files to be kept unchanged, got %+v", b.contextFiles)
+	}
+}
@@ -2,14 +2,19 @@ package utils
 
 import (
 	"fmt"
+	"io"
 	"strings"
-
-	"github.com/gin-gonic/gin"
 )
 
+// FlushWriter 是SSE事件的输出目标，gin.ResponseWriter 和可重放的事件缓存都实现了该接口
+type FlushWriter interface {
+	io.Writer
+	Flush()
+}
+
 // SSE 写入器，格式化为 SSE 事件
 type SseWriter struct {
-	ResponseWriter gin.ResponseWriter
+	ResponseWriter FlushWriter
 	Buffer         *strings.Builder
 	MsgId          int64
 	UserMsgId      int64